@@ -0,0 +1,128 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var claimMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// ClaimsBuilder composes AstarteClaims one permission at a time, checking each method and path regular expression
+// as it is added. Create one with Claims, chain the Allow methods and call Build.
+type ClaimsBuilder struct {
+	claims AstarteClaims
+	err    error
+}
+
+// Claims returns an empty ClaimsBuilder. A token built out of it grants access only to what is explicitly allowed.
+func Claims() *ClaimsBuilder {
+	return &ClaimsBuilder{}
+}
+
+// AllowAppEngine allows calls with method to AppEngine API paths matching pathRegex. method is an HTTP method
+// such as "GET", or "*" to allow any method. pathRegex is matched against the path relative to the realm, e.g.
+// "devices/.*".
+func (b *ClaimsBuilder) AllowAppEngine(method, pathRegex string) *ClaimsBuilder {
+	b.claims.AppEngineAPI = b.appendAPIClaim(b.claims.AppEngineAPI, method, pathRegex)
+	return b
+}
+
+// AllowRealmManagement allows calls with method to Realm Management API paths matching pathRegex, with the same
+// rules as AllowAppEngine.
+func (b *ClaimsBuilder) AllowRealmManagement(method, pathRegex string) *ClaimsBuilder {
+	b.claims.RealmManagement = b.appendAPIClaim(b.claims.RealmManagement, method, pathRegex)
+	return b
+}
+
+// AllowPairing allows calls with method to Pairing API paths matching pathRegex, with the same rules as
+// AllowAppEngine.
+func (b *ClaimsBuilder) AllowPairing(method, pathRegex string) *ClaimsBuilder {
+	b.claims.Pairing = b.appendAPIClaim(b.claims.Pairing, method, pathRegex)
+	return b
+}
+
+// AllowHousekeeping allows calls with method to Housekeeping API paths matching pathRegex, with the same rules
+// as AllowAppEngine.
+func (b *ClaimsBuilder) AllowHousekeeping(method, pathRegex string) *ClaimsBuilder {
+	b.claims.Housekeeping = b.appendAPIClaim(b.claims.Housekeeping, method, pathRegex)
+	return b
+}
+
+// AllowFlow allows calls with method to Flow API paths matching pathRegex, with the same rules as AllowAppEngine.
+func (b *ClaimsBuilder) AllowFlow(method, pathRegex string) *ClaimsBuilder {
+	b.claims.Flow = b.appendAPIClaim(b.claims.Flow, method, pathRegex)
+	return b
+}
+
+// AllowChannelsJoin allows joining the Channels rooms matching roomRegex.
+func (b *ClaimsBuilder) AllowChannelsJoin(roomRegex string) *ClaimsBuilder {
+	b.claims.Channels = b.appendClaim(b.claims.Channels, "JOIN", roomRegex)
+	return b
+}
+
+// AllowChannelsWatch allows installing watches in the Channels rooms matching roomRegex.
+func (b *ClaimsBuilder) AllowChannelsWatch(roomRegex string) *ClaimsBuilder {
+	b.claims.Channels = b.appendClaim(b.claims.Channels, "WATCH", roomRegex)
+	return b
+}
+
+// Build returns the composed AstarteClaims, or the first error encountered while adding them. If nothing was
+// allowed, ErrNoClaims is returned: empty claims would grant access to everything once passed to
+// GenerateAstarteJWT.
+func (b *ClaimsBuilder) Build() (AstarteClaims, error) {
+	if b.err != nil {
+		return AstarteClaims{}, b.err
+	}
+	if b.claims.isEmpty() {
+		return AstarteClaims{}, ErrNoClaims
+	}
+	return b.claims, nil
+}
+
+func (b *ClaimsBuilder) appendAPIClaim(claims []string, method, pathRegex string) []string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	switch {
+	case method == "*":
+		method = ".*"
+	case !claimMethods[method]:
+		b.setError(fmt.Errorf("invalid HTTP method %q in claim", method))
+		return claims
+	}
+	// Claims are matched against paths relative to the realm, without a leading slash
+	return b.appendClaim(claims, method, strings.TrimPrefix(pathRegex, "/"))
+}
+
+func (b *ClaimsBuilder) appendClaim(claims []string, prefix, regex string) []string {
+	if regex == "" {
+		b.setError(fmt.Errorf("empty regular expression in %s claim", prefix))
+		return claims
+	}
+	if _, err := regexp.Compile(regex); err != nil {
+		b.setError(fmt.Errorf("invalid regular expression in %s claim: %w", prefix, err))
+		return claims
+	}
+	return append(claims, prefix+"::"+regex)
+}
+
+func (b *ClaimsBuilder) setError(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClaimsBuilder(t *testing.T) {
+	claims, err := Claims().
+		AllowAppEngine("get", "/devices/.*").
+		AllowAppEngine("*", "groups").
+		AllowRealmManagement("GET", "interfaces.*").
+		AllowChannelsWatch("devices:.*").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := AstarteClaims{
+		AppEngineAPI:    []string{"GET::devices/.*", ".*::groups"},
+		RealmManagement: []string{"GET::interfaces.*"},
+		Channels:        []string{"WATCH::devices:.*"},
+	}
+	if !reflect.DeepEqual(claims, expected) {
+		t.Error("Unexpected claims", claims)
+	}
+}
+
+func TestClaimsBuilderErrors(t *testing.T) {
+	for name, builder := range map[string]*ClaimsBuilder{
+		"method": Claims().AllowPairing("FETCH", "agent/devices"),
+		"regex":  Claims().AllowHousekeeping("GET", "realms/(.*"),
+		"empty":  Claims().AllowChannelsJoin(""),
+		"first":  Claims().AllowFlow("GET", "flows").AllowFlow("GET", "["),
+	} {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+
+	if _, err := Claims().Build(); !errors.Is(err, ErrNoClaims) {
+		t.Error("Expected ErrNoClaims for an empty builder, got", err)
+	}
+}
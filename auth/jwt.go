@@ -0,0 +1,176 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth provides helpers to generate the tokens used to authorize calls to Astarte APIs.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"time"
+
+	jwt "github.com/cristalhq/jwt/v3"
+)
+
+var (
+	// ErrUnsupportedPrivateKey is returned when the private key is not supported for JWT generation
+	ErrUnsupportedPrivateKey = errors.New("key is not supported for JWT generation")
+	// ErrUnsupportedPublicKey is returned when the public key is not supported for JWT verification
+	ErrUnsupportedPublicKey = errors.New("key is not supported for JWT verification")
+	// ErrTokenExpired is returned when verifying a token whose expiry is in the past
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenNotYetValid is returned when verifying a token which is not valid before a time in the future
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	// ErrNoClaims is returned when building claims out of a ClaimsBuilder which allows nothing, as
+	// GenerateAstarteJWT would turn them into AllowAllClaims
+	ErrNoClaims = errors.New("no permission was allowed in claims")
+)
+
+const allowAllClaim = ".*::.*"
+
+// AstarteClaims holds the authorization claims of an Astarte token. Each claim is a regular expression in the form
+// `<HTTP method regex>::<path regex>` (or `<JOIN|WATCH>::<room regex>` for Channels). A service with no claims
+// can't be accessed by the token.
+type AstarteClaims struct {
+	AppEngineAPI    []string `json:"a_aea,omitempty"`
+	Channels        []string `json:"a_ch,omitempty"`
+	Flow            []string `json:"a_f,omitempty"`
+	Housekeeping    []string `json:"a_ha,omitempty"`
+	RealmManagement []string `json:"a_rma,omitempty"`
+	Pairing         []string `json:"a_pa,omitempty"`
+}
+
+// AllowAllClaims returns AstarteClaims granting access to the entirety of the API tree of every service
+func AllowAllClaims() AstarteClaims {
+	return AstarteClaims{
+		AppEngineAPI:    []string{allowAllClaim},
+		Channels:        []string{"JOIN::.*", "WATCH::.*"},
+		Flow:            []string{allowAllClaim},
+		Housekeeping:    []string{allowAllClaim},
+		RealmManagement: []string{allowAllClaim},
+		Pairing:         []string{allowAllClaim},
+	}
+}
+
+func (c AstarteClaims) isEmpty() bool {
+	return len(c.AppEngineAPI) == 0 && len(c.Channels) == 0 && len(c.Flow) == 0 && len(c.Housekeeping) == 0 &&
+		len(c.RealmManagement) == 0 && len(c.Pairing) == 0
+}
+
+type tokenClaims struct {
+	jwt.StandardClaims
+	AstarteClaims
+}
+
+// GenerateAstarteJWT generates an Astarte token signed with privateKey, which must be either an *rsa.PrivateKey or
+// an *ecdsa.PrivateKey. When claims is empty, the token grants access to every service, as with AllowAllClaims.
+// The token expires after expiry, or never when expiry is 0.
+func GenerateAstarteJWT(privateKey crypto.PrivateKey, claims AstarteClaims, expiry time.Duration) (string, error) {
+	signer, err := getJWTSigner(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	if claims.isEmpty() {
+		claims = AllowAllClaims()
+	}
+	token := tokenClaims{AstarteClaims: claims}
+	now := time.Now()
+	token.IssuedAt = jwt.NewNumericDate(now)
+	if expiry > 0 {
+		token.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+	}
+
+	built, err := jwt.NewBuilder(signer).Build(&token)
+	if err != nil {
+		return "", err
+	}
+	return built.String(), nil
+}
+
+// VerifyAstarteJWT verifies the signature of token against publicKeyPEM, such as a Realm public key, and returns
+// its AstarteClaims. The signing algorithm is taken from the token header and must match the key type. Tokens
+// which are expired or not valid yet are rejected with ErrTokenExpired and ErrTokenNotYetValid respectively.
+func VerifyAstarteJWT(token string, publicKeyPEM []byte) (AstarteClaims, error) {
+	publicKey, err := PublicKeyFromPEM(publicKeyPEM)
+	if err != nil {
+		return AstarteClaims{}, err
+	}
+
+	parsed, err := jwt.ParseString(token)
+	if err != nil {
+		return AstarteClaims{}, err
+	}
+	verifier, err := getJWTVerifier(publicKey, parsed.Header().Algorithm)
+	if err != nil {
+		return AstarteClaims{}, err
+	}
+	if err := verifier.Verify(parsed.Payload(), parsed.Signature()); err != nil {
+		return AstarteClaims{}, err
+	}
+
+	claims := tokenClaims{}
+	if err := json.Unmarshal(parsed.RawClaims(), &claims); err != nil {
+		return AstarteClaims{}, err
+	}
+	now := time.Now()
+	if !claims.IsValidExpiresAt(now) {
+		return AstarteClaims{}, ErrTokenExpired
+	}
+	if !claims.IsValidNotBefore(now) {
+		return AstarteClaims{}, ErrTokenNotYetValid
+	}
+	return claims.AstarteClaims, nil
+}
+
+func getJWTSigner(key crypto.PrivateKey) (jwt.Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.NewSignerRS(jwt.RS256, k)
+
+	case *ecdsa.PrivateKey:
+		// Match the EC curve with the correct signing algorithm
+		switch k.PublicKey.Curve.Params().Name {
+		case "P-256":
+			return jwt.NewSignerES(jwt.ES256, k)
+		case "P-384":
+			return jwt.NewSignerES(jwt.ES384, k)
+		case "P-521":
+			return jwt.NewSignerES(jwt.ES512, k)
+		}
+	}
+
+	return nil, ErrUnsupportedPrivateKey
+}
+
+func getJWTVerifier(key crypto.PublicKey, alg jwt.Algorithm) (jwt.Verifier, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case jwt.RS256, jwt.RS384, jwt.RS512:
+			return jwt.NewVerifierRS(alg, k)
+		case jwt.PS256, jwt.PS384, jwt.PS512:
+			return jwt.NewVerifierPS(alg, k)
+		}
+		return nil, jwt.ErrAlgorithmMismatch
+
+	case *ecdsa.PublicKey:
+		return jwt.NewVerifierES(alg, k)
+	}
+
+	return nil, ErrUnsupportedPublicKey
+}
@@ -0,0 +1,156 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	jwt "github.com/cristalhq/jwt/v3"
+)
+
+func parseTestToken(t *testing.T, token string, verifier jwt.Verifier) tokenClaims {
+	parsed, err := jwt.ParseAndVerifyString(token, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := tokenClaims{}
+	if err := json.Unmarshal(parsed.RawClaims(), &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestGenerateAstarteJWTWithRSAKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateAstarteJWT(key, AstarteClaims{AppEngineAPI: []string{"GET::devices/.*"}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, _ := jwt.NewVerifierRS(jwt.RS256, &key.PublicKey)
+	claims := parseTestToken(t, token, verifier)
+	if !reflect.DeepEqual(claims.AstarteClaims, AstarteClaims{AppEngineAPI: []string{"GET::devices/.*"}}) {
+		t.Error("Unexpected claims", claims.AstarteClaims)
+	}
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Sub(claims.IssuedAt.Time) != time.Hour {
+		t.Error("Unexpected expiry", claims.ExpiresAt)
+	}
+}
+
+func TestGenerateAstarteJWTWithECKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateAstarteJWT(key, AstarteClaims{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, _ := jwt.NewVerifierES(jwt.ES384, &key.PublicKey)
+	claims := parseTestToken(t, token, verifier)
+	if !reflect.DeepEqual(claims.AstarteClaims, AllowAllClaims()) {
+		t.Error("Empty claims were not defaulted to allow all", claims.AstarteClaims)
+	}
+	if claims.ExpiresAt != nil {
+		t.Error("Token should not expire", claims.ExpiresAt)
+	}
+}
+
+func TestGenerateAstarteJWTWithUnsupportedKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateAstarteJWT(key, AstarteClaims{}, 0); !errors.Is(err, ErrUnsupportedPrivateKey) {
+		t.Error("Expected ErrUnsupportedPrivateKey, got", err)
+	}
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyAstarteJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	claims := AstarteClaims{RealmManagement: []string{"GET::interfaces"}}
+
+	for name, key := range map[string]crypto.Signer{"RSA": rsaKey, "EC": ecKey} {
+		token, err := GenerateAstarteJWT(key, claims, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verified, err := VerifyAstarteJWT(token, publicKeyPEM(t, key.Public()))
+		if err != nil {
+			t.Errorf("Could not verify %s token: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(verified, claims) {
+			t.Errorf("Unexpected %s claims: %v", name, verified)
+		}
+	}
+}
+
+func TestVerifyAstarteJWTErrors(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token, _ := GenerateAstarteJWT(key, AstarteClaims{}, time.Hour)
+
+	if _, err := VerifyAstarteJWT(token, publicKeyPEM(t, &otherKey.PublicKey)); !errors.Is(err, jwt.ErrInvalidSignature) {
+		t.Error("Expected ErrInvalidSignature, got", err)
+	}
+	if _, err := VerifyAstarteJWT(token, publicKeyPEM(t, &rsaKey.PublicKey)); !errors.Is(err, jwt.ErrAlgorithmMismatch) {
+		t.Error("Expected ErrAlgorithmMismatch, got", err)
+	}
+	if _, err := VerifyAstarteJWT("not a token", publicKeyPEM(t, &key.PublicKey)); err == nil {
+		t.Error("Malformed token was verified")
+	}
+
+	signer, _ := jwt.NewSignerES(jwt.ES256, key)
+	expired := tokenClaims{AstarteClaims: AllowAllClaims()}
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	built, _ := jwt.NewBuilder(signer).Build(&expired)
+	if _, err := VerifyAstarteJWT(built.String(), publicKeyPEM(t, &key.PublicKey)); !errors.Is(err, ErrTokenExpired) {
+		t.Error("Expected ErrTokenExpired, got", err)
+	}
+
+	notYetValid := tokenClaims{AstarteClaims: AllowAllClaims()}
+	notYetValid.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	built, _ = jwt.NewBuilder(signer).Build(&notYetValid)
+	if _, err := VerifyAstarteJWT(built.String(), publicKeyPEM(t, &key.PublicKey)); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Error("Expected ErrTokenNotYetValid, got", err)
+	}
+}
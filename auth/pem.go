@@ -0,0 +1,106 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+)
+
+var (
+	// ErrKeyMustBePEMEncoded is returned when the key is not encoded in PEM format
+	ErrKeyMustBePEMEncoded = errors.New("invalid key: key must be a PEM encoded private key")
+	// ErrNotPrivateKey is returned when the PEM block does not contain a private key
+	ErrNotPrivateKey = errors.New("key is not a valid private key")
+	// ErrNotPublicKey is returned when the PEM block does not contain a public key
+	ErrNotPublicKey = errors.New("key is not a valid public key")
+)
+
+// PrivateKeyFromPEM parses a PEM encoded private key, such as a Realm or Housekeeping private key generated by
+// astartectl. PKCS#1 RSA, PKCS#8 and SEC 1 EC encodings are supported. The returned key is either an
+// *rsa.PrivateKey or an *ecdsa.PrivateKey, ready to be used with GenerateAstarteJWT.
+func PrivateKeyFromPEM(pemBytes []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	var key crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, ErrNotPrivateKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedPrivateKey
+	}
+}
+
+// PrivateKeyFromPEMFile is a convenience function to call PrivateKeyFromPEM with a file as input
+func PrivateKeyFromPEMFile(path string) (crypto.PrivateKey, error) {
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return PrivateKeyFromPEM(pemBytes)
+}
+
+// PublicKeyFromPEM parses a PEM encoded public key, such as the Realm public key stored in Astarte. PKIX and
+// PKCS#1 RSA encodings are supported. The returned key is either an *rsa.PublicKey or an *ecdsa.PublicKey.
+func PublicKeyFromPEM(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+
+	var key crypto.PublicKey
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, ErrNotPublicKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, ErrUnsupportedPublicKey
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrivateKeyFromPEM(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	pkcs8DER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+
+	for name, block := range map[string]*pem.Block{
+		"PKCS#1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"EC":     {Type: "EC PRIVATE KEY", Bytes: ecDER},
+		"PKCS#8": {Type: "PRIVATE KEY", Bytes: pkcs8DER},
+	} {
+		key, err := PrivateKeyFromPEM(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Errorf("Could not parse %s key: %v", name, err)
+			continue
+		}
+		if _, err := GenerateAstarteJWT(key, AstarteClaims{}, 0); err != nil {
+			t.Errorf("Could not sign with %s key: %v", name, err)
+		}
+	}
+}
+
+func TestPrivateKeyFromPEMErrors(t *testing.T) {
+	if _, err := PrivateKeyFromPEM([]byte("not a key")); !errors.Is(err, ErrKeyMustBePEMEncoded) {
+		t.Error("Expected ErrKeyMustBePEMEncoded, got", err)
+	}
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{0}})
+	if _, err := PrivateKeyFromPEM(certificate); !errors.Is(err, ErrNotPrivateKey) {
+		t.Error("Expected ErrNotPrivateKey, got", err)
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER})
+	if _, err := PrivateKeyFromPEM(edPEM); !errors.Is(err, ErrUnsupportedPrivateKey) {
+		t.Error("Expected ErrUnsupportedPrivateKey, got", err)
+	}
+}
+
+func TestPrivateKeyFromPEMFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astarte-go-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	keyFile := filepath.Join(dir, "test_private.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PrivateKeyFromPEMFile(keyFile); err != nil {
+		t.Error(err)
+	}
+	if _, err := PrivateKeyFromPEMFile(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestPublicKeyFromPEM(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+
+	for name, block := range map[string]*pem.Block{
+		"PKCS#1": {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)},
+		"PKIX":   {Type: "PUBLIC KEY", Bytes: ecDER},
+	} {
+		if _, err := PublicKeyFromPEM(pem.EncodeToMemory(block)); err != nil {
+			t.Errorf("Could not parse %s key: %v", name, err)
+		}
+	}
+
+	if _, err := PublicKeyFromPEM([]byte("not a key")); !errors.Is(err, ErrKeyMustBePEMEncoded) {
+		t.Error("Expected ErrKeyMustBePEMEncoded, got", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if _, err := PublicKeyFromPEM(privateKey); !errors.Is(err, ErrNotPublicKey) {
+		t.Error("Expected ErrNotPublicKey, got", err)
+	}
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKIXPublicKey(edKey)
+	if _, err := PublicKeyFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edDER})); !errors.Is(err, ErrUnsupportedPublicKey) {
+		t.Error("Expected ErrUnsupportedPublicKey, got", err)
+	}
+}
@@ -17,9 +17,11 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net"
 	"time"
 
+	"github.com/astarte-platform/astarte-go/interfaces"
 	"github.com/iancoleman/orderedmap"
 )
 
@@ -88,6 +90,31 @@ type deviceProtocolsResponse struct {
 	AstarteMQTTv1 AstarteMQTTv1ProtocolInformation `json:"astarte_mqtt_v1,omitempty"`
 }
 
+// DeviceCredentialsStatus represents the status of a Device as seen by the Device itself through the Pairing API
+type DeviceCredentialsStatus struct {
+	// Status is the status of the Device, e.g. "pending" until it obtains its first certificate, "confirmed"
+	// afterwards, or "inhibited"
+	Status string
+	// Inhibited is true when the Device is not allowed to obtain new credentials
+	Inhibited bool
+	// Version is the version of Astarte
+	Version string
+	// MQTTv1 holds the information to connect to Astarte through astarte_mqtt_v1
+	MQTTv1 AstarteMQTTv1ProtocolInformation
+}
+
+// MQTTv1CertificateVerification represents the outcome of the verification of an astarte_mqtt_v1 certificate
+type MQTTv1CertificateVerification struct {
+	Valid bool `json:"valid"`
+	// Timestamp is the time the certificate was verified at
+	Timestamp time.Time `json:"timestamp"`
+	// Until is the expiration time of the certificate, if it is valid
+	Until *time.Time `json:"until,omitempty"`
+	// Cause and Details report why the certificate is not valid, e.g. "EXPIRED"
+	Cause   string `json:"cause,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
 // Not exported as it's for internal use
 type getDeviceProtocolStatusResponse struct {
 	Status    string                  `json:"status,omitempty"`
@@ -133,6 +160,66 @@ type DeviceDetails struct {
 	Metadata                 map[string]string                       `json:"metadata,omitempty"`
 }
 
+// ConnectionState represents the connection status of a Device, see DeviceDetails.ConnectionState
+type ConnectionState int
+
+const (
+	// NeverConnected is the state of a Device which never connected to Astarte
+	NeverConnected ConnectionState = iota
+	// Connected is the state of a Device currently connected to Astarte
+	Connected
+	// Disconnected is the state of a Device which connected to Astarte in the past, but is not connected now
+	Disconnected
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case NeverConnected:
+		return "never connected"
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	}
+	return fmt.Sprintf("ConnectionState(%d)", int(s))
+}
+
+// ConnectionState returns the connection status of the Device, telling apart Devices which never connected from
+// the ones which are disconnected.
+func (d DeviceDetails) ConnectionState() ConnectionState {
+	switch {
+	case d.Connected:
+		return Connected
+	case d.LastConnection.IsZero():
+		return NeverConnected
+	}
+	return Disconnected
+}
+
+// HasInterface returns whether interfaceName is part of the current introspection of the Device.
+func (d DeviceDetails) HasInterface(interfaceName string) bool {
+	_, ok := d.Introspection[interfaceName]
+	return ok
+}
+
+// InterfaceVersion returns the version of interfaceName in the current introspection of the Device. The returned bool
+// is false if the Device doesn't expose interfaceName.
+func (d DeviceDetails) InterfaceVersion(interfaceName string) (interfaces.InterfaceVersion, bool) {
+	entry, ok := d.Introspection[interfaceName]
+	if !ok {
+		return interfaces.InterfaceVersion{}, false
+	}
+	return interfaces.InterfaceVersion{Major: entry.Major, Minor: entry.Minor}, true
+}
+
+// DeviceConnectionInfo holds the connection status of a Device, as reported in its DeviceDetails. LastConnection
+// and LastDisconnection are zero times if the Device never connected (or disconnected) yet.
+type DeviceConnectionInfo struct {
+	Connected         bool
+	LastConnection    time.Time
+	LastDisconnection time.Time
+}
+
 // DatastreamValue represent one single Datastream Value
 type DatastreamValue struct {
 	Value              interface{} `json:"value"`
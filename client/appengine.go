@@ -15,11 +15,15 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
@@ -28,6 +32,9 @@ import (
 
 const defaultPageSize int = 10000
 
+// maxPageSize is the largest page size Astarte accepts
+const maxPageSize int = 10000
+
 var invalidTime time.Time = time.Unix(0, 0)
 
 // AppEngineService is the API Client for AppEngine API
@@ -39,7 +46,16 @@ type AppEngineService struct {
 // GetProperties returns all the currently set Properties on a given Interface
 func (s *AppEngineService) GetProperties(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	interfaceName string) (map[string]interface{}, error) {
-	data, err := s.nestedIndividualQuery(interfaceName, realm, deviceIdentifier, deviceIdentifierType, "")
+	return s.GetPropertiesWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetPropertiesWithContext is the same as GetProperties, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetPropertiesWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...RequestOption) (map[string]interface{}, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	data, err := s.nestedIndividualQuery(ctx, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "")
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +63,53 @@ func (s *AppEngineService) GetProperties(realm string, deviceIdentifier string,
 	return parsePropertyInterface(data), nil
 }
 
+// GetProperty returns the value of the Property set on interfacePath of the given Interface. If interfacePath
+// has never been set or has been unset, the returned error wraps ErrPathNotFound.
+func (s *AppEngineService) GetProperty(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string) (interface{}, error) {
+	return s.GetPropertyWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath)
+}
+
+// GetPropertyWithContext is the same as GetProperty, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetPropertyWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, opts ...RequestOption) (interface{}, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validatePropertyPath(interfaceName, interfacePath); err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err := s.appengineGenericJSONDataAPIGet(ctx, &value, interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, "")
+	switch {
+	case isDeviceNotFound(err):
+		return nil, withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+	case isPathNotFound(err):
+		return nil, withErrorCause(err, http.StatusNotFound, ErrPathNotFound)
+	case err != nil:
+		return nil, err
+	case value == nil:
+		// Some Astarte versions reply with null data rather than a 404 for unset paths
+		return nil, ErrPathNotFound
+	}
+
+	return value, nil
+}
+
 // GetDatastreamSnapshot returns all the last values on all paths for a Datastream interface
 func (s *AppEngineService) GetDatastreamSnapshot(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	interfaceName string) (map[string]DatastreamValue, error) {
-	data, err := s.nestedIndividualQuery(interfaceName, realm, deviceIdentifier, deviceIdentifierType, "")
+	return s.GetDatastreamSnapshotWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetDatastreamSnapshotWithContext is the same as GetDatastreamSnapshot, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDatastreamSnapshotWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string,
+	opts ...RequestOption) (map[string]DatastreamValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	data, err := s.nestedIndividualQuery(ctx, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "")
 	if err != nil {
 		return nil, err
 	}
@@ -61,8 +120,72 @@ func (s *AppEngineService) GetDatastreamSnapshot(realm string, deviceIdentifier
 // GetLastDatastreams returns all the last values on a path for a Datastream interface.
 // If limit is <= 0, it returns all existing datastreams. Consider using a GetDatastreamsPaginator in that case.
 func (s *AppEngineService) GetLastDatastreams(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, limit int) ([]DatastreamValue, error) {
+	return s.GetLastDatastreamsWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, limit)
+}
+
+// GetLastDatastreamsWithContext is the same as GetLastDatastreams, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) GetLastDatastreamsWithContext(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, limit int, opts ...RequestOption) ([]DatastreamValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
-	return s.getDatastreamInternal(realm, deviceIdentifier, resolvedDeviceIdentifierType, interfaceName, interfacePath, invalidTime, invalidTime, limit, DescendingOrder)
+	return s.getDatastreamInternal(ctx, realm, deviceIdentifier, resolvedDeviceIdentifierType, interfaceName, interfacePath, invalidTime, invalidTime, limit, DescendingOrder)
+}
+
+// GetLatestDatastreamValue returns the most recent value on a path for an individual Datastream interface. If the
+// path has no values, the returned error wraps ErrNoData.
+func (s *AppEngineService) GetLatestDatastreamValue(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string) (DatastreamValue, error) {
+	return s.GetLatestDatastreamValueWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType,
+		interfaceName, interfacePath)
+}
+
+// GetLatestDatastreamValueWithContext is the same as GetLatestDatastreamValue, but ctx is used for the underlying
+// HTTP request.
+func (s *AppEngineService) GetLatestDatastreamValueWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, opts ...RequestOption) (DatastreamValue, error) {
+	values, err := s.GetLastDatastreamsWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName,
+		interfacePath, 1, opts...)
+	switch {
+	case isDeviceNotFound(err):
+		return DatastreamValue{}, withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+	case err != nil:
+		return DatastreamValue{}, withErrorCause(err, http.StatusNotFound, ErrNoData)
+	case len(values) == 0:
+		return DatastreamValue{}, ErrNoData
+	}
+
+	return values[0], nil
+}
+
+// GetDatastreamIndividualValues returns the values on a path for an individual Datastream interface in the
+// [since, to] time window, starting from the oldest one. A zero since means from the first value, while a zero to
+// means up to now. If limit is <= 0, it returns all values in the window. Consider using a
+// GetDatastreamsTimeWindowPaginator for large windows.
+func (s *AppEngineService) GetDatastreamIndividualValues(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, since, to time.Time, limit int) ([]DatastreamValue, error) {
+	return s.GetDatastreamIndividualValuesWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType,
+		interfaceName, interfacePath, since, to, limit)
+}
+
+// GetDatastreamIndividualValuesWithContext is the same as GetDatastreamIndividualValues, but ctx is used for all the
+// underlying HTTP requests.
+func (s *AppEngineService) GetDatastreamIndividualValuesWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time, limit int,
+	opts ...RequestOption) ([]DatastreamValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if since.IsZero() {
+		since = invalidTime
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	return s.getDatastreamInternal(ctx, realm, deviceIdentifier, resolvedDeviceIdentifierType, interfaceName, interfacePath,
+		since, to, limit, AscendingOrder)
 }
 
 // GetDatastreamsPaginator returns a Paginator for all the values on a path for a Datastream interface.
@@ -77,11 +200,54 @@ func (s *AppEngineService) GetDatastreamsTimeWindowPaginator(realm, deviceIdenti
 	return s.getDatastreamPaginatorInternal(realm, deviceIdentifier, resolvedDeviceIdentifierType, interfaceName, interfacePath, since, to, defaultPageSize, resultSetOrder)
 }
 
+// GetDatastreamIndividualPaginator returns a Paginator for the values on a path for an individual Datastream interface,
+// in the time window and order defined by opts, starting from the newest value by default. Each page is retrieved by
+// asking Astarte for the values following the last timestamp of the previous page, so that arbitrarily large
+// histories can be walked through without loading them in memory.
+func (s *AppEngineService) GetDatastreamIndividualPaginator(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, opts DatastreamQueryOptions) (DatastreamPaginator, error) {
+	extraQuery, err := opts.queryParameters()
+	if err != nil {
+		return DatastreamPaginator{}, err
+	}
+	since, to, pageSize := opts.Since, opts.To, opts.PageSize
+	if since.IsZero() {
+		since = invalidTime
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if pageSize <= 0 && opts.DownsampleTo > 0 {
+		pageSize = opts.DownsampleTo
+	} else if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	paginator, err := s.getDatastreamPaginatorInternal(realm, deviceIdentifier, resolvedDeviceIdentifierType, interfaceName,
+		interfacePath, since, to, pageSize, opts.order())
+	if err != nil {
+		return DatastreamPaginator{}, err
+	}
+	paginator.extraQuery = extraQuery
+	return paginator, nil
+}
+
 // GetAggregateParametricDatastreamSnapshot returns the last value for a Parametric Datastream aggregate interface
 func (s *AppEngineService) GetAggregateParametricDatastreamSnapshot(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string) (map[string]DatastreamAggregateValue, error) {
+	return s.GetAggregateParametricDatastreamSnapshotWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetAggregateParametricDatastreamSnapshotWithContext is the same as GetAggregateParametricDatastreamSnapshot,
+// but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetAggregateParametricDatastreamSnapshotWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string,
+	opts ...RequestOption) (map[string]DatastreamAggregateValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	// It's a snapshot, so limit=1
 	snapshot := orderedmap.OrderedMap{}
-	if err := s.appengineGenericJSONDataAPIGet(&snapshot, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "limit=1"); err != nil {
+	if err := s.appengineGenericJSONDataAPIGet(ctx, &snapshot, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "limit=1"); err != nil {
 		return nil, err
 	}
 
@@ -95,8 +261,19 @@ func (s *AppEngineService) GetAggregateParametricDatastreamSnapshot(realm, devic
 
 // GetAggregateDatastreamSnapshot returns the last value for a non-parametric, Datastream aggregate interface
 func (s *AppEngineService) GetAggregateDatastreamSnapshot(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName string) (DatastreamAggregateValue, error) {
+	return s.GetAggregateDatastreamSnapshotWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetAggregateDatastreamSnapshotWithContext is the same as GetAggregateDatastreamSnapshot, but ctx is used for the
+// underlying HTTP request.
+func (s *AppEngineService) GetAggregateDatastreamSnapshotWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string,
+	opts ...RequestOption) (DatastreamAggregateValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	// It's a snapshot, so limit=1
-	datastreams, err := s.aggregateDatastreamQuery(interfaceName, realm, deviceIdentifier, deviceIdentifierType, "limit=1")
+	datastreams, err := s.aggregateDatastreamQuery(ctx, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "limit=1")
 	if err != nil {
 		return DatastreamAggregateValue{}, err
 	}
@@ -109,15 +286,77 @@ func (s *AppEngineService) GetAggregateDatastreamSnapshot(realm, deviceIdentifie
 	return datastreams[0], nil
 }
 
+// GetDatastreamObjectSnapshot returns the latest values for a non-parametric, Datastream object aggregated interface as
+// a map of endpoint -> value. If the interface has no data yet, an empty map is returned.
+func (s *AppEngineService) GetDatastreamObjectSnapshot(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName string) (map[string]interface{}, error) {
+	return s.GetDatastreamObjectSnapshotWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetDatastreamObjectSnapshotWithContext is the same as GetDatastreamObjectSnapshot, but ctx is used for the
+// underlying HTTP request.
+func (s *AppEngineService) GetDatastreamObjectSnapshotWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...RequestOption) (map[string]interface{}, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	// Astarte might return either an empty object or an empty array when there's no data, so decode lazily
+	raw := json.RawMessage{}
+	if err := s.appengineGenericJSONDataAPIGet(ctx, &raw, interfaceName, realm, deviceIdentifier, deviceIdentifierType, "limit=1"); err != nil {
+		return nil, err
+	}
+
+	ret := map[string]interface{}{}
+	datastreams := []DatastreamAggregateValue{}
+	if err := json.Unmarshal(raw, &datastreams); err != nil {
+		// Not an array: it's fine only if there's no data at all
+		empty := map[string]interface{}{}
+		if json.Unmarshal(raw, &empty) == nil && len(empty) == 0 {
+			return ret, nil
+		}
+		return nil, err
+	}
+	if len(datastreams) == 0 {
+		return ret, nil
+	}
+
+	for _, k := range datastreams[0].Values.Keys() {
+		ret[k], _ = datastreams[0].Values.Get(k)
+	}
+	return ret, nil
+}
+
 // GetLastAggregateDatastreams returns the last count values for a Datastream aggregate interface
 func (s *AppEngineService) GetLastAggregateDatastreams(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, count int) ([]DatastreamAggregateValue, error) {
-	return s.aggregateDatastreamQuery(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, fmt.Sprintf("limit=%v", count))
+	return s.GetLastAggregateDatastreamsWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, count)
+}
+
+// GetLastAggregateDatastreamsWithContext is the same as GetLastAggregateDatastreams, but ctx is used for the
+// underlying HTTP request.
+func (s *AppEngineService) GetLastAggregateDatastreamsWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, count int,
+	opts ...RequestOption) ([]DatastreamAggregateValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	return s.aggregateDatastreamQuery(ctx, interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, fmt.Sprintf("limit=%v", count))
 }
 
 // GetAggregateDatastreamsTimeWindow returns the last count values for a Datastream aggregate interface
 func (s *AppEngineService) GetAggregateDatastreamsTimeWindow(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time) ([]DatastreamAggregateValue, error) {
-	return s.aggregateDatastreamQuery(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType,
-		fmt.Sprintf("since=%s&to=%s", since.UTC().Format(time.RFC3339Nano), to.UTC().Format(time.RFC3339Nano)))
+	return s.GetAggregateDatastreamsTimeWindowWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, since, to)
+}
+
+// GetAggregateDatastreamsTimeWindowWithContext is the same as GetAggregateDatastreamsTimeWindow, but ctx is used for
+// the underlying HTTP request.
+func (s *AppEngineService) GetAggregateDatastreamsTimeWindowWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, since, to time.Time,
+	opts ...RequestOption) ([]DatastreamAggregateValue, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	return s.aggregateDatastreamQuery(ctx, interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType,
+		fmt.Sprintf("since=%s&to=%s", formatQueryTime(since), formatQueryTime(to)))
 }
 
 //////////
@@ -128,16 +367,30 @@ func (s *AppEngineService) GetAggregateDatastreamsTimeWindow(realm, deviceIdenti
 // with the operation, as such it is assumed that the operation will be always validated on the client side. If you have access to a native
 // Interface object, accessing this method rather than the lower level ones is advised.
 // payload must match a compatible type for the Interface path. In case of an aggregate interface, payload *must* be a
-// map[string]interface{}, and each payload will be individually checked
+// map[string]interface{}, and each payload will be individually checked. Values are encoded according to the type of
+// their mapping with interfaces.EncodeValue, e.g. []byte as base64 and time.Time as ISO8601.
 func (s *AppEngineService) SendData(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
 	astarteInterface interfaces.AstarteInterface, interfacePath string, payload interface{}) error {
+	return s.SendDataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, astarteInterface, interfacePath, payload)
+}
+
+// SendDataWithContext is the same as SendData, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SendDataWithContext(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	astarteInterface interfaces.AstarteInterface, interfacePath string, payload interface{}, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	// Perform a set of checks depending on the interface structure
 	switch {
 	case astarteInterface.Ownership == interfaces.DeviceOwnership:
 		return errors.New("cannot send data to device-owned interfaces")
 	case astarteInterface.Type == interfaces.PropertiesType, astarteInterface.Aggregation == interfaces.IndividualAggregation:
-		// In this case, validate the individual message
-		if err := interfaces.ValidateIndividualMessage(astarteInterface, interfacePath, payload); err != nil {
+		// In this case, validate the individual message and encode it according to its mapping
+		mapping, err := interfaces.InterfaceMappingFromPath(astarteInterface, interfacePath)
+		if err != nil {
+			return err
+		}
+		if payload, err = interfaces.EncodeValue(mapping, payload); err != nil {
 			return err
 		}
 	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
@@ -148,16 +401,27 @@ func (s *AppEngineService) SendData(realm, deviceIdentifier string, deviceIdenti
 		if err := interfaces.ValidateAggregateMessage(astarteInterface, interfacePath, aggregatePayload); err != nil {
 			return err
 		}
+		encodedPayload := map[string]interface{}{}
+		for k, v := range aggregatePayload {
+			// Mappings have already been validated
+			mapping, _ := interfaces.InterfaceMappingFromPath(astarteInterface, path.Join(interfacePath, k))
+			encoded, err := interfaces.EncodeValue(mapping, v)
+			if err != nil {
+				return err
+			}
+			encodedPayload[k] = encoded
+		}
+		payload = encodedPayload
 	}
 
 	// If we got here, it's time to do the right thing.
 	switch {
 	case astarteInterface.Type == interfaces.PropertiesType:
-		return s.SetProperty(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
+		return s.SetPropertyWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
 	case astarteInterface.Aggregation == interfaces.IndividualAggregation:
-		return s.SendDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
+		return s.SendDatastreamWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
 	case astarteInterface.Aggregation == interfaces.ObjectAggregation:
-		return s.SendAggregateDatastream(realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
+		return s.SendAggregateDatastreamWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, astarteInterface.Name, interfacePath, payload)
 	}
 
 	// We should never get here
@@ -168,47 +432,235 @@ func (s *AppEngineService) SendData(realm, deviceIdentifier string, deviceIdenti
 // payload must be of a type compatible with the interface's endpoint. Any errors will be returned on the server side or
 // in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised
 func (s *AppEngineService) SendDatastream(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}) error {
+	return s.SendDatastreamWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// SendDatastreamWithContext is the same as SendDatastream, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SendDatastreamWithContext(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, payload interface{}, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	if reflect.TypeOf(payload).Kind() == reflect.Map {
 		return errors.New("payload must not be a map")
 	}
-	return s.performSendRequest(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "POST")
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	return s.performSendRequest(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "POST")
+}
+
+// SendDatastreamWithTimestamp sends a datastream to the given interface without additional checks, like SendDatastream.
+// If timestamp is not nil, it is sent as the explicit timestamp of the value, otherwise Astarte will assign it
+// upon reception.
+func (s *AppEngineService) SendDatastreamWithTimestamp(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, payload interface{}, timestamp *time.Time) error {
+	return s.SendDatastreamWithTimestampWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType,
+		interfaceName, interfacePath, payload, timestamp)
+}
+
+// SendDatastreamWithTimestampWithContext is the same as SendDatastreamWithTimestamp, but ctx is used for the underlying
+// HTTP request.
+func (s *AppEngineService) SendDatastreamWithTimestampWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}, timestamp *time.Time,
+	opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if reflect.TypeOf(payload).Kind() == reflect.Map {
+		return errors.New("payload must not be a map")
+	}
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	return s.performSendRequestWithTimestamp(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath,
+		payload, timestamp, "POST")
 }
 
 // SendAggregateDatastream sends an aggregate datastream to the given interface without additional checks.
 // payload must be a map. Any errors will be returned on the server side or
 // in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised
 func (s *AppEngineService) SendAggregateDatastream(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}) error {
+	return s.SendAggregateDatastreamWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// SendAggregateDatastreamWithContext is the same as SendAggregateDatastream, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SendAggregateDatastreamWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{},
+	opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	if reflect.TypeOf(payload).Kind() != reflect.Map {
 		return errors.New("payload must be a map")
 	}
-	return s.performSendRequest(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "POST")
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	return s.performSendRequest(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "POST")
+}
+
+// SendDatastreamObject sends an object to an object aggregated Datastream interface without checking it against
+// the interface definition. values maps the last token of each endpoint (e.g. "value" for "/%{sensor}/value")
+// to its value, and is sent under basePath (e.g. "/mySensor"). If timestamp is not nil, it is sent as the
+// explicit timestamp of the object, otherwise Astarte will assign it upon reception. To check basePath and
+// values against the interface before sending them, pass WithInterfaceValidation to SendDatastreamObjectWithContext.
+func (s *AppEngineService) SendDatastreamObject(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, basePath string, values map[string]interface{}, timestamp *time.Time) error {
+	return s.SendDatastreamObjectWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType,
+		interfaceName, basePath, values, timestamp)
+}
+
+// SendDatastreamObjectWithContext is the same as SendDatastreamObject, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SendDatastreamObjectWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, basePath string, values map[string]interface{}, timestamp *time.Time,
+	opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validateObjectBasePath(basePath, values); err != nil {
+		return err
+	}
+	if err := validateObjectPaths(opts, interfaceName, strings.TrimSuffix(basePath, "/"), values); err != nil {
+		return err
+	}
+	return s.performSendRequestWithTimestamp(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName,
+		strings.TrimSuffix(basePath, "/"), values, timestamp, "POST")
 }
 
 // SetProperty sets a property on the given interface without additional checks. payload must be of a type
 // compatible with the interface's endpoint Any errors will be returned on the server side or
 // in payload marshaling. If you have a native AstarteInterface object, calling SendData is advised
 func (s *AppEngineService) SetProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}) error {
-	return s.performSendRequest(realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "PUT")
+	return s.SetPropertyWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload)
+}
+
+// SetPropertyWithContext is the same as SetProperty, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SetPropertyWithContext(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, payload interface{}, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validatePropertyPath(interfaceName, interfacePath); err != nil {
+		return err
+	}
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	return s.performSendRequest(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, "PUT")
+}
+
+// UnsetProperty unsets a property on the given interface. The mapping must allow unsetting, otherwise
+// Astarte will return an error.
+func (s *AppEngineService) UnsetProperty(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string) error {
+	return s.UnsetPropertyWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath)
+}
+
+// UnsetPropertyWithContext is the same as UnsetProperty, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) UnsetPropertyWithContext(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validatePropertyPath(interfaceName, interfacePath); err != nil {
+		return err
+	}
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	url, err := s.appengineGenericJSONDataAPIURL(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, "")
+	if err != nil {
+		return err
+	}
+
+	return s.client.genericJSONDataAPIDelete(ctx, url.String(), 204)
+}
+
+// DeleteDatastreamValue deletes the values stored on interfacePath of the given Datastream Interface. Deleting
+// Datastream values is not supported by all Astarte versions: in that case, the returned error wraps
+// ErrUnsupportedByAstarte. To unset a Property, use UnsetProperty instead.
+func (s *AppEngineService) DeleteDatastreamValue(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string) error {
+	return s.DeleteDatastreamValueWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath)
+}
+
+// DeleteDatastreamValueWithContext is the same as DeleteDatastreamValue, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) DeleteDatastreamValueWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validatePropertyPath(interfaceName, interfacePath); err != nil {
+		return err
+	}
+	if err := validateInterfacePath(opts, interfaceName, interfacePath); err != nil {
+		return err
+	}
+	url, err := s.appengineGenericJSONDataAPIURL(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, "")
+	if err != nil {
+		return err
+	}
+
+	err = s.client.genericJSONDataAPIDelete(ctx, url.String(), 204)
+	switch {
+	case isDeviceNotFound(err):
+		return withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+	case isPathNotFound(err):
+		return withErrorCause(err, http.StatusNotFound, ErrPathNotFound)
+	}
+	return withUnsupportedCause(err)
+}
+
+// DeleteInterfaceData deletes all the data a Device has on the given Interface, on all of its paths. Deleting
+// Interface data is not supported by all Astarte versions: in that case, the returned error wraps
+// ErrUnsupportedByAstarte.
+func (s *AppEngineService) DeleteInterfaceData(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName string) error {
+	return s.DeleteInterfaceDataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// DeleteInterfaceDataWithContext is the same as DeleteInterfaceData, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) DeleteInterfaceDataWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if interfaceName == "" {
+		return errors.New("interface name must not be empty")
+	}
+	url, err := s.appengineGenericJSONDataAPIURL(interfaceName, realm, deviceIdentifier, deviceIdentifierType, "")
+	if err != nil {
+		return err
+	}
+
+	err = s.client.genericJSONDataAPIDelete(ctx, url.String(), 204)
+	switch {
+	case isDeviceNotFound(err):
+		return withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+	case isRouteNotFound(err):
+		// Astarte versions without the endpoint have no route for it at all
+		return withErrorCause(err, http.StatusNotFound, ErrUnsupportedByAstarte)
+	}
+	return withUnsupportedCause(err)
 }
 
 //////////
 // Private APIs: These abstract the real calls and do custom decoding of the different reply types
 //////////
 
-func (s *AppEngineService) nestedIndividualQuery(urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) (map[string]interface{}, error) {
+func (s *AppEngineService) nestedIndividualQuery(ctx context.Context, urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) (map[string]interface{}, error) {
 	ret := map[string]interface{}{}
-	err := s.appengineGenericJSONDataAPIGet(&ret, urlPath, realm, deviceIdentifier, deviceIdentifierType, rawQuery)
+	err := s.appengineGenericJSONDataAPIGet(ctx, &ret, urlPath, realm, deviceIdentifier, deviceIdentifierType, rawQuery)
 
 	return ret, err
 }
 
-func (s *AppEngineService) aggregateDatastreamQuery(urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) ([]DatastreamAggregateValue, error) {
+func (s *AppEngineService) aggregateDatastreamQuery(ctx context.Context, urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) ([]DatastreamAggregateValue, error) {
 	ret := []DatastreamAggregateValue{}
-	err := s.appengineGenericJSONDataAPIGet(&ret, urlPath, realm, deviceIdentifier, deviceIdentifierType, rawQuery)
+	err := s.appengineGenericJSONDataAPIGet(ctx, &ret, urlPath, realm, deviceIdentifier, deviceIdentifierType, rawQuery)
 
 	return ret, err
 }
-
 func (s *AppEngineService) appengineGenericJSONDataAPIURL(urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) (*url.URL, error) {
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, err := url.Parse(s.appEngineURL.String())
@@ -223,16 +675,16 @@ func (s *AppEngineService) appengineGenericJSONDataAPIURL(urlPath, realm, device
 	return callURL, nil
 }
 
-func (s *AppEngineService) appengineGenericJSONDataAPIGet(ret interface{}, urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) error {
+func (s *AppEngineService) appengineGenericJSONDataAPIGet(ctx context.Context, ret interface{}, urlPath, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, rawQuery string) error {
 	url, err := s.appengineGenericJSONDataAPIURL(urlPath, realm, deviceIdentifier, deviceIdentifierType, rawQuery)
 	if err != nil {
 		return err
 	}
 
-	return s.client.genericJSONDataAPIGET(ret, url.String(), 200)
+	return s.client.genericJSONDataAPIGET(ctx, ret, url.String(), 200)
 }
 
-func (s *AppEngineService) getDatastreamInternal(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string,
+func (s *AppEngineService) getDatastreamInternal(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string,
 	since, to time.Time, limit int, resultSetOrder ResultSetOrder) ([]DatastreamValue, error) {
 	realLimit := limit
 	if limit < 0 || limit > defaultPageSize {
@@ -246,7 +698,7 @@ func (s *AppEngineService) getDatastreamInternal(realm, deviceIdentifier string,
 
 	var resultSet []DatastreamValue
 	for ok := true; ok; ok = datastreamPaginator.HasNextPage() {
-		page, err := datastreamPaginator.GetNextPage()
+		page, err := datastreamPaginator.GetNextPageWithContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -258,7 +710,7 @@ func (s *AppEngineService) getDatastreamInternal(realm, deviceIdentifier string,
 				return append(resultSet, page...), nil
 			} else if totalSize > limit {
 				missingSamples := limit - len(resultSet)
-				return append(resultSet, page[0:missingSamples-1]...), nil
+				return append(resultSet, page[:missingSamples]...), nil
 			}
 		}
 
@@ -288,12 +740,91 @@ func (s *AppEngineService) getDatastreamPaginatorInternal(realm, deviceIdentifie
 	return datastreamPaginator, nil
 }
 
-func (s *AppEngineService) performSendRequest(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}, method string) error {
+func validatePropertyPath(interfaceName, interfacePath string) error {
+	if interfaceName == "" {
+		return errors.New("interfaceName must not be empty")
+	}
+	if !strings.HasPrefix(interfacePath, "/") {
+		return fmt.Errorf("interfacePath %s must begin with /", interfacePath)
+	}
+	return nil
+}
+
+func validateObjectBasePath(basePath string, values map[string]interface{}) error {
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		return fmt.Errorf("basePath %s must begin with /", basePath)
+	}
+	if len(values) == 0 {
+		return errors.New("values must not be empty")
+	}
+	lastToken := path.Base(basePath)
+	for k := range values {
+		if k == "" || strings.Contains(k, "/") {
+			return fmt.Errorf("invalid key %s: values must contain the last endpoint token only, without slashes", k)
+		}
+		// basePath must not point to one of the individual endpoints of the object
+		if k == lastToken {
+			return fmt.Errorf("basePath %s must not include the endpoint %s", basePath, k)
+		}
+	}
+	return nil
+}
+
+func (s *AppEngineService) performSendRequest(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, interfaceName, interfacePath string, payload interface{}, method string) error {
+	url, err := s.appengineGenericJSONDataAPIURL(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, "")
+	if err != nil {
+		return err
+	}
+
+	// Normalize payload encoding bytes, given we're using JSON
+	return s.client.genericJSONDataAPIWriteNoResponse(ctx, method, url.String(), interfaces.NormalizePayload(payload, true), 200)
+}
+
+func (s *AppEngineService) performSendRequestWithTimestamp(ctx context.Context, realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName, interfacePath string, payload interface{}, timestamp *time.Time, method string) error {
+	if timestamp == nil {
+		return s.performSendRequest(ctx, realm, deviceIdentifier, deviceIdentifierType, interfaceName, interfacePath, payload, method)
+	}
+
 	url, err := s.appengineGenericJSONDataAPIURL(interfaceName+interfacePath, realm, deviceIdentifier, deviceIdentifierType, "")
 	if err != nil {
 		return err
 	}
 
+	var requestBody struct {
+		Data      interface{} `json:"data"`
+		Timestamp time.Time   `json:"timestamp"`
+	}
 	// Normalize payload encoding bytes, given we're using JSON
-	return s.client.genericJSONDataAPIWriteNoResponse(method, url.String(), interfaces.NormalizePayload(payload, true), 200)
+	requestBody.Data = interfaces.NormalizePayload(payload, true)
+	requestBody.Timestamp = timestamp.UTC()
+
+	return s.client.genericJSONAPIWriteRequestBody(ctx, nil, method, url.String(), requestBody, "application/json", 200)
+}
+
+// isDeviceNotFound returns whether err is a 404 reporting the Device does not exist, as opposed to a 404 on
+// a path or Interface of an existing Device.
+func isDeviceNotFound(err error) bool {
+	var apiError *AstarteAPIError
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound && apiError.Detail == "Device not found"
+}
+
+// isPathNotFound returns whether err is a 404 reporting that a path of an existing Device has no value.
+func isPathNotFound(err error) bool {
+	var apiError *AstarteAPIError
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound && apiError.Detail == "Path not found"
+}
+
+// isRouteNotFound returns whether err is the 404 Astarte replies with when no route matches the request, as
+// opposed to a 404 about a Device or one of its paths.
+func isRouteNotFound(err error) bool {
+	var apiError *AstarteAPIError
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound && apiError.Detail == "Not Found"
+}
+
+// withUnsupportedCause makes err wrap ErrUnsupportedByAstarte if its status code reports that the requested
+// method is not implemented by Astarte.
+func withUnsupportedCause(err error) error {
+	err = withErrorCause(err, http.StatusMethodNotAllowed, ErrUnsupportedByAstarte)
+	return withErrorCause(err, http.StatusNotImplemented, ErrUnsupportedByAstarte)
 }
@@ -15,9 +15,17 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
+	"sync"
+
+	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/interfaces"
 )
 
 // This file contains all API Calls related to device management and information such as aliases, stats...
@@ -35,20 +43,40 @@ const (
 	AstarteDeviceAlias
 )
 
+// DetectDeviceIdentifierType returns the kind of identifier AutodiscoverDeviceIdentifier resolves identifier to.
+// identifier is considered a Device ID if it is a well formed Device ID, i.e. a 22 characters long base64url
+// encoding of 128 bits, and an alias otherwise. Note that an alias could still be a well formed Device ID: if
+// your aliases can be, always pass AstarteDeviceAlias explicitly.
+func DetectDeviceIdentifierType(identifier string) DeviceIdentifierType {
+	if deviceid.ValidateDeviceID(identifier) {
+		return AstarteDeviceID
+	}
+	return AstarteDeviceAlias
+}
+
 // ListDevices returns the list of Device IDs for all Devices in the Realm. The
 // returned result can be large, GetDeviceListPaginator can be used instead to
 // retrieve the device list incrementally.
 func (s *AppEngineService) ListDevices(realm string) ([]string, error) {
+	return s.ListDevicesWithContext(context.Background(), realm)
+}
+
+// ListDevicesWithContext is the same as ListDevices, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) ListDevicesWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	result := []string{}
 
-	paginator, err := s.GetDeviceListPaginator(realm, defaultPageSize, DeviceIDFormat)
+	paginator, err := s.GetDeviceListPaginator(realm, s.client.devicesPageSize, DeviceIDFormat)
 	if err != nil {
 		return result, err
 	}
 
 	for hasNext := paginator.HasNextPage(); hasNext; hasNext = paginator.HasNextPage() {
 		page := []string{}
-		err := paginator.GetNextPage(&page)
+		err := paginator.GetNextPageWithContext(ctx, &page)
 		if err != nil {
 			return []string{}, err
 		}
@@ -58,21 +86,102 @@ func (s *AppEngineService) ListDevices(realm string) ([]string, error) {
 	return result, nil
 }
 
+// ListDevicesLimited returns the Device IDs of at most max Devices in the Realm, fetching no more pages than
+// needed: the last page requested is shrunk to the number of Device IDs still missing.
+func (s *AppEngineService) ListDevicesLimited(realm string, max int) ([]string, error) {
+	return s.ListDevicesLimitedWithContext(context.Background(), realm, max)
+}
+
+// ListDevicesLimitedWithContext is the same as ListDevicesLimited, but ctx is used for all the underlying HTTP
+// requests.
+func (s *AppEngineService) ListDevicesLimitedWithContext(ctx context.Context, realm string, max int,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	result := []string{}
+
+	paginator, err := s.GetDeviceListPaginator(realm, s.client.devicesPageSize, DeviceIDFormat)
+	if err != nil {
+		return result, err
+	}
+	pageSize := paginator.pageSize
+
+	for len(result) < max && paginator.HasNextPage() {
+		if remaining := max - len(result); pageSize <= 0 || remaining < pageSize {
+			paginator.pageSize = remaining
+		}
+		page := []string{}
+		if err := paginator.GetNextPageWithContext(ctx, &page); err != nil {
+			return []string{}, err
+		}
+		result = append(result, page...)
+	}
+
+	if len(result) > max {
+		result = result[:max]
+	}
+	return result, nil
+}
+
+// StreamDevices lists the Devices in the Realm in a separate goroutine, delivering their IDs on the first returned
+// channel as pages are fetched. The channel is closed once all Devices have been delivered, when an error occurs or
+// when ctx is done. In the latter cases, the error, such as the one of ctx, is delivered on the second channel,
+// which is always closed after the first one and receives at most one error.
+func (s *AppEngineService) StreamDevices(ctx context.Context, realm string) (<-chan string, <-chan error) {
+	deviceIDs := make(chan string)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(deviceIDs)
+
+		paginator, err := s.GetDeviceListPaginator(realm, s.client.devicesPageSize, DeviceIDFormat)
+		if err != nil {
+			errs <- err
+			return
+		}
+		err = paginator.ForEachWithContext(ctx, func(deviceID string) error {
+			select {
+			case deviceIDs <- deviceID:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return deviceIDs, errs
+}
+
 // ListDevicesWithDetails returns a list of all Devices in the Realm, each
 // represented by a DeviceDetails struct. The returned result can be large,
 // GetDeviceListPaginator can be used instead to retrieve the device list
 // incrementally.
 func (s *AppEngineService) ListDevicesWithDetails(realm string) ([]DeviceDetails, error) {
+	return s.ListDevicesWithDetailsWithContext(context.Background(), realm)
+}
+
+// ListDevicesWithDetailsWithContext is the same as ListDevicesWithDetails, but ctx is used for all the
+// underlying HTTP requests.
+func (s *AppEngineService) ListDevicesWithDetailsWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) ([]DeviceDetails, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	result := []DeviceDetails{}
 
-	paginator, err := s.GetDeviceListPaginator(realm, defaultPageSize, DeviceDetailsFormat)
+	paginator, err := s.GetDeviceListPaginator(realm, s.client.devicesPageSize, DeviceDetailsFormat)
 	if err != nil {
 		return result, err
 	}
 
 	for hasNext := paginator.HasNextPage(); hasNext; hasNext = paginator.HasNextPage() {
 		page := []DeviceDetails{}
-		err := paginator.GetNextPage(&page)
+		err := paginator.GetNextPageWithContext(ctx, &page)
 		if err != nil {
 			return []DeviceDetails{}, err
 		}
@@ -86,6 +195,9 @@ func (s *AppEngineService) ListDevicesWithDetails(realm string) ([]DeviceDetails
 // The paginator can return different result formats depending on the format
 // parameter.
 func (s *AppEngineService) GetDeviceListPaginator(realm string, pageSize int, format DeviceResultFormat) (DeviceListPaginator, error) {
+	if format != DeviceIDFormat && format != DeviceDetailsFormat {
+		return DeviceListPaginator{}, fmt.Errorf("invalid device result format %d", format)
+	}
 	callURL, err := url.Parse(s.appEngineURL.String())
 	if err != nil {
 		return DeviceListPaginator{}, err
@@ -94,6 +206,7 @@ func (s *AppEngineService) GetDeviceListPaginator(realm string, pageSize int, fo
 	query := url.Values{}
 
 	deviceListPaginator := DeviceListPaginator{
+		realm:       realm,
 		baseURL:     callURL,
 		nextQuery:   query,
 		format:      format,
@@ -104,25 +217,406 @@ func (s *AppEngineService) GetDeviceListPaginator(realm string, pageSize int, fo
 	return deviceListPaginator, nil
 }
 
-// GetDevice returns the DeviceDetails of a single Device in the Realm
+// RestoreDeviceListPaginator returns a DeviceListPaginator for the Devices in realm, positioned where the one
+// state was taken from with DeviceListPaginator.State was.
+func (s *AppEngineService) RestoreDeviceListPaginator(realm string, state []byte) (DeviceListPaginator, error) {
+	paginatorState := deviceListPaginatorState{}
+	if err := json.Unmarshal(state, &paginatorState); err != nil {
+		return DeviceListPaginator{}, fmt.Errorf("invalid paginator state: %w", err)
+	}
+	if paginatorState.Realm != realm {
+		return DeviceListPaginator{}, fmt.Errorf("paginator state is for realm %s, not %s", paginatorState.Realm, realm)
+	}
+	nextQuery, err := url.ParseQuery(paginatorState.NextQuery)
+	if err != nil {
+		return DeviceListPaginator{}, fmt.Errorf("invalid paginator state: %w", err)
+	}
+
+	paginator, err := s.GetDeviceListPaginator(realm, paginatorState.PageSize, paginatorState.Format)
+	if err != nil {
+		return DeviceListPaginator{}, err
+	}
+	paginator.nextQuery = nextQuery
+	paginator.hasNextPage = paginatorState.HasNextPage
+	return paginator, nil
+}
+
+// GetDeviceListDetailsPaginator returns a DeviceDetailsPaginator for all the Devices in the realm.
+// Each page contains the full DeviceDetails of the Devices, avoiding a GetDevice call per Device.
+// If any filters are given, only Devices matching all of them will be returned.
+func (s *AppEngineService) GetDeviceListDetailsPaginator(realm string, pageSize int, filters ...DeviceFilter) (DeviceDetailsPaginator, error) {
+	paginator, err := s.GetDeviceListPaginator(realm, pageSize, DeviceDetailsFormat)
+	if err != nil {
+		return DeviceDetailsPaginator{}, err
+	}
+
+	return DeviceDetailsPaginator{paginator: paginator, filters: filters}, nil
+}
+
+// ListConnectedDevices returns the list of Device IDs for all currently connected Devices in the Realm.
+// As Astarte does not filter Devices on the server side, this call goes through the whole Device list.
+func (s *AppEngineService) ListConnectedDevices(realm string) ([]string, error) {
+	return s.ListConnectedDevicesWithContext(context.Background(), realm)
+}
+
+// ListConnectedDevicesWithContext is the same as ListConnectedDevices, but ctx is used for all the underlying
+// HTTP requests.
+func (s *AppEngineService) ListConnectedDevicesWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	return s.listFilteredDevices(ctx, realm, FilterConnected(true))
+}
+
+// ListDisconnectedDevices returns the list of Device IDs for all currently disconnected Devices in the Realm.
+// As Astarte does not filter Devices on the server side, this call goes through the whole Device list.
+func (s *AppEngineService) ListDisconnectedDevices(realm string) ([]string, error) {
+	return s.ListDisconnectedDevicesWithContext(context.Background(), realm)
+}
+
+// ListDisconnectedDevicesWithContext is the same as ListDisconnectedDevices, but ctx is used for all the
+// underlying HTTP requests.
+func (s *AppEngineService) ListDisconnectedDevicesWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	return s.listFilteredDevices(ctx, realm, FilterConnected(false))
+}
+
+func (s *AppEngineService) listFilteredDevices(ctx context.Context, realm string, filters ...DeviceFilter) ([]string, error) {
+	result := []string{}
+
+	paginator, err := s.GetDeviceListDetailsPaginator(realm, s.client.devicesPageSize, filters...)
+	if err != nil {
+		return result, err
+	}
+
+	for hasNext := paginator.HasNextPage(); hasNext; hasNext = paginator.HasNextPage() {
+		page, err := paginator.GetNextPageWithContext(ctx)
+		if err != nil {
+			return []string{}, err
+		}
+		for _, device := range page {
+			result = append(result, device.DeviceID)
+		}
+	}
+
+	return result, nil
+}
+
+// GetDevice returns the DeviceDetails of a single Device in the Realm. If the Device does not exist,
+// the returned error wraps ErrDeviceNotFound.
 func (s *AppEngineService) GetDevice(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (DeviceDetails, error) {
+	return s.GetDeviceWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceWithContext is the same as GetDevice, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (DeviceDetails, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	callURL, _ := url.Parse(s.appEngineURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
+	deviceDetails := DeviceDetails{}
+	err := s.client.genericJSONDataAPIGET(ctx, &deviceDetails, callURL.String(), 200)
+
+	return deviceDetails, withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+}
+
+// GetDeviceRaw is the same as GetDevice, but it also returns the data of the reply of Astarte as is, so that fields
+// not modeled by DeviceDetails can be inspected.
+func (s *AppEngineService) GetDeviceRaw(realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType) (DeviceDetails, json.RawMessage, error) {
+	return s.GetDeviceRawWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceRawWithContext is the same as GetDeviceRaw, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceRawWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (DeviceDetails, json.RawMessage, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
+	raw := json.RawMessage{}
+	if err := s.client.genericJSONDataAPIGET(ctx, &raw, callURL.String(), 200); err != nil {
+		return DeviceDetails{}, nil, withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+	}
+
 	deviceDetails := DeviceDetails{}
-	err := s.client.genericJSONDataAPIGET(&deviceDetails, callURL.String(), 200)
+	err := json.Unmarshal(raw, &deviceDetails)
+	return deviceDetails, raw, err
+}
+
+// GetDevicesDetails returns the DeviceDetails of all the Devices in deviceIDs, performing at most concurrency requests
+// at a time (at least one). Requests are subject to the rate limit of the Client, if any. Failures don't stop the
+// batch: the returned maps hold the details of each Device which was fetched successfully, and the error of each
+// one which was not.
+func (s *AppEngineService) GetDevicesDetails(realm string, deviceIDs []string, concurrency int) (map[string]DeviceDetails, map[string]error) {
+	return s.GetDevicesDetailsWithContext(context.Background(), realm, deviceIDs, concurrency)
+}
+
+// GetDevicesDetailsWithContext is the same as GetDevicesDetails, but ctx is used for all the underlying HTTP requests.
+// Devices which have not been fetched yet when ctx is done are reported with ctx's error.
+func (s *AppEngineService) GetDevicesDetailsWithContext(ctx context.Context, realm string, deviceIDs []string, concurrency int,
+	opts ...RequestOption) (map[string]DeviceDetails, map[string]error) {
+	details := map[string]DeviceDetails{}
+	var lock sync.Mutex
+	errs := forEachDeviceConcurrently(ctx, deviceIDs, concurrency, func(deviceID string) error {
+		deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceID, AstarteDeviceID, opts...)
+		if err == nil {
+			lock.Lock()
+			details[deviceID] = deviceDetails
+			lock.Unlock()
+		}
+		return err
+	})
+
+	return details, errs
+}
+
+// GetValueForAllDevices returns the value on interfacePath of interfaceName for every Device in the Realm, keyed by
+// Device ID: the value of the Property for Properties, or the last sample for Datastreams. Only the last sample is
+// fetched, and it is returned as reported by Astarte, i.e. as an object holding the value (or the values of an
+// object aggregated Interface) along with its timestamp. Astarte has no API to query all Devices at once, so this lists all the Devices and performs one request per
+// Device, at most concurrency at a time (at least one): use it with care on large Realms. Failures on single
+// Devices don't stop the operation, and are returned in the second map. Devices which have no value on
+// interfacePath, e.g. because they don't have interfaceName, fail with an error wrapping ErrPathNotFound. An error
+// is returned only if listing the Devices fails.
+func (s *AppEngineService) GetValueForAllDevices(realm, interfaceName, interfacePath string,
+	concurrency int) (map[string]interface{}, map[string]error, error) {
+	return s.GetValueForAllDevicesWithContext(context.Background(), realm, interfaceName, interfacePath, concurrency)
+}
+
+// GetValueForAllDevicesWithContext is the same as GetValueForAllDevices, but ctx is used for all the underlying HTTP
+// requests. Devices whose value has not been fetched yet when ctx is done are reported with ctx's error.
+func (s *AppEngineService) GetValueForAllDevicesWithContext(ctx context.Context, realm, interfaceName, interfacePath string,
+	concurrency int, opts ...RequestOption) (map[string]interface{}, map[string]error, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validatePropertyPath(interfaceName, interfacePath); err != nil {
+		return nil, nil, err
+	}
+	deviceIDs, err := s.ListDevicesWithContext(ctx, realm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := map[string]interface{}{}
+	var lock sync.Mutex
+	errs := forEachDeviceConcurrently(ctx, deviceIDs, concurrency, func(deviceID string) error {
+		// limit is ignored for Properties, while it makes Datastreams reply with their last sample only
+		var value interface{}
+		err := s.appengineGenericJSONDataAPIGet(ctx, &value, interfaceName+interfacePath, realm, deviceID, AstarteDeviceID, "limit=1")
+		if samples, ok := value.([]interface{}); ok && isDatastreamSamples(samples) {
+			value = samples[0]
+		}
+		switch {
+		case isDeviceNotFound(err):
+			return withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
+		case err != nil:
+			return withErrorCause(err, http.StatusNotFound, ErrPathNotFound)
+		case value == nil:
+			return ErrPathNotFound
+		}
+		lock.Lock()
+		values[deviceID] = value
+		lock.Unlock()
+		return nil
+	})
+
+	return values, errs, nil
+}
 
-	return deviceDetails, err
+// isDatastreamSamples tells whether value, a list returned by Astarte when reading an Interface path, holds
+// Datastream samples rather than being the value of an array Property. Samples are objects carrying a timestamp.
+func isDatastreamSamples(value []interface{}) bool {
+	if len(value) == 0 {
+		return false
+	}
+	sample, ok := value[0].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = sample["timestamp"]
+	return ok
+}
+
+// forEachDeviceConcurrently calls fn for each of deviceIDs, performing at most concurrency calls at a time (at least
+// one), and returns the errors of the calls which failed. Devices which have not been processed yet when ctx is done
+// are reported with ctx's error.
+func forEachDeviceConcurrently(ctx context.Context, deviceIDs []string, concurrency int, fn func(deviceID string) error) map[string]error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := map[string]error{}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for _, deviceID := range deviceIDs {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			lock.Lock()
+			errs[deviceID] = ctx.Err()
+			lock.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(deviceID string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			if err := fn(deviceID); err != nil {
+				lock.Lock()
+				errs[deviceID] = err
+				lock.Unlock()
+			}
+		}(deviceID)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// GetDeviceInterfaceStats returns the entry of interfaceName in the introspection of a Device, holding the number
+// of messages and bytes the Device exchanged on it. If interfaceName is not part of the current introspection of
+// the Device, the returned error wraps ErrInterfaceNotInIntrospection: stats of Interfaces the Device used in the
+// past are available in the PreviousInterfaces of its DeviceDetails.
+func (s *AppEngineService) GetDeviceInterfaceStats(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType,
+	interfaceName string) (DeviceInterfaceIntrospection, error) {
+	return s.GetDeviceInterfaceStatsWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, interfaceName)
+}
+
+// GetDeviceInterfaceStatsWithContext is the same as GetDeviceInterfaceStats, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceInterfaceStatsWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, interfaceName string, opts ...RequestOption) (DeviceInterfaceIntrospection, error) {
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, opts...)
+	if err != nil {
+		return DeviceInterfaceIntrospection{}, err
+	}
+
+	stats, ok := deviceDetails.Introspection[interfaceName]
+	if !ok {
+		return DeviceInterfaceIntrospection{}, fmt.Errorf("%w: %s", ErrInterfaceNotInIntrospection, interfaceName)
+	}
+	// The name is the key of the introspection map, and is not repeated in its values
+	stats.Name = interfaceName
+	return stats, nil
+}
+
+// GetDeviceTrafficStats returns the total number of bytes and messages received by Astarte from a Device, across
+// all of its current and previous Interfaces.
+func (s *AppEngineService) GetDeviceTrafficStats(realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType) (bytes int64, msgs int64, err error) {
+	return s.GetDeviceTrafficStatsWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceTrafficStatsWithContext is the same as GetDeviceTrafficStats, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceTrafficStatsWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (bytes int64, msgs int64, err error) {
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, opts...)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(deviceDetails.TotalReceivedBytes), deviceDetails.TotalReceivedMessages, nil
+}
+
+// GetDeviceConnectionInfo returns whether a Device is connected, along with the times of its last connection and
+// disconnection, e.g. to detect stale Devices.
+func (s *AppEngineService) GetDeviceConnectionInfo(realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType) (DeviceConnectionInfo, error) {
+	return s.GetDeviceConnectionInfoWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceConnectionInfoWithContext is the same as GetDeviceConnectionInfo, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceConnectionInfoWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (DeviceConnectionInfo, error) {
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, opts...)
+	if err != nil {
+		return DeviceConnectionInfo{}, err
+	}
+	return DeviceConnectionInfo{
+		Connected:         deviceDetails.Connected,
+		LastConnection:    deviceDetails.LastConnection,
+		LastDisconnection: deviceDetails.LastDisconnection,
+	}, nil
+}
+
+// ResolveDevice returns the DeviceDetails of the Device identified by identifier, which can be either a Device ID
+// or an alias, along with the kind of identifier it turned out to be. identifiers which look like a Device ID
+// are first looked up as such, then as an alias. If no Device matches, the returned error wraps ErrDeviceNotFound.
+func (s *AppEngineService) ResolveDevice(realm string, identifier string) (DeviceDetails, DeviceIdentifierType, error) {
+	return s.ResolveDeviceWithContext(context.Background(), realm, identifier)
+}
+
+// ResolveDeviceWithContext is the same as ResolveDevice, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) ResolveDeviceWithContext(ctx context.Context, realm string, identifier string,
+	opts ...RequestOption) (DeviceDetails, DeviceIdentifierType, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(identifier, AutodiscoverDeviceIdentifier)
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, identifier, resolvedDeviceIdentifierType)
+	if errors.Is(err, ErrDeviceNotFound) && resolvedDeviceIdentifierType == AstarteDeviceID {
+		// Aliases can look like Device IDs as well
+		resolvedDeviceIdentifierType = AstarteDeviceAlias
+		deviceDetails, err = s.GetDeviceWithContext(ctx, realm, identifier, resolvedDeviceIdentifierType)
+	}
+	if err != nil {
+		return DeviceDetails{}, AutodiscoverDeviceIdentifier, err
+	}
+
+	return deviceDetails, resolvedDeviceIdentifierType, nil
+}
+
+// DeleteDevice deletes a Device and all of its data from the Realm. If the Device does not exist,
+// the returned error wraps ErrDeviceNotFound.
+func (s *AppEngineService) DeleteDevice(realm string, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) error {
+	return s.DeleteDeviceWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// DeleteDeviceWithContext is the same as DeleteDevice, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) DeleteDeviceWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
+	callURL, _ := url.Parse(s.appEngineURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
+	err := s.client.genericJSONDataAPIDelete(ctx, callURL.String(), 204)
+
+	return withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
 }
 
 // GetDeviceIDFromDeviceIdentifier returns the DeviceID of a Device identified with a deviceIdentifier
 // of type deviceIdentifierType.
 func (s *AppEngineService) GetDeviceIDFromDeviceIdentifier(realm string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType) (string, error) {
+	return s.GetDeviceIDFromDeviceIdentifierWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceIDFromDeviceIdentifierWithContext is the same as GetDeviceIDFromDeviceIdentifier, but ctx is used
+// for the underlying HTTP request, if any.
+func (s *AppEngineService) GetDeviceIDFromDeviceIdentifierWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	switch resolvedDeviceIdentifierType {
 	case AstarteDeviceAlias:
-		return s.GetDeviceIDFromAlias(realm, deviceIdentifier)
+		return s.GetDeviceIDFromAliasWithContext(ctx, realm, deviceIdentifier)
 	default:
 		return deviceIdentifier, nil
 	}
@@ -130,7 +624,16 @@ func (s *AppEngineService) GetDeviceIDFromDeviceIdentifier(realm string, deviceI
 
 // GetDeviceIDFromAlias returns the Device ID of a device given one of its aliases
 func (s *AppEngineService) GetDeviceIDFromAlias(realm string, deviceAlias string) (string, error) {
-	deviceDetails, err := s.GetDevice(realm, deviceAlias, AstarteDeviceAlias)
+	return s.GetDeviceIDFromAliasWithContext(context.Background(), realm, deviceAlias)
+}
+
+// GetDeviceIDFromAliasWithContext is the same as GetDeviceIDFromAlias, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceIDFromAliasWithContext(ctx context.Context, realm string, deviceAlias string,
+	opts ...RequestOption) (string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceAlias, AstarteDeviceAlias)
 	if err != nil {
 		return "", err
 	}
@@ -138,21 +641,111 @@ func (s *AppEngineService) GetDeviceIDFromAlias(realm string, deviceAlias string
 	return deviceDetails.DeviceID, nil
 }
 
+// ResolveAliases returns the Device IDs of the Devices with the given aliases, performing at most concurrency
+// requests at a time (at least one). Failures don't stop the batch: the returned maps hold the Device ID of each
+// alias which was resolved, and the error of each one which was not. Unknown aliases fail with an error wrapping
+// ErrDeviceNotFound.
+func (s *AppEngineService) ResolveAliases(realm string, aliases []string, concurrency int) (map[string]string, map[string]error) {
+	return s.ResolveAliasesWithContext(context.Background(), realm, aliases, concurrency)
+}
+
+// ResolveAliasesWithContext is the same as ResolveAliases, but ctx is used for all the underlying HTTP requests.
+// Aliases which have not been resolved yet when ctx is done are reported with ctx's error.
+func (s *AppEngineService) ResolveAliasesWithContext(ctx context.Context, realm string, aliases []string, concurrency int,
+	opts ...RequestOption) (map[string]string, map[string]error) {
+	deviceIDs := map[string]string{}
+	var lock sync.Mutex
+	errs := forEachDeviceConcurrently(ctx, aliases, concurrency, func(alias string) error {
+		deviceID, err := s.GetDeviceIDFromAliasWithContext(ctx, realm, alias, opts...)
+		if err == nil {
+			lock.Lock()
+			deviceIDs[alias] = deviceID
+			lock.Unlock()
+		}
+		return err
+	})
+
+	return deviceIDs, errs
+}
+
 // ListDeviceInterfaces returns the list of Interfaces exposed by the Device's introspection
 func (s *AppEngineService) ListDeviceInterfaces(realm string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType) ([]string, error) {
+	return s.ListDeviceInterfacesWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// ListDeviceInterfacesWithContext is the same as ListDeviceInterfaces, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) ListDeviceInterfacesWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s/interfaces", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
 	deviceInterfacesList := []string{}
-	err := s.client.genericJSONDataAPIGET(&deviceInterfacesList, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(ctx, &deviceInterfacesList, callURL.String(), 200)
 
 	return deviceInterfacesList, err
 }
 
+// GetDeviceIntrospection returns the introspection of a Device, that is the version of each Interface it exposes.
+// Unlike ListDeviceInterfaces, it preserves the versions, which are needed to pick the right Interface definition
+// e.g. when decoding data.
+func (s *AppEngineService) GetDeviceIntrospection(realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType) (map[string]interfaces.InterfaceVersion, error) {
+	return s.GetDeviceIntrospectionWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDeviceIntrospectionWithContext is the same as GetDeviceIntrospection, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceIntrospectionWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (map[string]interfaces.InterfaceVersion, error) {
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	introspection := map[string]interfaces.InterfaceVersion{}
+	for interfaceName := range deviceDetails.Introspection {
+		introspection[interfaceName], _ = deviceDetails.InterfaceVersion(interfaceName)
+	}
+	return introspection, nil
+}
+
+// GetDevicePreviousInterfaces returns the Interfaces a Device exposed in the past and no longer exposes, e.g. after
+// a firmware upgrade bumped their major version. Each entry holds the name and version of the Interface, along with
+// the messages and bytes the Device exchanged on it.
+func (s *AppEngineService) GetDevicePreviousInterfaces(realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType) ([]DeviceInterfaceIntrospection, error) {
+	return s.GetDevicePreviousInterfacesWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// GetDevicePreviousInterfacesWithContext is the same as GetDevicePreviousInterfaces, but ctx is used for the underlying
+// HTTP request.
+func (s *AppEngineService) GetDevicePreviousInterfacesWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) ([]DeviceInterfaceIntrospection, error) {
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if deviceDetails.PreviousInterfaces == nil {
+		return []DeviceInterfaceIntrospection{}, nil
+	}
+	return deviceDetails.PreviousInterfaces, nil
+}
+
 // ListDeviceAliases is an helper to list all aliases of a Device
 func (s *AppEngineService) ListDeviceAliases(realm string, deviceID string) (map[string]string, error) {
-	deviceDetails, err := s.GetDevice(realm, deviceID, AstarteDeviceID)
+	return s.ListDeviceAliasesWithContext(context.Background(), realm, deviceID)
+}
+
+// ListDeviceAliasesWithContext is the same as ListDeviceAliases, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) ListDeviceAliasesWithContext(ctx context.Context, realm string, deviceID string,
+	opts ...RequestOption) (map[string]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceID, AstarteDeviceID)
 	if err != nil {
 		return nil, err
 	}
@@ -161,10 +754,34 @@ func (s *AppEngineService) ListDeviceAliases(realm string, deviceID string) (map
 
 // AddDeviceAlias adds an Alias to a Device
 func (s *AppEngineService) AddDeviceAlias(realm string, deviceID string, aliasTag string, deviceAlias string) error {
+	return s.AddDeviceAliasWithContext(context.Background(), realm, deviceID, aliasTag, deviceAlias)
+}
+
+// AddDeviceAliasWithContext is the same as AddDeviceAlias, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) AddDeviceAliasWithContext(ctx context.Context, realm string, deviceID string, aliasTag string,
+	deviceAlias string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	return s.AddDeviceAliasesWithContext(ctx, realm, deviceID, map[string]string{aliasTag: deviceAlias})
+}
+
+// AddDeviceAliases adds a set of Aliases to a Device in a single request. aliases maps each Alias tag
+// to its Alias.
+func (s *AppEngineService) AddDeviceAliases(realm string, deviceID string, aliases map[string]string) error {
+	return s.AddDeviceAliasesWithContext(context.Background(), realm, deviceID, aliases)
+}
+
+// AddDeviceAliasesWithContext is the same as AddDeviceAliases, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) AddDeviceAliasesWithContext(ctx context.Context, realm string, deviceID string, aliases map[string]string,
+	opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s", realm, deviceID))
-	payload := map[string]map[string]string{"aliases": {aliasTag: deviceAlias}}
-	err := s.client.genericJSONDataAPIPatch(callURL.String(), payload, 200)
+	payload := map[string]map[string]string{"aliases": aliases}
+	err := s.client.genericJSONDataAPIPatch(ctx, callURL.String(), payload, 200)
 	if err != nil {
 		return err
 	}
@@ -174,12 +791,21 @@ func (s *AppEngineService) AddDeviceAlias(realm string, deviceID string, aliasTa
 
 // DeleteDeviceAlias deletes an Alias from a Device based on the Alias' tag
 func (s *AppEngineService) DeleteDeviceAlias(realm string, deviceID string, aliasTag string) error {
+	return s.DeleteDeviceAliasWithContext(context.Background(), realm, deviceID, aliasTag)
+}
+
+// DeleteDeviceAliasWithContext is the same as DeleteDeviceAlias, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) DeleteDeviceAliasWithContext(ctx context.Context, realm string, deviceID string, aliasTag string,
+	opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s", realm, deviceID))
 	// We're using map[string]interface{} rather than map[string]string since we want to have null
 	// rather than an empty string in the JSON payload, and this is the only way.
 	payload := map[string]map[string]interface{}{"aliases": {aliasTag: nil}}
-	err := s.client.genericJSONDataAPIPatch(callURL.String(), payload, 200)
+	err := s.client.genericJSONDataAPIPatch(ctx, callURL.String(), payload, 200)
 	if err != nil {
 		return err
 	}
@@ -190,11 +816,20 @@ func (s *AppEngineService) DeleteDeviceAlias(realm string, deviceID string, alia
 // InhibitDevice sets the Credentials Inhibition state of a Device
 func (s *AppEngineService) InhibitDevice(realm string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType, inhibit bool) error {
+	return s.InhibitDeviceWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, inhibit)
+}
+
+// InhibitDeviceWithContext is the same as InhibitDevice, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) InhibitDeviceWithContext(ctx context.Context, realm string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, inhibit bool, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
 	payload := map[string]bool{"credentials_inhibited": inhibit}
-	err := s.client.genericJSONDataAPIPatch(callURL.String(), payload, 200)
+	err := s.client.genericJSONDataAPIPatch(ctx, callURL.String(), payload, 200)
 	if err != nil {
 		return err
 	}
@@ -204,17 +839,54 @@ func (s *AppEngineService) InhibitDevice(realm string, deviceIdentifier string,
 
 // GetDevicesStats returns the DevicesStats of a Realm
 func (s *AppEngineService) GetDevicesStats(realm string) (DevicesStats, error) {
+	return s.GetDevicesStatsWithContext(context.Background(), realm)
+}
+
+// GetDevicesStatsWithContext is the same as GetDevicesStats, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDevicesStatsWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) (DevicesStats, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/stats/devices", realm))
 	deviceStats := DevicesStats{}
-	err := s.client.genericJSONDataAPIGET(&deviceStats, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(ctx, &deviceStats, callURL.String(), 200)
 
 	return deviceStats, err
 }
 
+// GetDeviceCount returns the number of Devices in a Realm, and how many of them are connected. It reads
+// DevicesStats, so it is much cheaper than counting the result of ListDevices.
+func (s *AppEngineService) GetDeviceCount(realm string) (total int, connected int, err error) {
+	return s.GetDeviceCountWithContext(context.Background(), realm)
+}
+
+// GetDeviceCountWithContext is the same as GetDeviceCount, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) GetDeviceCountWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) (total int, connected int, err error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	stats, err := s.GetDevicesStatsWithContext(ctx, realm)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(stats.TotalDevices), int(stats.ConnectedDevices), nil
+}
+
 // ListDeviceMetadata is an helper to list all Metadata of a Device
 func (s *AppEngineService) ListDeviceMetadata(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) (map[string]string, error) {
-	deviceDetails, err := s.GetDevice(realm, deviceIdentifier, deviceIdentifierType)
+	return s.ListDeviceMetadataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType)
+}
+
+// ListDeviceMetadataWithContext is the same as ListDeviceMetadata, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) ListDeviceMetadataWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) (map[string]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType)
 	if err != nil {
 		return nil, err
 	}
@@ -223,11 +895,20 @@ func (s *AppEngineService) ListDeviceMetadata(realm, deviceIdentifier string, de
 
 // SetDeviceMetadata sets a Metadata key to a certain value for a Device
 func (s *AppEngineService) SetDeviceMetadata(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, metadataKey, metadataValue string) error {
+	return s.SetDeviceMetadataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, metadataKey, metadataValue)
+}
+
+// SetDeviceMetadataWithContext is the same as SetDeviceMetadata, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) SetDeviceMetadataWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, metadataKey, metadataValue string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
 	payload := map[string]map[string]string{"metadata": {metadataKey: metadataValue}}
-	err := s.client.genericJSONDataAPIPatch(callURL.String(), payload, 200)
+	err := s.client.genericJSONDataAPIPatch(ctx, callURL.String(), payload, 200)
 	if err != nil {
 		return err
 	}
@@ -237,13 +918,22 @@ func (s *AppEngineService) SetDeviceMetadata(realm, deviceIdentifier string, dev
 
 // DeleteDeviceMetadata deletes a Metadata key and its value from a Device
 func (s *AppEngineService) DeleteDeviceMetadata(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, metadataKey string) error {
+	return s.DeleteDeviceMetadataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, metadataKey)
+}
+
+// DeleteDeviceMetadataWithContext is the same as DeleteDeviceMetadata, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) DeleteDeviceMetadataWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, metadataKey string, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	resolvedDeviceIdentifierType := resolveDeviceIdentifierType(deviceIdentifier, deviceIdentifierType)
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/%s", realm, devicePath(deviceIdentifier, resolvedDeviceIdentifierType)))
 	// We're using map[string]interface{} rather than map[string]string since we want to have null
 	// rather than an empty string in the JSON payload, and this is the only way.
 	payload := map[string]map[string]interface{}{"metadata": {metadataKey: nil}}
-	err := s.client.genericJSONDataAPIPatch(callURL.String(), payload, 200)
+	err := s.client.genericJSONDataAPIPatch(ctx, callURL.String(), payload, 200)
 	if err != nil {
 		return err
 	}
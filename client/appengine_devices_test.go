@@ -15,8 +15,18 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
 )
 
 func TestListDevices(t *testing.T) {
@@ -35,3 +45,669 @@ func TestListDevices(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestListDevicesWithCanceledContext(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.AppEngine.ListDevicesWithContext(ctx, testRealmName); !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
+
+func TestGetDevice(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	device, err := client.AppEngine.GetDevice(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Error(err)
+	}
+	if device.DeviceID != testDevices[0] {
+		t.Error("Wrong device returned", device)
+	}
+}
+
+func TestGetDeviceRaw(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `{"data":{"id":"%s","connected":true,"unmodeled_field":42}}`, testDevices[0])
+	})
+	defer server.Close()
+
+	device, raw, err := client.AppEngine.GetDeviceRaw(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.DeviceID != testDevices[0] || !device.Connected {
+		t.Error("Wrong device returned", device)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["unmodeled_field"] != float64(42) {
+		t.Error("Wrong raw data returned", string(raw))
+	}
+}
+
+func TestGetDeviceNotFound(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	_, err := client.AppEngine.GetDevice(testRealmName, "Ks2mF8FeSmuIU1tXk6WSpQ", AstarteDeviceID)
+	if !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) {
+		t.Fatal("Expected an AstarteAPIError, got", err)
+	}
+	if apiError.StatusCode != http.StatusNotFound || apiError.Detail != "Device not found" {
+		t.Error("Wrong error details", apiError)
+	}
+}
+
+func TestGetDeviceInterfaceStats(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	stats, err := client.AppEngine.GetDeviceInterfaceStats(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Name != "org.astarte-platform.genericsensors.Values" || stats.Major != 1 || stats.ExchangedMessages != 40 ||
+		stats.ExchangedBytes != 4000 {
+		t.Error("Unexpected interface stats", stats)
+	}
+
+	_, err = client.AppEngine.GetDeviceInterfaceStats(testRealmName, testDevices[0], AstarteDeviceID, "com.example.Missing")
+	if !errors.Is(err, ErrInterfaceNotInIntrospection) {
+		t.Error("Expected ErrInterfaceNotInIntrospection, got", err)
+	}
+}
+
+func TestGetDeviceTrafficStats(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	bytes, msgs, err := client.AppEngine.GetDeviceTrafficStats(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes != 4200 || msgs != 42 {
+		t.Errorf("Unexpected traffic stats: %d bytes, %d messages", bytes, msgs)
+	}
+
+	if _, _, err := client.AppEngine.GetDeviceTrafficStats(testRealmName, "Ks2mF8FeSmuIU1tXk6WSpQ", AstarteDeviceID); !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+}
+
+func TestGetDeviceConnectionInfo(t *testing.T) {
+	lastConnection := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		details := map[string]interface{}{"id": path.Base(req.URL.Path), "connected": false,
+			"last_connection": nil, "last_disconnection": nil}
+		if path.Base(req.URL.Path) == testDevices[0] {
+			details["connected"] = true
+			details["last_connection"] = lastConnection
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": details})
+	})
+	defer server.Close()
+
+	info, err := client.AppEngine.GetDeviceConnectionInfo(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Connected || !info.LastConnection.Equal(lastConnection) || !info.LastDisconnection.IsZero() {
+		t.Error("Unexpected connection info", info)
+	}
+
+	// Devices which never connected have no connection times
+	info, err = client.AppEngine.GetDeviceConnectionInfo(testRealmName, testDevices[1], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Connected || !info.LastConnection.IsZero() || !info.LastDisconnection.IsZero() {
+		t.Error("Unexpected connection info", info)
+	}
+}
+
+func TestGetDevicesDetails(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	missing := "Ks2mF8FeSmuIU1tXk6WSpQ"
+	details, errs := client.AppEngine.GetDevicesDetails(testRealmName, append([]string{missing}, testDevices...), 2)
+	if len(details) != len(testDevices) {
+		t.Error("Unexpected details", details)
+	}
+	for _, deviceID := range testDevices {
+		if details[deviceID].DeviceID != deviceID {
+			t.Error("Wrong details for", deviceID, details[deviceID])
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[missing], ErrDeviceNotFound) {
+		t.Error("Unexpected errors", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	details, errs = client.AppEngine.GetDevicesDetailsWithContext(ctx, testRealmName, testDevices, 1)
+	if len(details) != 0 || len(errs) != len(testDevices) {
+		t.Error("Unexpected results with a canceled context", details, errs)
+	}
+}
+
+func TestResolveAliases(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	aliases := []string{"missing"}
+	for alias := range testDeviceAliases {
+		aliases = append(aliases, alias)
+	}
+	deviceIDs, errs := client.AppEngine.ResolveAliases(testRealmName, aliases, 2)
+	if !reflect.DeepEqual(deviceIDs, testDeviceAliases) {
+		t.Error("Unexpected Device IDs", deviceIDs)
+	}
+	if len(errs) != 1 || !errors.Is(errs["missing"], ErrDeviceNotFound) {
+		t.Error("Unexpected errors", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deviceIDs, errs = client.AppEngine.ResolveAliasesWithContext(ctx, testRealmName, aliases, 1)
+	if len(deviceIDs) != 0 || len(errs) != len(aliases) {
+		t.Error("Unexpected results with a canceled context", deviceIDs, errs)
+	}
+}
+
+func TestGetDeviceIntrospection(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	introspection, err := client.AppEngine.GetDeviceIntrospection(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interfaces.InterfaceVersion{"org.astarte-platform.genericsensors.Values": {Major: 1, Minor: 0}}
+	if !reflect.DeepEqual(introspection, expected) {
+		t.Error("Unexpected introspection", introspection)
+	}
+}
+
+func TestDeviceDetailsInterfaces(t *testing.T) {
+	device := testDeviceDetails(testDevices[0])
+
+	if !device.HasInterface("org.astarte-platform.genericsensors.Values") || device.HasInterface("com.example.Missing") {
+		t.Error("Unexpected HasInterface results")
+	}
+	version, ok := device.InterfaceVersion("org.astarte-platform.genericsensors.Values")
+	if !ok || version != (interfaces.InterfaceVersion{Major: 1, Minor: 0}) {
+		t.Error("Unexpected interface version", version, ok)
+	}
+	if _, ok := device.InterfaceVersion("com.example.Missing"); ok {
+		t.Error("Unexpected version for a missing interface")
+	}
+}
+
+func TestDeviceDetailsConnectionState(t *testing.T) {
+	lastConnection := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		device   DeviceDetails
+		expected ConnectionState
+		name     string
+	}{
+		{DeviceDetails{}, NeverConnected, "never connected"},
+		{DeviceDetails{Connected: true, LastConnection: lastConnection}, Connected, "connected"},
+		{DeviceDetails{LastConnection: lastConnection, LastDisconnection: lastConnection.Add(time.Hour)}, Disconnected, "disconnected"},
+	}
+	for _, tc := range testCases {
+		state := tc.device.ConnectionState()
+		if state != tc.expected || state.String() != tc.name {
+			t.Errorf("Unexpected connection state %v, expected %v", state, tc.expected)
+		}
+	}
+}
+
+func TestGetDevicePreviousInterfaces(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	previousInterfaces, err := client.AppEngine.GetDevicePreviousInterfaces(testRealmName, testDevices[0], AstarteDeviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previousInterfaces) != 1 || previousInterfaces[0].Name != "org.astarte-platform.genericsensors.Values" ||
+		previousInterfaces[0].Major != 0 || previousInterfaces[0].Minor != 1 {
+		t.Error("Unexpected previous interfaces", previousInterfaces)
+	}
+}
+
+func TestRegistrationFilters(t *testing.T) {
+	since := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	devices := []DeviceDetails{
+		{DeviceID: "old", FirstRegistration: since.Add(-time.Hour), FirstCredentialsRequest: since.Add(-time.Minute)},
+		{DeviceID: "registered", FirstRegistration: since.Add(time.Hour)},
+		{DeviceID: "connected", FirstRegistration: since.Add(time.Hour), FirstCredentialsRequest: since.Add(2 * time.Hour)},
+	}
+
+	testCases := []struct {
+		filter   DeviceFilter
+		expected []string
+	}{
+		{FilterRegisteredAfter(since), []string{"registered", "connected"}},
+		{FilterFirstCredentialsRequestAfter(since), []string{"connected"}},
+	}
+	for i, tc := range testCases {
+		matched := []string{}
+		for _, device := range devices {
+			if tc.filter(device) {
+				matched = append(matched, device.DeviceID)
+			}
+		}
+		if !reflect.DeepEqual(matched, tc.expected) {
+			t.Errorf("Unexpected devices matched by filter %d: %v", i, matched)
+		}
+	}
+}
+
+func TestGetDeviceListDetailsPaginator(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDeviceListDetailsPaginator(testRealmName, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := paginator.GetNextPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != len(testDevices) || page[0].DeviceID != testDevices[0] {
+		t.Error("Wrong page returned", page)
+	}
+	if paginator.HasNextPage() {
+		t.Error("Paginator should have no more pages")
+	}
+}
+
+func TestListConnectedDevices(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	connected, err := client.AppEngine.ListConnectedDevices(testRealmName)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(connected, testDevices[:1]) {
+		t.Error("Wrong connected devices", connected)
+	}
+
+	disconnected, err := client.AppEngine.ListDisconnectedDevices(testRealmName)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(disconnected, testDevices[1:]) {
+		t.Error("Wrong disconnected devices", disconnected)
+	}
+}
+
+func TestAddDeviceAliases(t *testing.T) {
+	var payload map[string]map[string]map[string]string
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPatch {
+			t.Error("Unexpected method", req.Method)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	aliases := map[string]string{"name": "my-device", "serial": "1234"}
+	if err := client.AppEngine.AddDeviceAliases(testRealmName, testDevices[0], aliases); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(payload["data"]["aliases"], aliases) {
+		t.Error("Wrong payload sent", payload)
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	if err := client.AppEngine.DeleteDevice(testRealmName, testDevices[0], AstarteDeviceID); err != nil {
+		t.Error(err)
+	}
+	if err := client.AppEngine.DeleteDevice(testRealmName, "Ks2mF8FeSmuIU1tXk6WSpQ", AstarteDeviceID); !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+}
+
+func TestDeviceListPaginatorForEach(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	for _, format := range []DeviceResultFormat{DeviceIDFormat, DeviceDetailsFormat} {
+		paginator, err := client.AppEngine.GetDeviceListPaginator(testRealmName, 2, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deviceIDs := []string{}
+		err = paginator.ForEach(func(deviceID string) error {
+			deviceIDs = append(deviceIDs, deviceID)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(deviceIDs, testDevices) {
+			t.Error("Unexpected devices", deviceIDs)
+		}
+
+		// Stop at the first error, then start over
+		paginator.Rewind()
+		stop := errors.New("stop")
+		visited := 0
+		err = paginator.ForEach(func(deviceID string) error {
+			visited++
+			return stop
+		})
+		if err != stop || visited != 1 {
+			t.Error("ForEach did not stop at the first error", err, visited)
+		}
+	}
+}
+
+func TestRestoreDeviceListPaginator(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDeviceListPaginator(testRealmName, 2, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceIDs := []string{}
+	if err := paginator.GetNextPage(&deviceIDs); err != nil {
+		t.Fatal(err)
+	}
+	state, err := paginator.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := client.AppEngine.RestoreDeviceListPaginator(testRealmName, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.GetPageSize() != 2 {
+		t.Error("Wrong page size", restored.GetPageSize())
+	}
+	err = restored.ForEach(func(deviceID string) error {
+		deviceIDs = append(deviceIDs, deviceID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deviceIDs, testDevices) {
+		t.Error("Unexpected devices", deviceIDs)
+	}
+
+	if _, err := client.AppEngine.RestoreDeviceListPaginator("other", state); err == nil {
+		t.Error("Expected an error for a state of another realm")
+	}
+	if _, err := client.AppEngine.RestoreDeviceListPaginator(testRealmName, []byte("not json")); err == nil {
+		t.Error("Expected an error for an invalid state")
+	}
+}
+
+func TestListDevicesWithDefaultPageSize(t *testing.T) {
+	requests := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		astarteAPIMock(w, req)
+	}, WithDefaultPageSize(1))
+	defer server.Close()
+
+	devices, err := client.AppEngine.ListDevices(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices) {
+		t.Error("Unexpected devices", devices)
+	}
+	if requests != len(testDevices) {
+		t.Error("Expected one request per device, got", requests)
+	}
+
+	if _, err := NewClient(server.URL, nil, WithDefaultPageSize(0)); err == nil {
+		t.Error("A page size of 0 should be rejected")
+	}
+}
+
+func TestListDevicesLimited(t *testing.T) {
+	limits := []string{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		limits = append(limits, req.URL.Query().Get("limit"))
+		astarteAPIMock(w, req)
+	}, WithDefaultPageSize(2))
+	defer server.Close()
+
+	devices, err := client.AppEngine.ListDevicesLimited(testRealmName, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices[:3]) {
+		t.Error("Unexpected devices", devices)
+	}
+	if !reflect.DeepEqual(limits, []string{"2", "1"}) {
+		t.Error("Unexpected page sizes", limits)
+	}
+
+	limits = []string{}
+	devices, err = client.AppEngine.ListDevicesLimited(testRealmName, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices[:1]) || !reflect.DeepEqual(limits, []string{"1"}) {
+		t.Error("Unexpected devices", devices, limits)
+	}
+
+	limits = []string{}
+	devices, err = client.AppEngine.ListDevicesLimited(testRealmName, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices) {
+		t.Error("Unexpected devices", devices)
+	}
+	if devices, _ := client.AppEngine.ListDevicesLimited(testRealmName, 0); len(devices) != 0 {
+		t.Error("Unexpected devices", devices)
+	}
+}
+
+func TestStreamDevices(t *testing.T) {
+	client, server := getTestContextWithHandler(t, astarteAPIMock, WithDefaultPageSize(1))
+	defer server.Close()
+
+	deviceIDs, errs := client.AppEngine.StreamDevices(context.Background(), testRealmName)
+	devices := []string{}
+	for deviceID := range deviceIDs {
+		devices = append(devices, deviceID)
+	}
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices) {
+		t.Error("Unexpected devices", devices)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deviceIDs, errs = client.AppEngine.StreamDevices(ctx, testRealmName)
+	if deviceID := <-deviceIDs; deviceID != testDevices[0] {
+		t.Error("Unexpected device", deviceID)
+	}
+	cancel()
+	for range deviceIDs {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
+
+func TestGetValueForAllDevices(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.SamplingRate"
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/temp/enable", testRealmName, testDevices[0], iface):
+			fmt.Fprint(w, `{"data":true}`)
+		case fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/temp/enable", testRealmName, testDevices[1], iface):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Path not found"}}`)
+		case fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/temp/enable", testRealmName, testDevices[2], iface):
+			fmt.Fprint(w, `{"data":null}`)
+		default:
+			astarteAPIMock(w, req)
+		}
+	})
+	defer server.Close()
+
+	values, errs, err := client.AppEngine.GetValueForAllDevices(testRealmName, iface, "/temp/enable", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, map[string]interface{}{testDevices[0]: true}) {
+		t.Error("Unexpected values", values)
+	}
+	if len(errs) != 2 || !errors.Is(errs[testDevices[1]], ErrPathNotFound) || !errors.Is(errs[testDevices[2]], ErrPathNotFound) {
+		t.Error("Unexpected errors", errs)
+	}
+
+	if _, _, err := client.AppEngine.GetValueForAllDevices(testRealmName, iface, "temp/enable", 2); err == nil {
+		t.Error("Expected an error for an invalid path")
+	}
+
+	// Only the last sample of Datastreams is fetched
+	iface = "org.astarte-platform.genericsensors.Values"
+	samples := map[string]string{
+		testDevices[0]: `[{"value":21.5,"timestamp":"2021-03-01T10:01:00Z"}]`,
+		testDevices[1]: `[{"value":19,"timestamp":"2021-03-01T10:00:00Z"}]`,
+	}
+	client, server = getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		for deviceID, sample := range samples {
+			if req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/temp/value", testRealmName, deviceID, iface) {
+				if req.URL.Query().Get("limit") != "1" {
+					t.Error("The whole history was requested:", req.URL.RawQuery)
+				}
+				fmt.Fprintf(w, `{"data":%s}`, sample)
+				return
+			}
+		}
+		if strings.HasSuffix(req.URL.Path, "/temp/value") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Path not found"}}`)
+			return
+		}
+		astarteAPIMock(w, req)
+	})
+	defer server.Close()
+
+	values, errs, err = client.AppEngine.GetValueForAllDevices(testRealmName, iface, "/temp/value", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		testDevices[0]: map[string]interface{}{"value": 21.5, "timestamp": "2021-03-01T10:01:00Z"},
+		testDevices[1]: map[string]interface{}{"value": float64(19), "timestamp": "2021-03-01T10:00:00Z"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Error("Unexpected values", values)
+	}
+	if len(errs) != 1 || !errors.Is(errs[testDevices[2]], ErrPathNotFound) {
+		t.Error("Unexpected errors", errs)
+	}
+}
+
+func TestGetDeviceCount(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	total, connected, err := client.AppEngine.GetDeviceCount(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != len(testDevices) || connected != 1 {
+		t.Error("Unexpected device count", total, connected)
+	}
+}
+
+func TestResolveDevice(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	for identifier, expected := range map[string]struct {
+		deviceID       string
+		identifierType DeviceIdentifierType
+	}{
+		testDevices[0]:           {testDevices[0], AstarteDeviceID},
+		"sensor-1":               {testDevices[1], AstarteDeviceAlias},
+		"kitchenSensorNumber01A": {testDevices[2], AstarteDeviceAlias},
+	} {
+		details, identifierType, err := client.AppEngine.ResolveDevice(testRealmName, identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.DeviceID != expected.deviceID || identifierType != expected.identifierType {
+			t.Errorf("Wrong resolution for %s: %s, %v", identifier, details.DeviceID, identifierType)
+		}
+	}
+
+	if _, _, err := client.AppEngine.ResolveDevice(testRealmName, "missing"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+}
+
+func TestDetectDeviceIdentifierType(t *testing.T) {
+	for identifier, expected := range map[string]DeviceIdentifierType{
+		testDevices[0]: AstarteDeviceID,
+		"sensor-1":     AstarteDeviceAlias,
+		// 22 characters, but not well formed Device IDs
+		"my-alias-is-22-chars-x": AstarteDeviceAlias,
+		"my.alias.is.22.chars.A": AstarteDeviceAlias,
+		"my+alias+is+22+chars+A": AstarteDeviceAlias,
+		"kitchen sensor 01 of 2": AstarteDeviceAlias,
+		// Well formed Device IDs which are used as aliases are ambiguous, and are detected as Device IDs
+		"kitchenSensorNumber01A": AstarteDeviceID,
+	} {
+		if detected := DetectDeviceIdentifierType(identifier); detected != expected {
+			t.Errorf("Wrong identifier type for %s: expected %v, got %v", identifier, expected, detected)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	_, err := client.AppEngine.GetDeviceWithContext(context.Background(), testRealmName, testDevices[0], AstarteDeviceID,
+		WithTimeout(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// DeviceDataRecord is a single value written by ExportDeviceData. Timestamp is nil for Properties, and Value
+// holds all the values of the object, keyed by endpoint, for object aggregated Datastreams.
+type DeviceDataRecord struct {
+	Interface string      `json:"interface"`
+	Path      string      `json:"path"`
+	Timestamp *time.Time  `json:"timestamp,omitempty"`
+	Value     interface{} `json:"value"`
+}
+
+// ExportDeviceData writes all the data of a Device to w as newline-delimited JSON, one DeviceDataRecord per line:
+// the current value of each Property, and the whole history of each Datastream path, from the oldest value. Only
+// Interfaces in the introspection of the Device are exported, and their definitions are retrieved from Realm
+// Management, which must be available in the Client. Histories are walked through page by page and written as
+// they are retrieved, so that they are never held in memory as a whole.
+func (s *AppEngineService) ExportDeviceData(realm, deviceIdentifier string, deviceIdentifierType DeviceIdentifierType, w io.Writer) error {
+	return s.ExportDeviceDataWithContext(context.Background(), realm, deviceIdentifier, deviceIdentifierType, w)
+}
+
+// ExportDeviceDataWithContext is the same as ExportDeviceData, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) ExportDeviceDataWithContext(ctx context.Context, realm, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, w io.Writer, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if s.client.RealmManagement == nil {
+		return errors.New("exporting device data requires the Realm Management API")
+	}
+	deviceDetails, err := s.GetDeviceWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType)
+	if err != nil {
+		return err
+	}
+	interfaceNames := []string{}
+	for interfaceName := range deviceDetails.Introspection {
+		interfaceNames = append(interfaceNames, interfaceName)
+	}
+	sort.Strings(interfaceNames)
+
+	exporter := deviceDataExporter{appEngine: s, realm: realm, deviceIdentifier: deviceIdentifier,
+		deviceIdentifierType: deviceIdentifierType, encoder: json.NewEncoder(w)}
+	for _, interfaceName := range interfaceNames {
+		astarteInterface, err := s.client.RealmManagement.GetInterfaceWithContext(ctx, realm, interfaceName,
+			deviceDetails.Introspection[interfaceName].Major)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case astarteInterface.Type == interfaces.PropertiesType:
+			err = exporter.exportProperties(ctx, astarteInterface)
+		case astarteInterface.Aggregation == interfaces.ObjectAggregation:
+			err = exporter.exportObjectDatastream(ctx, astarteInterface)
+		default:
+			err = exporter.exportIndividualDatastream(ctx, astarteInterface)
+		}
+		if err != nil {
+			return fmt.Errorf("exporting %s: %w", interfaceName, err)
+		}
+	}
+
+	return nil
+}
+
+type deviceDataExporter struct {
+	appEngine            *AppEngineService
+	realm                string
+	deviceIdentifier     string
+	deviceIdentifierType DeviceIdentifierType
+	encoder              *json.Encoder
+}
+
+func (e *deviceDataExporter) exportProperties(ctx context.Context, astarteInterface interfaces.AstarteInterface) error {
+	properties, err := e.appEngine.GetPropertiesWithContext(ctx, e.realm, e.deviceIdentifier, e.deviceIdentifierType,
+		astarteInterface.Name)
+	if err != nil {
+		return err
+	}
+	interfacePaths := []string{}
+	for interfacePath := range properties {
+		interfacePaths = append(interfacePaths, interfacePath)
+	}
+	sort.Strings(interfacePaths)
+
+	for _, interfacePath := range interfacePaths {
+		record := DeviceDataRecord{Interface: astarteInterface.Name, Path: interfacePath, Value: properties[interfacePath]}
+		if err := e.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *deviceDataExporter) exportIndividualDatastream(ctx context.Context, astarteInterface interfaces.AstarteInterface) error {
+	// The snapshot holds all the paths with at least one value
+	snapshot, err := e.appEngine.GetDatastreamSnapshotWithContext(ctx, e.realm, e.deviceIdentifier, e.deviceIdentifierType,
+		astarteInterface.Name)
+	if err != nil {
+		return err
+	}
+	interfacePaths := []string{}
+	for interfacePath := range snapshot {
+		interfacePaths = append(interfacePaths, interfacePath)
+	}
+	sort.Strings(interfacePaths)
+
+	order := AscendingOrder
+	for _, interfacePath := range interfacePaths {
+		paginator, err := e.appEngine.GetDatastreamIndividualPaginator(e.realm, e.deviceIdentifier, e.deviceIdentifierType,
+			astarteInterface.Name, interfacePath, DatastreamQueryOptions{Order: &order})
+		if err != nil {
+			return err
+		}
+		for paginator.HasNextPage() {
+			page, err := paginator.GetNextPageWithContext(ctx)
+			if err != nil {
+				return err
+			}
+			for _, value := range page {
+				timestamp := value.Timestamp
+				record := DeviceDataRecord{Interface: astarteInterface.Name, Path: interfacePath, Timestamp: &timestamp,
+					Value: value.Value}
+				if err := e.encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *deviceDataExporter) exportObjectDatastream(ctx context.Context, astarteInterface interfaces.AstarteInterface) error {
+	interfacePaths := []string{}
+	if astarteInterface.IsParametric() {
+		snapshot, err := e.appEngine.GetAggregateParametricDatastreamSnapshotWithContext(ctx, e.realm, e.deviceIdentifier,
+			e.deviceIdentifierType, astarteInterface.Name)
+		if err != nil {
+			return err
+		}
+		for interfacePath := range snapshot {
+			interfacePaths = append(interfacePaths, interfacePath)
+		}
+		sort.Strings(interfacePaths)
+	} else if len(astarteInterface.Mappings) > 0 {
+		// All the endpoints of an object share the same parent
+		interfacePaths = append(interfacePaths, path.Dir(astarteInterface.Mappings[0].Endpoint))
+	}
+
+	for _, interfacePath := range interfacePaths {
+		paginator, err := e.appEngine.GetDatastreamsPaginator(e.realm, e.deviceIdentifier, e.deviceIdentifierType,
+			astarteInterface.Name, interfacePath, AscendingOrder)
+		if err != nil {
+			return err
+		}
+		for paginator.HasNextPage() {
+			page, err := paginator.GetNextAggregatePageWithContext(ctx)
+			if err != nil {
+				return err
+			}
+			for _, value := range page {
+				timestamp := value.Timestamp
+				record := DeviceDataRecord{Interface: astarteInterface.Name, Path: interfacePath, Timestamp: &timestamp,
+					Value: value.Values}
+				if err := e.encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const exportTestInterfaces = `{
+	"com.example.Config": {"interface_name": "com.example.Config", "version_major": 1, "version_minor": 0,
+		"type": "properties", "ownership": "server", "mappings": [{"endpoint": "/%{name}/enabled", "type": "boolean"}]},
+	"com.example.Samples": {"interface_name": "com.example.Samples", "version_major": 0, "version_minor": 1,
+		"type": "datastream", "ownership": "device", "mappings": [{"endpoint": "/temperature", "type": "double"}]},
+	"com.example.Readings": {"interface_name": "com.example.Readings", "version_major": 0, "version_minor": 1,
+		"type": "datastream", "ownership": "device", "aggregation": "object",
+		"mappings": [{"endpoint": "/sensor/value", "type": "double"}, {"endpoint": "/sensor/unit", "type": "string"}]}
+}`
+
+func TestExportDeviceData(t *testing.T) {
+	definitions := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(exportTestInterfaces), &definitions); err != nil {
+		t.Fatal(err)
+	}
+	devicePath := fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDevices[0])
+	replies := map[string]string{
+		devicePath: `{"data": {"id": "` + testDevices[0] + `", "introspection": {
+			"com.example.Config": {"major": 1, "minor": 0},
+			"com.example.Samples": {"major": 0, "minor": 1},
+			"com.example.Readings": {"major": 0, "minor": 1}}}}`,
+		devicePath + "/interfaces/com.example.Config": `{"data": {"heater": {"enabled": true}}}`,
+		devicePath + "/interfaces/com.example.Samples": `{"data": {"temperature":
+			{"value": 21.5, "timestamp": "2021-03-01T10:01:00Z"}}}`,
+		devicePath + "/interfaces/com.example.Samples/temperature": `{"data": [
+			{"value": 20, "timestamp": "2021-03-01T10:00:00Z"}, {"value": 21.5, "timestamp": "2021-03-01T10:01:00Z"}]}`,
+		devicePath + "/interfaces/com.example.Readings/sensor": `{"data": [
+			{"timestamp": "2021-03-01T10:00:00Z", "value": 0.5, "unit": "V"}]}`,
+	}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if reply, ok := replies[req.URL.Path]; ok {
+			fmt.Fprint(w, reply)
+			return
+		}
+		tokens := strings.Split(req.URL.Path, "/")
+		if strings.HasPrefix(req.URL.Path, "/realmmanagement/") && len(tokens) > 5 {
+			if definition, ok := definitions[tokens[5]]; ok {
+				fmt.Fprintf(w, `{"data": %s}`, definition)
+				return
+			}
+		}
+		t.Error("Unexpected request", req.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	if err := client.AppEngine.ExportDeviceData(testRealmName, testDevices[0], AstarteDeviceID, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`{"interface":"com.example.Config","path":"/heater/enabled","value":true}`,
+		`{"interface":"com.example.Readings","path":"/sensor","timestamp":"2021-03-01T10:00:00Z","value":{"value":0.5,"unit":"V"}}`,
+		`{"interface":"com.example.Samples","path":"/temperature","timestamp":"2021-03-01T10:00:00Z","value":20}`,
+		`{"interface":"com.example.Samples","path":"/temperature","timestamp":"2021-03-01T10:01:00Z","value":21.5}`,
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unexpected export:\n%s", buf.String())
+	}
+}
+
+func TestExportDeviceDataInterfaceContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	devicePath := fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDevices[0])
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == devicePath {
+			fmt.Fprint(w, `{"data": {"id": "`+testDevices[0]+`", "introspection": {"com.example.Config": {"major": 1, "minor": 0}}}}`)
+			return
+		}
+		// Cancel the export while the Interface is being fetched: the request must be aborted
+		cancel()
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+			t.Error("The Interface request ignored the context of the export")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+
+	err := client.AppEngine.ExportDeviceDataWithContext(ctx, testRealmName, testDevices[0], AstarteDeviceID, &bytes.Buffer{})
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected context.Canceled, got", err)
+	}
+}
@@ -15,30 +15,58 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"path"
+	"strings"
 )
 
 // This file contains all API Calls related to device group management
 
 // ListGroups lists the groups in a Realm
 func (s *AppEngineService) ListGroups(realm string) ([]string, error) {
+	return s.ListGroupsWithContext(context.Background(), realm)
+}
+
+// ListGroupsWithContext is the same as ListGroups, but ctx is used for the underlying HTTP request.
+func (s *AppEngineService) ListGroupsWithContext(ctx context.Context, realm string,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/groups", realm))
 	groupsList := []string{}
-	err := s.client.genericJSONDataAPIGET(&groupsList, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(ctx, &groupsList, callURL.String(), 200)
 
 	return groupsList, err
 }
 
-// CreateGroup creates a group with the given deviceIdentifierList in the Realm
+// CreateGroup creates a group with the given deviceIdentifierList in the Realm. Astarte does not allow empty
+// groups, so deviceIdentifierList must contain at least one Device.
 func (s *AppEngineService) CreateGroup(realm string, groupName string, deviceIdentifierList []string,
 	deviceIdentifiersType DeviceIdentifierType) error {
+	return s.CreateGroupWithContext(context.Background(), realm, groupName, deviceIdentifierList, deviceIdentifiersType)
+}
+
+// CreateGroupWithContext is the same as CreateGroup, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) CreateGroupWithContext(ctx context.Context, realm string, groupName string, deviceIdentifierList []string,
+	deviceIdentifiersType DeviceIdentifierType, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	if err := validateGroupName(groupName); err != nil {
+		return err
+	}
+	if len(deviceIdentifierList) == 0 {
+		return errors.New("a group must contain at least one device")
+	}
 
 	deviceIDList := make([]string, len(deviceIdentifierList))
 	for i, deviceIdentifier := range deviceIdentifierList {
-		deviceID, err := s.GetDeviceIDFromDeviceIdentifier(realm, deviceIdentifier, deviceIdentifiersType)
+		deviceID, err := s.GetDeviceIDFromDeviceIdentifierWithContext(ctx, realm, deviceIdentifier, deviceIdentifiersType)
 		if err != nil {
 			return err
 		}
@@ -47,7 +75,7 @@ func (s *AppEngineService) CreateGroup(realm string, groupName string, deviceIde
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/groups", realm))
 	payload := map[string]interface{}{"group_name": groupName, "devices": deviceIDList}
-	err := s.client.genericJSONDataAPIPost(callURL.String(), payload, 201)
+	err := s.client.genericJSONDataAPIPost(ctx, callURL.String(), payload, 201)
 	if err != nil {
 		return err
 	}
@@ -55,27 +83,77 @@ func (s *AppEngineService) CreateGroup(realm string, groupName string, deviceIde
 	return nil
 }
 
-// ListGroupDevices lists the devices that belong to a group
+// ListGroupDevices lists the devices that belong to a group. The returned result can be large,
+// GetGroupDevicesPaginator can be used instead to retrieve the device list incrementally.
 func (s *AppEngineService) ListGroupDevices(realm string, groupName string) ([]string, error) {
-	callURL, _ := url.Parse(s.appEngineURL.String())
+	return s.ListGroupDevicesWithContext(context.Background(), realm, groupName)
+}
+
+// ListGroupDevicesWithContext is the same as ListGroupDevices, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) ListGroupDevicesWithContext(ctx context.Context, realm string, groupName string,
+	opts ...RequestOption) ([]string, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	result := []string{}
+
+	paginator, err := s.GetGroupDevicesPaginator(realm, groupName, s.client.devicesPageSize)
+	if err != nil {
+		return result, err
+	}
+
+	for hasNext := paginator.HasNextPage(); hasNext; hasNext = paginator.HasNextPage() {
+		page := []string{}
+		err := paginator.GetNextPageWithContext(ctx, &page)
+		if err != nil {
+			return []string{}, err
+		}
+		result = append(result, page...)
+	}
+
+	return result, nil
+}
+
+// GetGroupDevicesPaginator returns a Paginator for all the Devices belonging to a group.
+// Pages are returned in DeviceIDFormat.
+func (s *AppEngineService) GetGroupDevicesPaginator(realm string, groupName string, pageSize int) (DeviceListPaginator, error) {
+	callURL, err := url.Parse(s.appEngineURL.String())
+	if err != nil {
+		return DeviceListPaginator{}, err
+	}
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/groups/%s/devices", realm, url.PathEscape(groupName)))
-	groupDevicesList := []string{}
-	err := s.client.genericJSONDataAPIGET(&groupDevicesList, callURL.String(), 200)
 
-	return groupDevicesList, err
+	deviceListPaginator := DeviceListPaginator{
+		baseURL:     callURL,
+		nextQuery:   url.Values{},
+		format:      DeviceIDFormat,
+		pageSize:    pageSize,
+		client:      s.client,
+		hasNextPage: true,
+	}
+	return deviceListPaginator, nil
 }
 
 // AddDeviceToGroup adds a device to the group
 func (s *AppEngineService) AddDeviceToGroup(realm string, groupName string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType) error {
+	return s.AddDeviceToGroupWithContext(context.Background(), realm, groupName, deviceIdentifier, deviceIdentifierType)
+}
+
+// AddDeviceToGroupWithContext is the same as AddDeviceToGroup, but ctx is used for all the underlying HTTP requests.
+func (s *AppEngineService) AddDeviceToGroupWithContext(ctx context.Context, realm string, groupName string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/groups/%s/devices", realm, url.PathEscape(groupName)))
-	deviceID, err := s.GetDeviceIDFromDeviceIdentifier(realm, deviceIdentifier, deviceIdentifierType)
+	deviceID, err := s.GetDeviceIDFromDeviceIdentifierWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType)
 	if err != nil {
 		return err
 	}
 	payload := map[string]string{"device_id": deviceID}
-	err = s.client.genericJSONDataAPIPost(callURL.String(), payload, 201)
+	err = s.client.genericJSONDataAPIPost(ctx, callURL.String(), payload, 201)
 	if err != nil {
 		return err
 	}
@@ -83,19 +161,42 @@ func (s *AppEngineService) AddDeviceToGroup(realm string, groupName string, devi
 	return nil
 }
 
-// RemoveDeviceFromGroup removes a device from the group
+// RemoveDeviceFromGroup removes a device from the group. Astarte does not allow empty groups: removing the
+// last device from a group deletes the group itself.
 func (s *AppEngineService) RemoveDeviceFromGroup(realm string, groupName string, deviceIdentifier string,
 	deviceIdentifierType DeviceIdentifierType) error {
-	deviceID, err := s.GetDeviceIDFromDeviceIdentifier(realm, deviceIdentifier, deviceIdentifierType)
+	return s.RemoveDeviceFromGroupWithContext(context.Background(), realm, groupName, deviceIdentifier, deviceIdentifierType)
+}
+
+// RemoveDeviceFromGroupWithContext is the same as RemoveDeviceFromGroup, but ctx is used for all the underlying
+// HTTP requests.
+func (s *AppEngineService) RemoveDeviceFromGroupWithContext(ctx context.Context, realm string, groupName string, deviceIdentifier string,
+	deviceIdentifierType DeviceIdentifierType, opts ...RequestOption) error {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	deviceID, err := s.GetDeviceIDFromDeviceIdentifierWithContext(ctx, realm, deviceIdentifier, deviceIdentifierType)
 	if err != nil {
 		return err
 	}
 	callURL, _ := url.Parse(s.appEngineURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/groups/%s/devices/%s", realm, url.PathEscape(groupName), deviceID))
-	err = s.client.genericJSONDataAPIDelete(callURL.String(), 204)
+	err = s.client.genericJSONDataAPIDelete(ctx, callURL.String(), 204)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// validateGroupName checks groupName against the rules enforced by Astarte: it must not be empty, and it
+// must not start with the reserved characters "~" and "@"
+func validateGroupName(groupName string) error {
+	switch {
+	case groupName == "":
+		return errors.New("group name must not be empty")
+	case strings.HasPrefix(groupName, "~"), strings.HasPrefix(groupName, "@"):
+		return fmt.Errorf("group name %s must not start with ~ or @", groupName)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testGroupName = "sensors"
+
+// groupsAPIMock mocks the groups API of AppEngine, including the deletion of groups left without devices
+func groupsAPIMock(t *testing.T, groups map[string][]string) http.HandlerFunc {
+	groupsPath := fmt.Sprintf("/appengine/v1/%s/groups", testRealmName)
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if req.Method == http.MethodPost {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+		}
+
+		tokens := strings.Split(strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, groupsPath), "/"), "/")
+		switch {
+		case req.URL.Path == groupsPath && req.Method == http.MethodGet:
+			names := []string{}
+			for name := range groups {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": names})
+
+		case req.URL.Path == groupsPath && req.Method == http.MethodPost:
+			devices := []string{}
+			for _, d := range body.Data["devices"].([]interface{}) {
+				devices = append(devices, d.(string))
+			}
+			groups[body.Data["group_name"].(string)] = devices
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(body)
+
+		case len(tokens) >= 2 && tokens[1] == "devices":
+			devices, ok := groups[tokens[0]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":{"detail":"Group not found"}}`)
+				return
+			}
+			switch {
+			case len(tokens) == 2 && req.Method == http.MethodGet:
+				// Paginate using the index of the next device as from_token
+				from, _ := strconv.Atoi(req.URL.Query().Get("from_token"))
+				limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+				if err != nil || from+limit > len(devices) {
+					limit = len(devices) - from
+				}
+				links := Links{Self: req.URL.String()}
+				if from+limit < len(devices) {
+					links.Next = fmt.Sprintf("%s?from_token=%d", req.URL.Path, from+limit)
+				}
+				response := map[string]interface{}{"data": devices[from : from+limit], "links": links}
+				_ = json.NewEncoder(w).Encode(response)
+			case len(tokens) == 2 && req.Method == http.MethodPost:
+				groups[tokens[0]] = append(devices, body.Data["device_id"].(string))
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(body)
+			case len(tokens) == 3 && req.Method == http.MethodDelete:
+				remaining := []string{}
+				for _, d := range devices {
+					if d != tokens[2] {
+						remaining = append(remaining, d)
+					}
+				}
+				if len(remaining) == 0 {
+					delete(groups, tokens[0])
+				} else {
+					groups[tokens[0]] = remaining
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestCreateAndListGroups(t *testing.T) {
+	groups := map[string][]string{}
+	client, server := getTestContextWithHandler(t, groupsAPIMock(t, groups))
+	defer server.Close()
+
+	if err := client.AppEngine.CreateGroup(testRealmName, testGroupName, testDevices[:2], AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups[testGroupName], testDevices[:2]) {
+		t.Error("Unexpected group devices", groups[testGroupName])
+	}
+
+	groupNames, err := client.AppEngine.ListGroups(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groupNames, []string{testGroupName}) {
+		t.Error("Unexpected groups", groupNames)
+	}
+}
+
+func TestCreateGroupValidation(t *testing.T) {
+	groups := map[string][]string{}
+	client, server := getTestContextWithHandler(t, groupsAPIMock(t, groups))
+	defer server.Close()
+
+	for _, groupName := range []string{"", "~reserved", "@reserved"} {
+		if err := client.AppEngine.CreateGroup(testRealmName, groupName, testDevices, AstarteDeviceID); err == nil {
+			t.Errorf("Group name %q should have been rejected", groupName)
+		}
+	}
+	if err := client.AppEngine.CreateGroup(testRealmName, testGroupName, []string{}, AstarteDeviceID); err == nil {
+		t.Error("An empty group should have been rejected")
+	}
+	if len(groups) != 0 {
+		t.Error("No group should have been created", groups)
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	groups := map[string][]string{testGroupName: {testDevices[0]}}
+	client, server := getTestContextWithHandler(t, groupsAPIMock(t, groups))
+	defer server.Close()
+
+	for _, deviceID := range testDevices[1:] {
+		if err := client.AppEngine.AddDeviceToGroup(testRealmName, testGroupName, deviceID, AstarteDeviceID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paginator, err := client.AppEngine.GetGroupDevicesPaginator(testRealmName, testGroupName, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := [][]string{}
+	for paginator.HasNextPage() {
+		page := []string{}
+		if err := paginator.GetNextPage(&page); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+	}
+	if !reflect.DeepEqual(pages, [][]string{testDevices[:2], testDevices[2:]}) {
+		t.Error("Unexpected pages", pages)
+	}
+
+	devices, err := client.AppEngine.ListGroupDevices(testRealmName, testGroupName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(devices, testDevices) {
+		t.Error("Unexpected group devices", devices)
+	}
+}
+
+func TestRemovingLastDeviceDeletesGroup(t *testing.T) {
+	groups := map[string][]string{testGroupName: testDevices[:2]}
+	mock := groupsAPIMock(t, groups)
+	requests := []string{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		mock(w, req)
+	})
+	defer server.Close()
+
+	for _, deviceID := range testDevices[:2] {
+		if err := client.AppEngine.RemoveDeviceFromGroup(testRealmName, testGroupName, deviceID, AstarteDeviceID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	groupPath := fmt.Sprintf("/appengine/v1/%s/groups/%s/devices", testRealmName, testGroupName)
+	expected := []string{
+		http.MethodDelete + " " + groupPath + "/" + testDevices[0],
+		http.MethodDelete + " " + groupPath + "/" + testDevices[1],
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Error("Unexpected requests", requests)
+	}
+
+	groupNames, err := client.AppEngine.ListGroups(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groupNames) != 0 {
+		t.Error("The group should have been deleted", groupNames)
+	}
+
+	// Once the group is gone, the 404 reported by Astarte must reach the caller
+	for _, err := range []error{
+		client.AppEngine.RemoveDeviceFromGroup(testRealmName, testGroupName, testDevices[0], AstarteDeviceID),
+		func() error { _, err := client.AppEngine.ListGroupDevices(testRealmName, testGroupName); return err }(),
+	} {
+		var apiError *AstarteAPIError
+		if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusNotFound || apiError.Detail != "Group not found" {
+			t.Error("Expected a Group not found error, got", err)
+		}
+	}
+}
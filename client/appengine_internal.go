@@ -20,26 +20,29 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/astarte-platform/astarte-go/misc"
 	"github.com/iancoleman/orderedmap"
 )
 
 // resolveDeviceIdentifierType maps a deviceIdentifier and DeviceIdentifierType to a resolved
 // DeviceIdentifierType (i.e. AstarteDeviceID or AstarteDeviceAlias). AutodiscoverDeviceIdentifier
-// is resolved by checking if it's a valid Device ID, otherwise it's considered a Device Alias.
-// AstarteDeviceAlias and AstarteDeviceID are returned as is.
+// is resolved with DetectDeviceIdentifierType. AstarteDeviceAlias and AstarteDeviceID are returned as is.
 func resolveDeviceIdentifierType(deviceIdentifier string, deviceIdentifierType DeviceIdentifierType) DeviceIdentifierType {
 	switch deviceIdentifierType {
 	case AutodiscoverDeviceIdentifier:
-		if misc.IsValidAstarteDeviceID(deviceIdentifier) {
-			return AstarteDeviceID
-		}
-		return AstarteDeviceAlias
+		return DetectDeviceIdentifierType(deviceIdentifier)
 	default:
 		return deviceIdentifierType
 	}
 }
 
+// astarteQueryTimeFormat is the RFC3339 format, with millisecond precision, Astarte expects in queries
+const astarteQueryTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// formatQueryTime formats t as expected by Astarte in query parameters such as since and to
+func formatQueryTime(t time.Time) string {
+	return t.UTC().Format(astarteQueryTimeFormat)
+}
+
 // devicePath accepts a deviceIdentifier and a resolved DeviceIdentifierType (i.e. AstarteDeviceID
 // or AstarteDeviceAlias) and returns the path for that device. AutodiscoverDeviceIdentifier has to
 // be resolved with resolveDeviceIdentifierType first
@@ -0,0 +1,346 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+const roomReconnectMaxDelay = 30 * time.Second
+
+// roomReconnectBaseDelay is the delay before the first attempt to reconnect a Room whose connection has been
+// lost. Subsequent attempts are spaced with an exponential backoff, up to roomReconnectMaxDelay
+var roomReconnectBaseDelay = time.Second
+
+// DeviceEvent is an event delivered by Astarte to a Room when one of its Volatile Triggers fires
+type DeviceEvent struct {
+	DeviceID string
+	// Timestamp is the time the event was generated at, if reported by Astarte
+	Timestamp time.Time
+	// Type is the condition which fired the trigger, e.g. "incoming_data" or "device_connected"
+	Type string
+	// Interface, Path and Value are set for events on data, such as "incoming_data"
+	Interface string
+	Path      string
+	Value     interface{}
+	// DeviceIPAddress is set for "device_connected" events
+	DeviceIPAddress string
+	// Event is the raw event, holding fields specific to its Type
+	Event json.RawMessage
+}
+
+// UnmarshalJSON unmarshals a DeviceEvent from the payload of a new_event message
+func (e *DeviceEvent) UnmarshalJSON(b []byte) error {
+	var payload struct {
+		DeviceID  string          `json:"device_id"`
+		Timestamp time.Time       `json:"timestamp"`
+		Event     json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return err
+	}
+	var event struct {
+		Type            string      `json:"type"`
+		Interface       string      `json:"interface"`
+		Path            string      `json:"path"`
+		Value           interface{} `json:"value"`
+		DeviceIPAddress string      `json:"device_ip_address"`
+	}
+	if err := json.Unmarshal(payload.Event, &event); err != nil {
+		return err
+	}
+
+	*e = DeviceEvent{
+		DeviceID:        payload.DeviceID,
+		Timestamp:       payload.Timestamp,
+		Type:            event.Type,
+		Interface:       event.Interface,
+		Path:            event.Path,
+		Value:           event.Value,
+		DeviceIPAddress: event.DeviceIPAddress,
+		Event:           payload.Event,
+	}
+	return nil
+}
+
+// Room is a subscription to real time events of Devices, delivered over Astarte AppEngine Channels. Events
+// are generated by Volatile Triggers, which live only as long as the Room. Use JoinRoom to create a Room.
+//
+// If the connection is lost, the Room reconnects with an exponential backoff, and installs its Volatile
+// Triggers again. Events generated while disconnected are lost.
+type Room struct {
+	appEngine *AppEngineService
+	realm     string
+	topic     string
+	events    chan DeviceEvent
+
+	lock    sync.Mutex
+	socket  *phoenixSocket
+	watches []triggers.AstarteTrigger
+
+	// queue holds the events received from the socket and not yet delivered on events, so that reading
+	// the socket (and thus the replies to pushes) never waits for the consumer of Events
+	queueLock  sync.Mutex
+	queue      []DeviceEvent
+	queueReady chan struct{}
+}
+
+// JoinRoom joins roomName in realm, and installs trigger into it as a Volatile Trigger. trigger needs no action,
+// and its SimpleTrigger must target either a Device or a group. Events are delivered on the channel returned by
+// Events until ctx is done, at which point the connection is closed and the channel is closed as well.
+func (s *AppEngineService) JoinRoom(ctx context.Context, realm string, roomName string, trigger triggers.AstarteTrigger) (*Room, error) {
+	if err := validateVolatileTrigger(trigger); err != nil {
+		return nil, err
+	}
+
+	r := &Room{
+		appEngine:  s,
+		realm:      realm,
+		topic:      fmt.Sprintf("rooms:%s:%s", realm, roomName),
+		events:     make(chan DeviceEvent),
+		watches:    []triggers.AstarteTrigger{trigger},
+		queueReady: make(chan struct{}, 1),
+	}
+	socket, err := r.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go r.run(ctx, socket, retryPolicy{baseDelay: roomReconnectBaseDelay, maxDelay: roomReconnectMaxDelay})
+	go r.dispatch(ctx)
+
+	return r, nil
+}
+
+// Events returns the channel on which the events of the Room are delivered. Events received while the channel
+// is not being read are queued, so it is safe to call InstallVolatileTrigger while handling an event.
+func (r *Room) Events() <-chan DeviceEvent {
+	return r.events
+}
+
+// VolatileTriggerSubscription is a Volatile Trigger installed in a Room with InstallVolatileTrigger
+type VolatileTriggerSubscription struct {
+	room *Room
+	name string
+}
+
+// InstallVolatileTrigger installs trigger into the Room as a Volatile Trigger, so that its events are delivered
+// on the channel returned by Events along with the ones of the other Volatile Triggers of the Room. Nothing is
+// persisted in Realm Management: the trigger is removed when unsubscribed or when the Room is closed. trigger
+// needs no action, and its SimpleTrigger must target either a Device or a group. Its name must be unique in the Room.
+// ctx bounds the wait for Astarte to acknowledge the trigger.
+func (r *Room) InstallVolatileTrigger(ctx context.Context, trigger triggers.AstarteTrigger) (*VolatileTriggerSubscription, error) {
+	if err := validateVolatileTrigger(trigger); err != nil {
+		return nil, err
+	}
+
+	// The lock is held across the push so that a concurrent reconnection installs either all or none
+	// of the triggers added meanwhile
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, watch := range r.watches {
+		if watch.Name == trigger.Name {
+			return nil, fmt.Errorf("a trigger named %s is already installed in the room", trigger.Name)
+		}
+	}
+	if _, err := r.socket.push(ctx, r.topic, "watch", volatileTriggerPayload(trigger)); err != nil {
+		return nil, err
+	}
+	r.watches = append(r.watches, trigger)
+
+	return &VolatileTriggerSubscription{room: r, name: trigger.Name}, nil
+}
+
+// Unsubscribe removes the Volatile Trigger from the Room. Events it generated before being removed might
+// still be delivered.
+func (s *VolatileTriggerSubscription) Unsubscribe() error {
+	r := s.room
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, watch := range r.watches {
+		if watch.Name == s.name {
+			// Even if unwatch fails, the trigger must not be installed again on reconnection
+			r.watches = append(r.watches[:i], r.watches[i+1:]...)
+			_, err := r.socket.push(context.Background(), r.topic, "unwatch", map[string]string{"name": s.name})
+			if errors.Is(err, errPhoenixSocketClosed) {
+				// Volatile Triggers are gone along with the connection
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// connect dials the socket, joins the Room and installs all of its Volatile Triggers.
+func (r *Room) connect(ctx context.Context) (*phoenixSocket, error) {
+	socketURL, err := r.socketURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := r.appEngine.client
+	header := http.Header{}
+	for key, values := range client.requestHeaders {
+		header[key] = values
+	}
+	header.Set("User-Agent", client.UserAgent)
+	socket, err := dialPhoenixSocket(ctx, newWebsocketDialer(client.httpClient), socketURL, header, r.handleMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := socket.push(ctx, r.topic, "phx_join", struct{}{}); err != nil {
+		socket.close()
+		return nil, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, trigger := range r.watches {
+		if _, err := socket.push(ctx, r.topic, "watch", volatileTriggerPayload(trigger)); err != nil {
+			socket.close()
+			return nil, err
+		}
+	}
+	r.socket = socket
+
+	return socket, nil
+}
+
+// run keeps the Room connected until ctx is done, reconnecting with backoff.
+func (r *Room) run(ctx context.Context, socket *phoenixSocket, backoff retryPolicy) {
+	for {
+		select {
+		case <-ctx.Done():
+			socket.close()
+			return
+		case <-socket.done:
+		}
+
+		for attempt := 0; ; attempt++ {
+			if err := sleepWithContext(ctx, backoff.backoff(attempt)); err != nil {
+				return
+			}
+			var err error
+			if socket, err = r.connect(ctx); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// handleMessage queues the events received from the socket. It must not block, as it runs in the read loop
+// of the socket.
+func (r *Room) handleMessage(message phoenixMessage) {
+	if message.Topic != r.topic || message.Event != "new_event" {
+		return
+	}
+	event := DeviceEvent{}
+	if err := json.Unmarshal(message.Payload, &event); err != nil {
+		return
+	}
+
+	r.queueLock.Lock()
+	r.queue = append(r.queue, event)
+	r.queueLock.Unlock()
+	select {
+	case r.queueReady <- struct{}{}:
+	default:
+		// dispatch has already been notified
+	}
+}
+
+// dispatch delivers the queued events on the events channel until ctx is done, then closes it.
+func (r *Room) dispatch(ctx context.Context) {
+	defer close(r.events)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.queueReady:
+		}
+
+		r.queueLock.Lock()
+		queue := r.queue
+		r.queue = nil
+		r.queueLock.Unlock()
+
+		for _, event := range queue {
+			select {
+			case r.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (r *Room) socketURL(ctx context.Context) (string, error) {
+	token, err := r.appEngine.client.getToken(ctx, r.realm)
+	if err != nil {
+		return "", err
+	}
+	socketURL, _ := url.Parse(r.appEngine.appEngineURL.String())
+	switch socketURL.Scheme {
+	case "https":
+		socketURL.Scheme = "wss"
+	default:
+		socketURL.Scheme = "ws"
+	}
+	socketURL.Path = path.Join(socketURL.Path, "/v1/socket/websocket")
+	socketURL.RawQuery = url.Values{"realm": {r.realm}, "token": {token}, "vsn": {"1.0.0"}}.Encode()
+
+	return socketURL.String(), nil
+}
+
+// validateVolatileTrigger ensures trigger can be installed as a Volatile Trigger. Unlike Triggers installed
+// through Realm Management, Volatile Triggers have no action, and must target a Device or a group.
+func validateVolatileTrigger(trigger triggers.AstarteTrigger) error {
+	if trigger.Name == "" {
+		return errors.New("trigger name must not be empty")
+	}
+	if len(trigger.SimpleTriggers) != 1 {
+		return errors.New("a trigger must have exactly one simple trigger")
+	}
+	simpleTrigger := trigger.SimpleTriggers[0]
+	if err := simpleTrigger.Validate(); err != nil {
+		return err
+	}
+	if simpleTrigger.DeviceID == "" && simpleTrigger.GroupName == "" {
+		return errors.New("volatile triggers require either a device_id or a group_name")
+	}
+	return nil
+}
+
+func volatileTriggerPayload(trigger triggers.AstarteTrigger) interface{} {
+	simpleTrigger := trigger.SimpleTriggers[0]
+	return struct {
+		Name          string                 `json:"name"`
+		DeviceID      string                 `json:"device_id,omitempty"`
+		GroupName     string                 `json:"group_name,omitempty"`
+		SimpleTrigger triggers.SimpleTrigger `json:"simple_trigger"`
+	}{trigger.Name, simpleTrigger.DeviceID, simpleTrigger.GroupName, simpleTrigger}
+}
@@ -0,0 +1,282 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/triggers"
+	"github.com/gorilla/websocket"
+)
+
+// phoenixMock is a minimal Astarte Channels server. It acknowledges joins and watches, and fires an
+// incoming_data event after every watch, carrying the number of the connection as value.
+type phoenixMock struct {
+	t *testing.T
+	// closeAfterEvent makes the server drop the connection after firing the event
+	closeAfterEvent bool
+
+	lock        sync.Mutex
+	connections int
+	watches     []map[string]interface{}
+}
+
+func (m *phoenixMock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/appengine/v1/socket/websocket" || req.URL.Query().Get("realm") != testRealmName ||
+		req.URL.Query().Get("token") != testTokenValue {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, req, nil)
+	if err != nil {
+		m.t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	m.lock.Lock()
+	m.connections++
+	connection := m.connections
+	m.lock.Unlock()
+
+	topic := fmt.Sprintf("rooms:%s:dashboard", testRealmName)
+	for {
+		message := phoenixMessage{}
+		if err := conn.ReadJSON(&message); err != nil {
+			return
+		}
+		reply := json.RawMessage(`{"status":"ok","response":{}}`)
+		if message.Topic != topic && message.Topic != "phoenix" {
+			reply = json.RawMessage(`{"status":"error","response":{"reason":"unauthorized"}}`)
+		}
+		_ = conn.WriteJSON(phoenixMessage{Topic: message.Topic, Event: "phx_reply", Payload: reply, Ref: message.Ref})
+
+		switch message.Event {
+		case "watch":
+			watch := map[string]interface{}{}
+			_ = json.Unmarshal(message.Payload, &watch)
+			m.lock.Lock()
+			m.watches = append(m.watches, watch)
+			m.lock.Unlock()
+
+			event := fmt.Sprintf(`{"device_id":%q,"timestamp":"2020-10-15T12:00:00Z","event":{"type":"incoming_data",`+
+				`"interface":"org.astarte-platform.genericsensors.Values","path":"/temperature","value":%d}}`,
+				testDevices[0], connection)
+			_ = conn.WriteJSON(phoenixMessage{Topic: topic, Event: "new_event", Payload: json.RawMessage(event)})
+			if m.closeAfterEvent {
+				return
+			}
+		case "unwatch":
+			watch := map[string]interface{}{}
+			_ = json.Unmarshal(message.Payload, &watch)
+			m.lock.Lock()
+			for i, w := range m.watches {
+				if w["name"] == watch["name"] {
+					m.watches = append(m.watches[:i], m.watches[i+1:]...)
+					break
+				}
+			}
+			m.lock.Unlock()
+		}
+	}
+}
+
+func getTestVolatileTrigger(name string) triggers.AstarteTrigger {
+	return triggers.AstarteTrigger{
+		Name: name,
+		SimpleTriggers: []triggers.SimpleTrigger{{
+			Type:          triggers.DataTrigger,
+			On:            triggers.IncomingData,
+			InterfaceName: "org.astarte-platform.genericsensors.Values",
+			MatchPath:     "/*",
+			DeviceID:      testDevices[0],
+		}},
+	}
+}
+
+func receiveDeviceEvent(t *testing.T, room *Room) DeviceEvent {
+	select {
+	case event, ok := <-room.Events():
+		if !ok {
+			t.Fatal("Events channel closed unexpectedly")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return DeviceEvent{}
+}
+
+func TestJoinRoom(t *testing.T) {
+	mock := &phoenixMock{t: t, closeAfterEvent: true}
+	client, server := getTestContextWithHandler(t, mock.ServeHTTP)
+	defer server.Close()
+	defer func(delay time.Duration) { roomReconnectBaseDelay = delay }(roomReconnectBaseDelay)
+	roomReconnectBaseDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	room, err := client.AppEngine.JoinRoom(ctx, testRealmName, "dashboard", getTestVolatileTrigger("temperatures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := receiveDeviceEvent(t, room)
+	if event.DeviceID != testDevices[0] || event.Type != "incoming_data" || event.Path != "/temperature" || event.Value != 1.0 ||
+		!event.Timestamp.Equal(time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// The server dropped the connection: the Room must reconnect and install its trigger again
+	if event := receiveDeviceEvent(t, room); event.Value != 2.0 {
+		t.Errorf("Unexpected event after reconnection %+v", event)
+	}
+	mock.lock.Lock()
+	if len(mock.watches) < 2 || mock.watches[1]["name"] != "temperatures" || mock.watches[1]["device_id"] != testDevices[0] {
+		t.Error("Trigger was not installed again", mock.watches)
+	}
+	mock.lock.Unlock()
+
+	cancel()
+	for range room.Events() {
+		// Drain events fired by further reconnections, until the channel is closed
+	}
+}
+
+func TestJoinRoomErrors(t *testing.T) {
+	mock := &phoenixMock{t: t}
+	client, server := getTestContextWithHandler(t, mock.ServeHTTP)
+	defer server.Close()
+
+	if _, err := client.AppEngine.JoinRoom(context.Background(), testRealmName, "other", getTestVolatileTrigger("temperatures")); err == nil {
+		t.Error("Expected an error joining a forbidden room")
+	}
+
+	noTarget := getTestVolatileTrigger("temperatures")
+	noTarget.SimpleTriggers[0].DeviceID = ""
+	if _, err := client.AppEngine.JoinRoom(context.Background(), testRealmName, "dashboard", noTarget); err == nil {
+		t.Error("Expected an error for a trigger without a target")
+	}
+
+	client.SetToken("invalid")
+	if _, err := client.AppEngine.JoinRoom(context.Background(), testRealmName, "dashboard", getTestVolatileTrigger("temperatures")); err == nil {
+		t.Error("Expected an error with an invalid token")
+	}
+}
+
+func TestInstallVolatileTrigger(t *testing.T) {
+	mock := &phoenixMock{t: t}
+	client, server := getTestContextWithHandler(t, mock.ServeHTTP)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	room, err := client.AppEngine.JoinRoom(ctx, testRealmName, "dashboard", getTestVolatileTrigger("temperatures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Installing a trigger while the event of the first one has not been read must not wait for it
+	installCtx, installCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer installCancel()
+	subscription, err := room.InstallVolatileTrigger(installCtx, getTestVolatileTrigger("humidity"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveDeviceEvent(t, room)
+	receiveDeviceEvent(t, room)
+
+	if _, err := room.InstallVolatileTrigger(ctx, getTestVolatileTrigger("humidity")); err == nil {
+		t.Error("Expected an error installing a duplicate trigger")
+	}
+
+	if err := subscription.Unsubscribe(); err != nil {
+		t.Error(err)
+	}
+	mock.lock.Lock()
+	if len(mock.watches) != 1 || mock.watches[0]["name"] != "temperatures" {
+		t.Error("Unexpected installed triggers", mock.watches)
+	}
+	mock.lock.Unlock()
+}
+
+func TestJoinRoomUsesClientTransport(t *testing.T) {
+	mock := &phoenixMock{t: t}
+	var apiKey string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		apiKey = req.Header.Get("X-Api-Key")
+		mock.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, nil, WithInsecureSkipVerify(true), WithRequestHeaders(http.Header{"X-Api-Key": {"secret"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(testTokenValue)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	room, err := client.AppEngine.JoinRoom(ctx, testRealmName, "dashboard", getTestVolatileTrigger("temperatures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveDeviceEvent(t, room)
+	if apiKey != "secret" {
+		t.Error("Request headers were not sent with the handshake")
+	}
+}
+
+func TestPhoenixSocketHeartbeatTimeout(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		phoenixHeartbeatInterval, phoenixReplyTimeout = interval, timeout
+	}(phoenixHeartbeatInterval, phoenixReplyTimeout)
+	phoenixHeartbeatInterval, phoenixReplyTimeout = 10*time.Millisecond, 50*time.Millisecond
+
+	// The server keeps the connection open, but never acknowledges heartbeats
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	socket, err := dialPhoenixSocket(context.Background(), newWebsocketDialer(server.Client()), "ws"+strings.TrimPrefix(server.URL, "http"),
+		nil, func(phoenixMessage) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-socket.done:
+	case <-time.After(5 * time.Second):
+		socket.close()
+		t.Error("The socket was not closed after a missed heartbeat")
+	}
+}
@@ -0,0 +1,535 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func TestGetDatastreamObjectSnapshot(t *testing.T) {
+	replies := map[string]string{
+		"empty-object": `{"data":{}}`,
+		"empty-array":  `{"data":[]}`,
+		"values":       `{"data":[{"timestamp":"2020-03-23T12:31:08.356Z","val1":true,"val2":12}]}`,
+	}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		for k, v := range replies {
+			if req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testDevices[0], k) {
+				fmt.Fprint(w, v)
+				return
+			}
+		}
+		t.Error("Unexpected path", req.URL.Path)
+	})
+	defer server.Close()
+
+	for _, iface := range []string{"empty-object", "empty-array"} {
+		values, err := client.AppEngine.GetDatastreamObjectSnapshot(testRealmName, testDevices[0], AstarteDeviceID, iface)
+		if err != nil {
+			t.Error(err)
+		}
+		if values == nil || len(values) != 0 {
+			t.Error("Expected an empty map, got", values)
+		}
+	}
+
+	values, err := client.AppEngine.GetDatastreamObjectSnapshot(testRealmName, testDevices[0], AstarteDeviceID, "values")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(values, map[string]interface{}{"val1": true, "val2": float64(12)}) {
+		t.Error("Wrong values returned", values)
+	}
+}
+
+func TestGetDatastreamIndividualValues(t *testing.T) {
+	since := time.Date(2020, 3, 12, 19, 0, 0, 123456789, time.UTC)
+	to := since.Add(time.Hour)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("since") != "2020-03-12T19:00:00.123Z" || query.Get("to") != "2020-03-12T20:00:00.123Z" {
+			t.Error("Wrong time window in query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"data":[{"value":1,"timestamp":"2020-03-12T19:10:00.000Z"},`+
+			`{"value":2,"timestamp":"2020-03-12T19:20:00.000Z"},{"value":3,"timestamp":"2020-03-12T19:30:00.000Z"}]}`)
+	})
+	defer server.Close()
+
+	values, err := client.AppEngine.GetDatastreamIndividualValues(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", since, to, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[1].Value != float64(2) {
+		t.Error("Wrong values returned", values)
+	}
+}
+
+func TestGetDatastreamIndividualPaginator(t *testing.T) {
+	pages := []string{
+		`{"data":[{"value":1,"timestamp":"2020-03-12T19:10:00.000Z"},{"value":2,"timestamp":"2020-03-12T19:20:00.000Z"}]}`,
+		`{"data":[{"value":3,"timestamp":"2020-03-12T19:30:00.000Z"}]}`,
+	}
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("limit") != "2" {
+			t.Error("Wrong limit in query", req.URL.RawQuery)
+		}
+		if calls > 0 && query.Get("since_after") != "2020-03-12T19:20:00.000Z" {
+			t.Error("Wrong cursor in query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, pages[calls])
+		calls++
+	})
+	defer server.Close()
+
+	order := AscendingOrder
+	paginator, err := client.AppEngine.GetDatastreamIndividualPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", DatastreamQueryOptions{PageSize: 2, Order: &order})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []DatastreamValue{}
+	for paginator.HasNextPage() {
+		page, err := paginator.GetNextPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, page...)
+	}
+	if len(values) != 3 || calls != 2 {
+		t.Error("Wrong values returned", values, calls)
+	}
+}
+
+func TestGetDatastreamIndividualPaginatorDefaultOrder(t *testing.T) {
+	pages := []string{
+		`{"data":[{"value":3,"timestamp":"2020-03-12T19:30:00.000Z"},{"value":2,"timestamp":"2020-03-12T19:20:00.000Z"}]}`,
+		`{"data":[{"value":1,"timestamp":"2020-03-12T19:10:00.000Z"}]}`,
+	}
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("since_after") != "" {
+			t.Error("Unexpected ascending cursor in query", req.URL.RawQuery)
+		}
+		if calls > 0 && query.Get("to") != "2020-03-12T19:20:00.000Z" {
+			t.Error("Wrong cursor in query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, pages[calls])
+		calls++
+	})
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDatastreamIndividualPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", DatastreamQueryOptions{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paginator.GetResultSetOrder() != DescendingOrder {
+		t.Error("Paginator should default to DescendingOrder")
+	}
+
+	values := []DatastreamValue{}
+	for paginator.HasNextPage() {
+		page, err := paginator.GetNextPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, page...)
+	}
+	if len(values) != 3 || values[0].Value != float64(3) || calls != 2 {
+		t.Error("Wrong values returned", values, calls)
+	}
+}
+
+func TestGetDatastreamIndividualPaginatorDownsampling(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("limit") != "500" || query.Get("downsample_to") != "500" || query.Get("downsample_key") != "value" ||
+			query.Get("keep_milliseconds") != "true" {
+			t.Error("Wrong query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDatastreamIndividualPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value",
+		DatastreamQueryOptions{DownsampleTo: 500, DownsampleKey: "value", KeepMilliseconds: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paginator.GetNextPage(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := client.AppEngine.GetDatastreamIndividualPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", DatastreamQueryOptions{DownsampleTo: -1}); err == nil {
+		t.Error("Expected an error for a negative DownsampleTo")
+	}
+}
+
+func TestSetAndUnsetProperty(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.SamplingRate"
+	endpoint := fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/sensor/enable", testRealmName, testDevices[0], iface)
+	methods := []string{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != endpoint {
+			t.Error("Unexpected path", req.URL.Path)
+		}
+		methods = append(methods, req.Method)
+		if req.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer server.Close()
+
+	if err := client.AppEngine.SetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, "/sensor/enable", true); err != nil {
+		t.Error(err)
+	}
+	if err := client.AppEngine.UnsetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, "/sensor/enable"); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(methods, []string{http.MethodPut, http.MethodDelete}) {
+		t.Error("Wrong requests performed", methods)
+	}
+
+	if err := client.AppEngine.UnsetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, "sensor/enable"); err == nil {
+		t.Error("Expected an error for a path without leading slash")
+	}
+	if err := client.AppEngine.SetProperty(testRealmName, testDevices[0], AstarteDeviceID, "", "/sensor/enable", true); err == nil {
+		t.Error("Expected an error for an empty interface name")
+	}
+}
+
+func TestDeleteInterfaceData(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.Values"
+	endpoint := fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testDevices[0], iface)
+	supported, routed := true, true
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method != http.MethodDelete:
+			t.Error("Unexpected method", req.Method)
+		case !routed:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Not Found"}})
+		case !strings.HasPrefix(req.URL.Path, endpoint):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Device not found"}})
+		case req.URL.Path == endpoint+"/streamTest/missing":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Path not found"}})
+		case req.URL.Path != endpoint && !strings.HasPrefix(req.URL.Path, endpoint+"/"):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Interface not found"}})
+		case !supported:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer server.Close()
+
+	if err := client.AppEngine.DeleteInterfaceData(testRealmName, testDevices[0], AstarteDeviceID, iface); err != nil {
+		t.Error(err)
+	}
+	if err := client.AppEngine.DeleteDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface, "/streamTest/value"); err != nil {
+		t.Error(err)
+	}
+	if err := client.AppEngine.DeleteInterfaceData(testRealmName, testDevices[1], AstarteDeviceID, iface); !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+	// A 404 on a missing Interface of an existing Device doesn't mean the endpoint is unsupported
+	err := client.AppEngine.DeleteInterfaceData(testRealmName, testDevices[0], AstarteDeviceID, iface+".Missing")
+	if err == nil || errors.Is(err, ErrUnsupportedByAstarte) {
+		t.Error("Expected a plain 404 for a missing Interface, got", err)
+	}
+	err = client.AppEngine.DeleteDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface, "/streamTest/missing")
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Error("Expected ErrPathNotFound, got", err)
+	}
+	err = client.AppEngine.DeleteDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface+".Missing", "/streamTest/value")
+	if err == nil || errors.Is(err, ErrPathNotFound) {
+		t.Error("Expected a plain 404 for a missing Interface, got", err)
+	}
+
+	routed = false
+	if err := client.AppEngine.DeleteInterfaceData(testRealmName, testDevices[0], AstarteDeviceID, iface); !errors.Is(err, ErrUnsupportedByAstarte) {
+		t.Error("Expected ErrUnsupportedByAstarte without a route, got", err)
+	}
+
+	routed, supported = true, false
+	if err := client.AppEngine.DeleteInterfaceData(testRealmName, testDevices[0], AstarteDeviceID, iface); !errors.Is(err, ErrUnsupportedByAstarte) {
+		t.Error("Expected ErrUnsupportedByAstarte, got", err)
+	}
+	err = client.AppEngine.DeleteDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface, "/streamTest/value")
+	if !errors.Is(err, ErrUnsupportedByAstarte) {
+		t.Error("Expected ErrUnsupportedByAstarte, got", err)
+	}
+}
+
+func TestSendDatastreamWithTimestamp(t *testing.T) {
+	var body map[string]interface{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		body = map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	})
+	defer server.Close()
+
+	iface := "org.astarte-platform.genericcommands.ServerCommands"
+	timestamp := time.Date(2020, 3, 12, 19, 0, 0, 0, time.UTC)
+	if err := client.AppEngine.SendDatastreamWithTimestamp(testRealmName, testDevices[0], AstarteDeviceID, iface, "/command",
+		"reboot", &timestamp); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(body, map[string]interface{}{"data": "reboot", "timestamp": "2020-03-12T19:00:00Z"}) {
+		t.Error("Wrong payload sent", body)
+	}
+
+	if err := client.AppEngine.SendDatastreamWithTimestamp(testRealmName, testDevices[0], AstarteDeviceID, iface, "/command",
+		"reboot", nil); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(body, map[string]interface{}{"data": "reboot"}) {
+		t.Error("Wrong payload sent", body)
+	}
+}
+
+func TestSendDatastreamObject(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.Geolocation"
+	var body map[string]interface{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/gps", testRealmName, testDevices[0], iface) {
+			t.Error("Unexpected path", req.URL.Path)
+		}
+		body = map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	})
+	defer server.Close()
+
+	values := map[string]interface{}{"latitude": 45.4, "longitude": 9.1}
+	if err := client.AppEngine.SendDatastreamObject(testRealmName, testDevices[0], AstarteDeviceID, iface, "/gps", values, nil); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(body, map[string]interface{}{"data": values}) {
+		t.Error("Wrong payload sent", body)
+	}
+
+	if err := client.AppEngine.SendDatastreamObject(testRealmName, testDevices[0], AstarteDeviceID, iface, "/gps/latitude",
+		values, nil); err == nil {
+		t.Error("Expected an error for a base path including an endpoint")
+	}
+	if err := client.AppEngine.SendDatastreamObject(testRealmName, testDevices[0], AstarteDeviceID, iface, "/gps",
+		map[string]interface{}{"position/latitude": 45.4}, nil); err == nil {
+		t.Error("Expected an error for keys with slashes")
+	}
+
+	body = nil
+	geolocation := interfaces.AstarteInterface{
+		Name:        iface,
+		Type:        interfaces.DatastreamType,
+		Aggregation: interfaces.ObjectAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor}/latitude", Type: interfaces.Double},
+			{Endpoint: "/%{sensor}/longitude", Type: interfaces.Double},
+		},
+	}
+	err := client.AppEngine.SendDatastreamObjectWithContext(context.Background(), testRealmName, testDevices[0], AstarteDeviceID,
+		iface, "/gps", map[string]interface{}{"latitude": 45.4, "altitude": 120.0}, nil, WithInterfaceValidation(geolocation))
+	var invalidPathsError *interfaces.InvalidPathsError
+	if !errors.As(err, &invalidPathsError) || !reflect.DeepEqual(invalidPathsError.Paths, []string{"/gps/altitude"}) {
+		t.Error("Expected an InvalidPathsError for /gps/altitude, got", err)
+	}
+	if body != nil {
+		t.Error("Invalid object sent", body)
+	}
+	if err := client.AppEngine.SendDatastreamObjectWithContext(context.Background(), testRealmName, testDevices[0], AstarteDeviceID,
+		iface, "/gps", values, nil, WithInterfaceValidation(geolocation)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithInterfaceValidation(t *testing.T) {
+	requests := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+	})
+	defer server.Close()
+
+	iface := interfaces.AstarteInterface{
+		Name:        "org.astarte-platform.genericcommands.ServerCommands",
+		Type:        interfaces.DatastreamType,
+		Aggregation: interfaces.IndividualAggregation,
+		Mappings:    []interfaces.AstarteInterfaceMapping{{Endpoint: "/%{device}/command", Type: interfaces.String}},
+	}
+	ctx := context.Background()
+	if err := client.AppEngine.SendDatastreamWithContext(ctx, testRealmName, testDevices[0], AstarteDeviceID, iface.Name,
+		"/dev0/command", "reboot", WithInterfaceValidation(iface)); err != nil {
+		t.Error(err)
+	}
+	if err := client.AppEngine.SendDatastreamWithContext(ctx, testRealmName, testDevices[0], AstarteDeviceID, iface.Name,
+		"/command", "reboot", WithInterfaceValidation(iface)); err == nil {
+		t.Error("Expected an error for a path not matching the interface")
+	}
+	if err := client.AppEngine.SendDatastreamWithContext(ctx, testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericcommands.Other", "/dev0/command", "reboot", WithInterfaceValidation(iface)); err == nil {
+		t.Error("Expected an error for a different interface")
+	}
+	if requests != 1 {
+		t.Errorf("Expected only 1 request, got %d", requests)
+	}
+}
+
+func TestGetProperty(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.SamplingRate"
+	endpoint := fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testDevices[0], iface)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case endpoint + "/sensor/samplingPeriod":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": 10})
+		case endpoint + "/sensor/enable":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": false})
+		case endpoint + "/sensor/nulled":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": nil})
+		case fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s/sensor/enable", testRealmName, testDevices[0], iface+"Missing"):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Interface not found"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Path not found"}})
+		}
+	})
+	defer server.Close()
+
+	if value, err := client.AppEngine.GetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, "/sensor/samplingPeriod"); err != nil || value != 10.0 {
+		t.Error("Unexpected value", value, err)
+	}
+	if value, err := client.AppEngine.GetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, "/sensor/enable"); err != nil || value != false {
+		t.Error("Unexpected value", value, err)
+	}
+	for _, unset := range []string{"/sensor/nulled", "/sensor/missing"} {
+		if _, err := client.AppEngine.GetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface, unset); !errors.Is(err, ErrPathNotFound) {
+			t.Error("Expected ErrPathNotFound, got", err)
+		}
+	}
+	// Other 404s, such as a missing Interface, are not about the path
+	_, err := client.AppEngine.GetProperty(testRealmName, testDevices[0], AstarteDeviceID, iface+"Missing", "/sensor/enable")
+	if err == nil || errors.Is(err, ErrPathNotFound) {
+		t.Error("Expected a plain 404 for a missing Interface, got", err)
+	}
+}
+
+func TestGetLatestDatastreamValue(t *testing.T) {
+	iface := "org.astarte-platform.genericsensors.Values"
+	endpoint := fmt.Sprintf("/appengine/v1/%s/devices/%s/interfaces/%s", testRealmName, testDevices[0], iface)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case endpoint + "/sensor/value":
+			if limit := req.URL.Query().Get("limit"); limit != "1" {
+				t.Error("Wrong limit in query", limit)
+			}
+			fmt.Fprint(w, `{"data":[{"value":21.5,"timestamp":"2020-03-12T19:30:00.000Z"}]}`)
+		case endpoint + "/sensor/empty":
+			fmt.Fprint(w, `{"data":[]}`)
+		case endpoint + "/sensor/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Path not found"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Device not found"}}`)
+		}
+	})
+	defer server.Close()
+
+	value, err := client.AppEngine.GetLatestDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface, "/sensor/value")
+	if err != nil || value.Value != 21.5 {
+		t.Error("Unexpected value", value, err)
+	}
+	for _, path := range []string{"/sensor/empty", "/sensor/missing"} {
+		if _, err := client.AppEngine.GetLatestDatastreamValue(testRealmName, testDevices[0], AstarteDeviceID, iface, path); !errors.Is(err, ErrNoData) {
+			t.Error("Expected ErrNoData, got", err)
+		}
+	}
+	if _, err := client.AppEngine.GetLatestDatastreamValue(testRealmName, testDevices[1], AstarteDeviceID, iface, "/sensor/value"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+}
+
+func TestSendDataEncodesValues(t *testing.T) {
+	iface := interfaces.AstarteInterface{
+		Name:         "org.astarte-platform.test.Uploads",
+		MinorVersion: 1,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.ServerOwnership,
+		Aggregation:  interfaces.ObjectAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{id}/content", Type: interfaces.BinaryBlob},
+			{Endpoint: "/%{id}/uploadedAt", Type: interfaces.DateTime},
+			{Endpoint: "/%{id}/size", Type: interfaces.Double},
+		},
+	}
+	var body map[string]interface{}
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		body = map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	})
+	defer server.Close()
+
+	values := map[string]interface{}{
+		"content":    []byte("hello"),
+		"uploadedAt": time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC),
+		"size":       5,
+	}
+	if err := client.AppEngine.SendData(testRealmName, testDevices[0], AstarteDeviceID, iface, "/file", values); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"data": map[string]interface{}{
+		"content":    "aGVsbG8=",
+		"uploadedAt": "2020-10-15T12:00:00.000Z",
+		"size":       5.0,
+	}}
+	if !reflect.DeepEqual(body, expected) {
+		t.Error("Wrong payload sent", body)
+	}
+
+	values["size"] = "5 bytes"
+	if err := client.AppEngine.SendData(testRealmName, testDevices[0], AstarteDeviceID, iface, "/file", values); err == nil {
+		t.Error("Expected an error for a mismatched type")
+	}
+	if calls != 1 {
+		t.Error("Invalid data reached Astarte")
+	}
+}
@@ -16,6 +16,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,18 +25,57 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"runtime/debug"
+	"sync"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/astarte-platform/astarte-go/misc"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 const (
-	userAgent = "astarte-go"
+	userAgent  = "astarte-go"
+	modulePath = "github.com/astarte-platform/astarte-go"
 )
 
 // Exported errors
 var (
 	ErrMalformedPayload = errors.New("received an invalid JSONAPI payload")
+	// ErrDeviceNotFound is returned (wrapped in an AstarteAPIError) when the requested Device does not exist
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrInterfaceAlreadyInstalled is returned (wrapped in an AstarteAPIError) when installing an Interface
+	// major version which already exists in the Realm
+	ErrInterfaceAlreadyInstalled = errors.New("interface already installed")
+	// ErrInvalidInterface is returned (wrapped in an AstarteAPIError) when Astarte rejects an Interface or an
+	// Interface update. The reasons of the rejection can be found in the Errors field of the AstarteAPIError
+	ErrInvalidInterface = errors.New("invalid interface")
+	// ErrInterfaceNotFound is returned (wrapped in an AstarteAPIError) when the requested Interface, or major
+	// version of an Interface, is not installed in the Realm
+	ErrInterfaceNotFound = errors.New("interface not found")
+	// ErrInterfaceNotDeletable is returned (wrapped in an AstarteAPIError) when Astarte refuses to delete an
+	// Interface, which happens when it is not a draft or when Devices still have data on it
+	ErrInterfaceNotDeletable = errors.New("interface cannot be deleted: it is not a draft or it still has data")
+	// ErrDeviceAlreadyRegistered is returned (wrapped in an AstarteAPIError) when registering a Device which
+	// has already been registered. Use UnregisterDevice to register it again
+	ErrDeviceAlreadyRegistered = errors.New("device already registered")
+	// ErrPathNotFound is returned (possibly wrapped in an AstarteAPIError) when reading a Property path which
+	// is not set
+	ErrPathNotFound = errors.New("path not found")
+	// ErrInterfaceNotInIntrospection is returned when the requested Interface is not part of the current
+	// introspection of a Device
+	ErrInterfaceNotInIntrospection = errors.New("interface not in device introspection")
+	// ErrUnsupportedByAstarte is returned (wrapped in an AstarteAPIError) when the Astarte instance does not
+	// support the requested operation, usually because it runs an older version
+	ErrUnsupportedByAstarte = errors.New("unsupported on this Astarte version")
+	// ErrNotModified is returned by GET calls made with WithIfNoneMatch when conditional requests are enabled with
+	// WithConditionalRequests, and the requested data did not change since the previous identical call
+	ErrNotModified = errors.New("not modified")
+	// ErrRealmNotFound is returned (wrapped in an AstarteAPIError) when the requested Realm does not exist
+	ErrRealmNotFound = errors.New("realm not found")
+	// ErrNoData is returned (possibly wrapped in an AstarteAPIError) when a Datastream path has no values
+	ErrNoData = errors.New("no data")
 )
 
 // Client is the base Astarte API client. It provides access to all of Astarte's APIs.
@@ -48,6 +88,8 @@ var (
 // Before using a Client, you must set an Authentication Token. To do so, you can invoke the
 // SetToken functions, which provide a number of helper mechanisms to use Private Keys.
 // You can reset the token at any time, and it will be evaluated before every API invocation.
+// Long running applications can instead pass WithTokenProvider to NewClient, to refresh the token
+// as it expires.
 // In most cases, you want to map an individual Client object to either Housekeeping or a
 // Realm, but in some cases you might want to reset the token often (for example, this applies
 // to methods such as GetMQTTv1ProtocolInformationForDevice and ObtainNewMQTTv1CertificateForDevice,
@@ -56,15 +98,65 @@ type Client struct {
 	baseURL   *url.URL
 	UserAgent string
 
-	httpClient *http.Client
-	token      string
+	httpClient     *http.Client
+	token          string
+	tokenProvider  *cachingTokenProvider
+	retryPolicy    retryPolicy
+	rateLimiter    *rate.Limiter
+	requestLogging requestLogging
+	tracer         trace.Tracer
+	// realmTokenProvider, if set, provides a different token for each Realm, see WithRealmTokenProvider
+	realmTokenProvider *realmTokenProvider
+	// metricsCollector, if set, is notified of every API call, see WithMetricsCollector
+	metricsCollector Collector
+	// devicesPageSize is the page size used when listing all the Devices of a Realm or a group
+	devicesPageSize int
+	// requestHeaders are added to every request, see WithRequestHeaders
+	requestHeaders http.Header
+	// etags is set when conditional requests are enabled, see WithConditionalRequests
+	etags *etagCache
+	// dryRun makes the Client skip all requests which are not read-only, see WithDryRun
+	dryRun bool
+	// astarteVersion caches the result of GetAstarteVersion, and astarteVersionErr the error it returned if
+	// Astarte predates the version endpoint
+	astarteVersion    *semver.Version
+	astarteVersionErr error
+	versionLock       sync.Mutex
 
 	AppEngine       *AppEngineService
+	Flow            *FlowService
 	Housekeeping    *HousekeepingService
 	Pairing         *PairingService
 	RealmManagement *RealmManagementService
 }
 
+func newDefaultHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: time.Second * 30,
+	}
+}
+
+// libraryUserAgent returns the User-Agent identifying this library, along with its version when it can be found
+// in the build information of the binary.
+func libraryUserAgent() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return userAgent
+	}
+	for _, dependency := range info.Deps {
+		if dependency.Path != modulePath {
+			continue
+		}
+		if dependency.Replace != nil {
+			dependency = dependency.Replace
+		}
+		if dependency.Version != "" && dependency.Version != "(devel)" {
+			return userAgent + "/" + dependency.Version
+		}
+	}
+	return userAgent
+}
+
 // Links is a struct that represent the links metadata returned by Astarte API.
 // This metadata is used in Astarte APIs to perform pagination, allowing the
 // user to simply follow the Next link, if any, to fetch the next page
@@ -73,12 +165,14 @@ type Links struct {
 	Next string `json:"next,omitempty"`
 }
 
-// NewClient creates a new Astarte API client with standard URL hierarchies.
-func NewClient(rawBaseURL string, httpClient *http.Client) (*Client, error) {
+// NewClient creates a new Astarte API client with standard URL hierarchies, that is with each service exposed
+// under rawBaseURL at its conventional path: /appengine, /flow, /housekeeping, /pairing and /realmmanagement.
+// Services exposed elsewhere can be relocated with WithServiceURL. If httpClient is nil, a client with a 30
+// seconds timeout is used.
+// Its behavior can be customized with any number of ClientOption.
+func NewClient(rawBaseURL string, httpClient *http.Client, options ...ClientOption) (*Client, error) {
 	if httpClient == nil {
-		httpClient = &http.Client{
-			Timeout: time.Second * 30,
-		}
+		httpClient = newDefaultHTTPClient()
 	}
 
 	baseURL, err := url.Parse(rawBaseURL)
@@ -86,7 +180,8 @@ func NewClient(rawBaseURL string, httpClient *http.Client) (*Client, error) {
 		return nil, err
 	}
 
-	c := &Client{httpClient: httpClient, baseURL: baseURL, UserAgent: userAgent}
+	c := &Client{httpClient: httpClient, baseURL: baseURL, UserAgent: libraryUserAgent(),
+		devicesPageSize: defaultPageSize}
 
 	// Apparently that's how you deep-copy the URLs.
 	// We're ignoring errors here as the cross-parsing cannot fail.
@@ -94,6 +189,10 @@ func NewClient(rawBaseURL string, httpClient *http.Client) (*Client, error) {
 	appEngineURL.Path = path.Join(appEngineURL.Path, "appengine")
 	c.AppEngine = &AppEngineService{client: c, appEngineURL: appEngineURL}
 
+	flowURL, _ := url.Parse(baseURL.String())
+	flowURL.Path = path.Join(flowURL.Path, "flow")
+	c.Flow = &FlowService{client: c, flowURL: flowURL}
+
 	housekeepingURL, _ := url.Parse(baseURL.String())
 	housekeepingURL.Path = path.Join(housekeepingURL.Path, "housekeeping")
 	c.Housekeeping = &HousekeepingService{client: c, housekeepingURL: housekeepingURL}
@@ -106,19 +205,24 @@ func NewClient(rawBaseURL string, httpClient *http.Client) (*Client, error) {
 	realmManagementURL.Path = path.Join(realmManagementURL.Path, "realmmanagement")
 	c.RealmManagement = &RealmManagementService{client: c, realmManagementURL: realmManagementURL}
 
+	if err := c.applyOptions(options); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
 // NewClientWithIndividualURLs creates a new Astarte API client with custom URL hierarchies.
-// Only services added in the individualURLs map will be instantiated - the others will be nil
-func NewClientWithIndividualURLs(individualURLs map[misc.AstarteService]string, httpClient *http.Client) (*Client, error) {
+// Only services added in the individualURLs map will be instantiated - the others will be nil.
+// Its behavior can be customized with any number of ClientOption.
+func NewClientWithIndividualURLs(individualURLs map[misc.AstarteService]string, httpClient *http.Client,
+	options ...ClientOption) (*Client, error) {
 	if httpClient == nil {
-		httpClient = &http.Client{
-			Timeout: time.Second * 30,
-		}
+		httpClient = newDefaultHTTPClient()
 	}
 
-	c := &Client{httpClient: httpClient, baseURL: nil, UserAgent: userAgent}
+	c := &Client{httpClient: httpClient, baseURL: nil, UserAgent: libraryUserAgent(),
+		devicesPageSize: defaultPageSize}
 
 	for k, v := range individualURLs {
 		// Parse URL
@@ -127,33 +231,116 @@ func NewClientWithIndividualURLs(individualURLs map[misc.AstarteService]string,
 			return nil, err
 		}
 
-		switch k {
-		case misc.AppEngine:
-			c.AppEngine = &AppEngineService{client: c, appEngineURL: parsedURL}
-		case misc.Housekeeping:
-			c.Housekeeping = &HousekeepingService{client: c, housekeepingURL: parsedURL}
-		case misc.Pairing:
-			c.Pairing = &PairingService{client: c, pairingURL: parsedURL}
-		case misc.RealmManagement:
-			c.RealmManagement = &RealmManagementService{client: c, realmManagementURL: parsedURL}
-		}
+		c.setServiceURL(k, parsedURL)
+	}
+
+	if err := c.applyOptions(options); err != nil {
+		return nil, err
 	}
 
 	return c, nil
 }
 
-func errorFromJSONErrors(responseBody io.Reader) error {
-	var errorBody struct {
-		Errors map[string]interface{} `json:"errors"`
+// setServiceURL makes service available at serviceURL, replacing its previous URL if any. It returns false if
+// service has no corresponding Service in the Client.
+func (c *Client) setServiceURL(service misc.AstarteService, serviceURL *url.URL) bool {
+	switch service {
+	case misc.AppEngine:
+		c.AppEngine = &AppEngineService{client: c, appEngineURL: serviceURL}
+	case misc.Flow:
+		c.Flow = &FlowService{client: c, flowURL: serviceURL}
+	case misc.Housekeeping:
+		c.Housekeeping = &HousekeepingService{client: c, housekeepingURL: serviceURL}
+	case misc.Pairing:
+		c.Pairing = &PairingService{client: c, pairingURL: serviceURL}
+	case misc.RealmManagement:
+		c.RealmManagement = &RealmManagementService{client: c, realmManagementURL: serviceURL}
+	default:
+		return false
+	}
+	return true
+}
+
+// AstarteAPIError is returned whenever an Astarte API replies with an unexpected status code.
+// Use errors.As to retrieve it from an error returned by any Service, and inspect StatusCode to
+// react to specific failures.
+type AstarteAPIError struct {
+	// StatusCode is the HTTP status code returned by the API
+	StatusCode int
+	// Detail is the error name reported by Astarte, if any (e.g. "Device not found")
+	Detail string
+	// Errors is the content of the "errors" object returned by Astarte, if any
+	Errors map[string]interface{}
+	// Body is the raw body of the response
+	Body []byte
+	// RetryAfter is the delay requested by the Retry-After header of a 429 or 503 response, if any
+	RetryAfter time.Duration
+
+	// cause is an optional sentinel error returned by Unwrap, set by the caller when the
+	// status code has a specific meaning in the context of the call
+	cause error
+}
+
+func (e *AstarteAPIError) Error() string {
+	if e.Errors == nil {
+		return fmt.Sprintf("received unexpected status code %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
 	}
 
-	err := json.NewDecoder(responseBody).Decode(&errorBody)
+	errJSON, _ := json.MarshalIndent(map[string]interface{}{"errors": e.Errors}, "", "  ")
+	return fmt.Sprintf("%s", errJSON)
+}
+
+// Unwrap returns the sentinel error matching the failure, if any (e.g. ErrDeviceNotFound).
+func (e *AstarteAPIError) Unwrap() error {
+	return e.cause
+}
+
+func errorFromJSONErrors(resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	errJSON, _ := json.MarshalIndent(&errorBody, "", "  ")
-	return fmt.Errorf("%s", errJSON)
+	var errorBody struct {
+		Errors map[string]interface{} `json:"errors"`
+	}
+	apiError := &AstarteAPIError{StatusCode: resp.StatusCode, Body: body}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		apiError.RetryAfter, _ = retryAfter(resp)
+	}
+	// The body might not be JSON at all (e.g. when an error comes from a proxy), in that
+	// case we just return the raw body
+	if err := json.Unmarshal(body, &errorBody); err == nil && errorBody.Errors != nil {
+		apiError.Errors = errorBody.Errors
+		if detail, ok := errorBody.Errors["detail"].(string); ok {
+			apiError.Detail = detail
+		}
+	}
+
+	return apiError
+}
+
+// withErrorCause makes err unwrap to cause if it is an AstarteAPIError with the given status code.
+func withErrorCause(err error, statusCode int, cause error) error {
+	var apiError *AstarteAPIError
+	if errors.As(err, &apiError) && apiError.StatusCode == statusCode {
+		apiError.cause = cause
+	}
+	return err
+}
+
+// addRequestHeaders adds the headers set with WithRequestHeaders to req, unless req already sets them.
+// Authorization is never among them.
+func (c *Client) addRequestHeaders(req *http.Request) {
+	for key, values := range c.requestHeaders {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
 }
 
 // SetTokenFromPrivateKeyFile generates a token from the supplied private key file and uses it for the session.
@@ -224,86 +411,100 @@ func (c *Client) SetToken(token string) {
 	c.token = token
 }
 
-func (c *Client) genericJSONDataAPIGET(ret interface{}, urlString string, expectedReturnCode int) error {
-	return c.genericJSONDataAPIGETWithLinks(ret, nil, urlString, expectedReturnCode)
+func (c *Client) genericJSONDataAPIGET(ctx context.Context, ret interface{}, urlString string, expectedReturnCode int) error {
+	return c.genericJSONDataAPIGETWithLinks(ctx, ret, nil, urlString, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIGETWithLinks(ret interface{}, retLinks *Links, urlString string, expectedReturnCode int) error {
-	req, err := http.NewRequest("GET", urlString, nil)
+func (c *Client) genericJSONDataAPIGETWithLinks(ctx context.Context, ret interface{}, retLinks *Links, urlString string,
+	expectedReturnCode int) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", urlString, nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
 
 	return c.doJSONAPIReqWithLinks(ret, retLinks, req, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPost(urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteNoResponse("POST", urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIPost(ctx context.Context, urlString string, dataPayload interface{}, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteNoResponse(ctx, "POST", urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPut(urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteNoResponse("PUT", urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIPut(ctx context.Context, urlString string, dataPayload interface{}, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteNoResponse(ctx, "PUT", urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPatch(urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteNoResponseWithContentType("PATCH", urlString, dataPayload, "application/merge-patch+json", expectedReturnCode)
+func (c *Client) genericJSONDataAPIPatch(ctx context.Context, urlString string, dataPayload interface{}, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteNoResponseWithContentType(ctx, "PATCH", urlString, dataPayload,
+		"application/merge-patch+json", expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPostWithResponse(ret interface{}, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithResponse(ret, "POST", urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIPostWithResponse(ctx context.Context, ret interface{}, urlString string, dataPayload interface{},
+	expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithResponse(ctx, ret, "POST", urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPutWithResponse(ret interface{}, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithResponse(ret, "PUT", urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIPutWithResponse(ctx context.Context, ret interface{}, urlString string, dataPayload interface{},
+	expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithResponse(ctx, ret, "PUT", urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIPatchWithResponse(ret interface{}, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithResponseWithContentType(ret, "PATCH", urlString, dataPayload, "application/merge-patch+json", expectedReturnCode)
+func (c *Client) genericJSONDataAPIPatchWithResponse(ctx context.Context, ret interface{}, urlString string, dataPayload interface{},
+	expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithResponseWithContentType(ctx, ret, "PATCH", urlString, dataPayload,
+		"application/merge-patch+json", expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWriteNoResponse(httpVerb string, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWrite(nil, httpVerb, urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIWriteNoResponse(ctx context.Context, httpVerb string, urlString string, dataPayload interface{},
+	expectedReturnCode int) error {
+	return c.genericJSONDataAPIWrite(ctx, nil, httpVerb, urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWriteWithResponse(ret interface{}, httpVerb string, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWrite(ret, httpVerb, urlString, dataPayload, expectedReturnCode)
+func (c *Client) genericJSONDataAPIWriteWithResponse(ctx context.Context, ret interface{}, httpVerb string, urlString string,
+	dataPayload interface{}, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWrite(ctx, ret, httpVerb, urlString, dataPayload, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWriteNoResponseWithContentType(httpVerb string, urlString string, dataPayload interface{},
-	contentType string, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithContentType(nil, httpVerb, urlString, dataPayload, contentType, expectedReturnCode)
+func (c *Client) genericJSONDataAPIWriteNoResponseWithContentType(ctx context.Context, httpVerb string, urlString string,
+	dataPayload interface{}, contentType string, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithContentType(ctx, nil, httpVerb, urlString, dataPayload, contentType, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWriteWithResponseWithContentType(ret interface{}, httpVerb string, urlString string, dataPayload interface{},
-	contentType string, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithContentType(ret, httpVerb, urlString, dataPayload, contentType, expectedReturnCode)
+func (c *Client) genericJSONDataAPIWriteWithResponseWithContentType(ctx context.Context, ret interface{}, httpVerb string,
+	urlString string, dataPayload interface{}, contentType string, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithContentType(ctx, ret, httpVerb, urlString, dataPayload, contentType, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWrite(ret interface{}, httpVerb string, urlString string, dataPayload interface{}, expectedReturnCode int) error {
-	return c.genericJSONDataAPIWriteWithContentType(ret, httpVerb, urlString, dataPayload, "application/json", expectedReturnCode)
+func (c *Client) genericJSONDataAPIWrite(ctx context.Context, ret interface{}, httpVerb string, urlString string,
+	dataPayload interface{}, expectedReturnCode int) error {
+	return c.genericJSONDataAPIWriteWithContentType(ctx, ret, httpVerb, urlString, dataPayload, "application/json", expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIWriteWithContentType(ret interface{}, httpVerb string, urlString string, dataPayload interface{},
-	contentType string, expectedReturnCode int) error {
+func (c *Client) genericJSONDataAPIWriteWithContentType(ctx context.Context, ret interface{}, httpVerb string, urlString string,
+	dataPayload interface{}, contentType string, expectedReturnCode int) error {
 	var requestBody struct {
 		Data interface{} `json:"data"`
 	}
 	requestBody.Data = dataPayload
 
+	return c.genericJSONAPIWriteRequestBody(ctx, ret, httpVerb, urlString, requestBody, contentType, expectedReturnCode)
+}
+
+// genericJSONAPIWriteRequestBody sends requestBody as is, without wrapping it into a data enclosure. It is
+// meant for the few APIs requiring additional fields alongside data.
+func (c *Client) genericJSONAPIWriteRequestBody(ctx context.Context, ret interface{}, httpVerb string, urlString string,
+	requestBody interface{}, contentType string, expectedReturnCode int) error {
 	b := new(bytes.Buffer)
 	err := json.NewEncoder(b).Encode(requestBody)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(httpVerb, urlString, b)
+	req, err := http.NewRequestWithContext(ctx, httpVerb, urlString, b)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Authorization", "Bearer "+c.token)
 	req.Header.Add("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
@@ -311,30 +512,102 @@ func (c *Client) genericJSONDataAPIWriteWithContentType(ret interface{}, httpVer
 	return c.doJSONAPIReq(ret, req, expectedReturnCode)
 }
 
-func (c *Client) genericJSONDataAPIDelete(urlString string, expectedReturnCode int) error {
-	req, err := http.NewRequest("DELETE", urlString, nil)
+func (c *Client) genericJSONDataAPIDelete(ctx context.Context, urlString string, expectedReturnCode int) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", urlString, nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Authorization", "Bearer "+c.token)
 	req.Header.Set("User-Agent", c.UserAgent)
 
 	return c.doJSONAPIReq(nil, req, expectedReturnCode)
 }
 
+// credentialsSecretKey is the context key holding the Credentials Secret a Device request must be authenticated
+// with, in place of the token of the Client. See withCredentialsSecret.
+type credentialsSecretKey struct{}
+
+// withCredentialsSecret makes requests performed with the returned context authenticate as a Device, using its
+// credentialsSecret.
+func withCredentialsSecret(ctx context.Context, credentialsSecret string) context.Context {
+	return context.WithValue(ctx, credentialsSecretKey{}, credentialsSecret)
+}
+
+// getToken returns the token to authenticate requests with: the Credentials Secret of a Device if set in ctx with
+// withCredentialsSecret, or else the one from the token provider or set with SetToken.
+func (c *Client) getToken(ctx context.Context, realm string) (string, error) {
+	if credentialsSecret, ok := ctx.Value(credentialsSecretKey{}).(string); ok {
+		return credentialsSecret, nil
+	}
+	if provider := c.getTokenProvider(realm); provider != nil {
+		return provider.getToken(ctx)
+	}
+	return c.token, nil
+}
+
+// getTokenProvider returns the provider of the tokens for realm, or nil if the Client uses a static token
+func (c *Client) getTokenProvider(realm string) *cachingTokenProvider {
+	if c.realmTokenProvider != nil {
+		return c.realmTokenProvider.forRealm(realm)
+	}
+	return c.tokenProvider
+}
+
 func (c *Client) doJSONAPIReq(ret interface{}, req *http.Request, expectedReturnCode int) error {
 	return c.doJSONAPIReqWithLinks(ret, nil, req, expectedReturnCode)
 }
 
-func (c *Client) doJSONAPIReqWithLinks(ret interface{}, retLinks *Links, req *http.Request, expectedReturnCode int) error {
-	resp, err := c.httpClient.Do(req)
+func (c *Client) doJSONAPIReqWithLinks(ret interface{}, retLinks *Links, req *http.Request, expectedReturnCode int) (err error) {
+	if c.dryRun && !isReadOnlyMethod(req.Method) {
+		c.logDryRunRequest(req)
+		return nil
+	}
+
+	service, realm := c.describeRequestURL(req.URL)
+	req, span := c.startRequestSpan(req, service, realm)
+	var resp *http.Response
+	start := time.Now()
+	defer func() {
+		endRequestSpan(span, resp, err)
+		c.observeRequest(service, req, resp, time.Since(start))
+		if c.etags != nil && err == nil {
+			c.etags.store(req, resp)
+		}
+	}()
+
+	// Housekeeping requires a Housekeeping token even for requests about a specific Realm
+	tokenRealm := realm
+	if service == misc.Housekeeping {
+		tokenRealm = ""
+	}
+	token, err := c.getToken(req.Context(), tokenRealm)
+	if err != nil {
+		return err
+	}
+	c.addRequestHeaders(req)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if c.etags != nil {
+		c.etags.prepare(req)
+	}
+
+	resp, err = c.doHTTPRequest(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized && req.Context().Value(credentialsSecretKey{}) == nil {
+		if provider := c.getTokenProvider(tokenRealm); provider != nil {
+			provider.invalidate()
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotModified && req.Header.Get("If-None-Match") != "" {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return ErrNotModified
+	}
+
 	if resp.StatusCode != expectedReturnCode {
-		return errorFromJSONErrors(resp.Body)
+		return errorFromJSONErrors(resp)
 	}
 
 	// If we don't want the reply, discard the body and return
@@ -17,9 +17,18 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/misc"
 )
 
 const (
@@ -98,6 +107,12 @@ var testInterfaces map[string]string = map[string]string{
 
 var testDevices []string = []string{"1vMeFtaJQF259nMsnis3sw", "t1J1uQSBQRi_1F3zIrjyYw", "V_pY-ZrLQzWz4iGjGu-NuQ"}
 
+// testDeviceAliases maps aliases to Device IDs. The second alias is also a valid Device ID
+var testDeviceAliases map[string]string = map[string]string{
+	"sensor-1":               testDevices[1],
+	"kitchenSensorNumber01A": testDevices[2],
+}
+
 func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 	authorization := req.Header.Get("Authorization")
 	if len(authorization) <= 0 {
@@ -112,12 +127,105 @@ func astarteAPIMock(w http.ResponseWriter, req *http.Request) {
 	// Process request
 	switch {
 	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/devices", testRealmName):
+		// Paginate using the index of the next device as from_token
+		from, _ := strconv.Atoi(req.URL.Query().Get("from_token"))
+		limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+		if err != nil || from+limit > len(testDevices) {
+			limit = len(testDevices) - from
+		}
 		links := map[string]string{"self": fmt.Sprintf("/v1/%s/devices", testRealmName)}
-		reply := map[string]interface{}{"data": testDevices, "links": links}
+		if from+limit < len(testDevices) {
+			links["next"] = fmt.Sprintf("/v1/%s/devices?from_token=%d", testRealmName, from+limit)
+		}
+		reply := map[string]interface{}{"data": testDevices[from : from+limit], "links": links}
+		if req.URL.Query().Get("details") == "true" {
+			details := []DeviceDetails{}
+			for i, d := range testDevices[from : from+limit] {
+				// Only the first device is connected
+				details = append(details, DeviceDetails{DeviceID: d, Connected: from+i == 0})
+			}
+			reply["data"] = details
+		}
+		json.NewEncoder(w).Encode(reply)
+	case req.URL.Path == fmt.Sprintf("/appengine/v1/%s/stats/devices", testRealmName):
+		// Only the first device is connected
+		stats := DevicesStats{TotalDevices: int64(len(testDevices)), ConnectedDevices: 1}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": stats})
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces", testRealmName) && req.Method == http.MethodPost:
+		var body struct {
+			Data struct {
+				Name string `json:"interface_name"`
+			} `json:"data"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		if _, ok := testInterfaces[body.Data.Name]; ok {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Interface already exists"}})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/interfaces", testRealmName):
+		interfaceNames := []string{}
+		for name := range testInterfaces {
+			interfaceNames = append(interfaceNames, name)
+		}
+		sort.Strings(interfaceNames)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": interfaceNames})
+	case strings.HasPrefix(req.URL.Path, fmt.Sprintf("/realmmanagement/v1/%s/interfaces/", testRealmName)):
+		tokens := strings.Split(strings.TrimPrefix(req.URL.Path, fmt.Sprintf("/realmmanagement/v1/%s/interfaces/", testRealmName)), "/")
+		definition, ok := testInterfaces[tokens[0]]
+		// All test interfaces have major version 0
+		if !ok || (len(tokens) > 1 && tokens[1] != "0") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Interface not found"}})
+			return
+		}
+		if len(tokens) > 1 {
+			fmt.Fprintf(w, `{"data": %s}`, definition)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []int{0}})
+	case strings.HasPrefix(req.URL.Path, fmt.Sprintf("/appengine/v1/%s/devices-by-alias/", testRealmName)):
+		deviceID, ok := testDeviceAliases[path.Base(req.URL.Path)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Device not found"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": DeviceDetails{DeviceID: deviceID}})
+	case strings.HasPrefix(req.URL.Path, fmt.Sprintf("/appengine/v1/%s/devices/", testRealmName)):
+		deviceID := path.Base(req.URL.Path)
+		for _, d := range testDevices {
+			if d == deviceID && req.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			} else if d == deviceID {
+				reply := map[string]interface{}{"data": testDeviceDetails(d)}
+				json.NewEncoder(w).Encode(reply)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		reply := map[string]interface{}{"errors": map[string]string{"detail": "Device not found"}}
 		json.NewEncoder(w).Encode(reply)
 	}
 }
 
+// testDeviceDetails returns the details of deviceID served by astarteAPIMock
+func testDeviceDetails(deviceID string) DeviceDetails {
+	return DeviceDetails{
+		DeviceID:              deviceID,
+		TotalReceivedMessages: 42,
+		TotalReceivedBytes:    4200,
+		Introspection: map[string]DeviceInterfaceIntrospection{
+			"org.astarte-platform.genericsensors.Values": {Major: 1, Minor: 0, ExchangedMessages: 40, ExchangedBytes: 4000},
+		},
+		PreviousInterfaces: []DeviceInterfaceIntrospection{
+			{Name: "org.astarte-platform.genericsensors.Values", Major: 0, Minor: 1, ExchangedMessages: 2, ExchangedBytes: 200},
+		},
+	}
+}
+
 func getTestContext(t *testing.T) (*Client, *httptest.Server) {
 	// Start a local HTTP server
 	server := httptest.NewServer(http.HandlerFunc(astarteAPIMock))
@@ -131,3 +239,242 @@ func getTestContext(t *testing.T) (*Client, *httptest.Server) {
 
 	return client, server
 }
+
+func getTestContextWithHandler(t *testing.T, handler http.HandlerFunc, options ...ClientOption) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	client, err := NewClient(server.URL, server.Client(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(testTokenValue)
+
+	return client, server
+}
+
+func TestWithRequestHeaders(t *testing.T) {
+	var received http.Header
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		received = req.Header
+		w.WriteHeader(http.StatusNoContent)
+	}, WithRequestHeaders(http.Header{
+		"x-api-key":     {"secret"},
+		"Authorization": {"Bearer other"},
+		"Content-Type":  {"text/plain"},
+	}))
+	defer server.Close()
+
+	if err := client.AppEngine.RemoveDeviceFromGroup(testRealmName, "group", testDevices[0], AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if received.Get("X-Api-Key") != "secret" {
+		t.Error("Custom header not sent", received)
+	}
+	if received.Get("Authorization") != "Bearer "+testTokenValue {
+		t.Error("Authorization was clobbered", received.Get("Authorization"))
+	}
+	if received.Get("User-Agent") != libraryUserAgent() {
+		t.Error("User-Agent was clobbered", received.Get("User-Agent"))
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(astarteAPIMock))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, nil, WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.httpClient != server.Client() {
+		t.Error("Custom HTTP client was not set")
+	}
+
+	client, err = NewClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.httpClient.Timeout != 30*time.Second {
+		t.Error("Unexpected default timeout", client.httpClient.Timeout)
+	}
+
+	if _, err := NewClient(server.URL, nil, WithHTTPClient(nil)); err == nil {
+		t.Error("Expected an error with a nil HTTP client")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"data":{}}`)
+	}
+
+	var loggedReq *http.Request
+	var loggedResp *http.Response
+	var reqBody, respBody []byte
+	logger := func(req *http.Request, resp *http.Response, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		loggedReq, loggedResp = req, resp
+		reqBody, _ = ioutil.ReadAll(req.Body)
+		respBody, _ = ioutil.ReadAll(resp.Body)
+	}
+
+	client, server := getTestContextWithHandler(t, handler, WithLogger(logger))
+	defer server.Close()
+	if err := client.AppEngine.AddDeviceToGroup(testRealmName, "group", testDevices[0], AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if loggedReq == nil || loggedResp == nil {
+		t.Fatal("Logger was not called")
+	}
+	if loggedReq.Header.Get("Authorization") != redactedHeaderValue {
+		t.Error("Authorization header was not redacted", loggedReq.Header.Get("Authorization"))
+	}
+	if loggedResp.StatusCode != http.StatusCreated {
+		t.Error("Unexpected status code", loggedResp.StatusCode)
+	}
+	if len(reqBody) != 0 || len(respBody) != 0 {
+		t.Error("Bodies were logged without WithLoggedBodies")
+	}
+
+	client, server = getTestContextWithHandler(t, handler, WithLogger(logger), WithLoggedBodies(true),
+		WithLoggedAuthorization(true))
+	defer server.Close()
+	if err := client.AppEngine.AddDeviceToGroup(testRealmName, "group", testDevices[0], AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if loggedReq.Header.Get("Authorization") != "Bearer "+testTokenValue {
+		t.Error("Unexpected Authorization header", loggedReq.Header.Get("Authorization"))
+	}
+	if !strings.Contains(string(reqBody), testDevices[0]) {
+		t.Error("Unexpected request body", string(reqBody))
+	}
+	if string(respBody) != `{"data":{}}` {
+		t.Error("Unexpected response body", string(respBody))
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	methods := []string{}
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		astarteAPIMock(w, req)
+	}
+	var loggedReq *http.Request
+	logger := func(req *http.Request, resp *http.Response, err error) {
+		if resp == nil && err == nil {
+			loggedReq = req
+		}
+	}
+
+	client, server := getTestContextWithHandler(t, handler, WithDryRun(true), WithLogger(logger))
+	defer server.Close()
+	if err := client.AppEngine.DeleteDevice(testRealmName, testDevices[0], AstarteDeviceID); err != nil {
+		t.Error(err)
+	}
+	if loggedReq == nil || loggedReq.Method != http.MethodDelete || !strings.HasSuffix(loggedReq.URL.Path, testDevices[0]) {
+		t.Error("Wrong request logged", loggedReq)
+	}
+
+	// Read-only calls are performed as usual, and are not logged as dry-run
+	loggedReq = nil
+	if _, err := client.AppEngine.GetDevice(testRealmName, testDevices[0], AstarteDeviceID); err != nil {
+		t.Error(err)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodGet {
+		t.Error("Unexpected requests sent", methods)
+	}
+	if loggedReq != nil {
+		t.Error("Read-only request logged as dry-run", loggedReq)
+	}
+}
+
+func TestWithServiceURL(t *testing.T) {
+	client, err := NewClient("https://api.astarte.example.com", nil,
+		WithServiceURL(misc.Pairing, "https://pairing.astarte.example.com/api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Pairing.pairingURL.String() != "https://pairing.astarte.example.com/api" {
+		t.Error("Unexpected Pairing URL", client.Pairing.pairingURL)
+	}
+	if client.AppEngine.appEngineURL.String() != "https://api.astarte.example.com/appengine" {
+		t.Error("Unexpected AppEngine URL", client.AppEngine.appEngineURL)
+	}
+
+	client, err = NewClientWithIndividualURLs(map[misc.AstarteService]string{
+		misc.AppEngine: "https://api.astarte.example.com/appengine",
+	}, nil, WithServiceURL(misc.Flow, "https://flow.astarte.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Flow == nil || client.RealmManagement != nil {
+		t.Error("Unexpected services in client")
+	}
+
+	if _, err := NewClient("https://api.astarte.example.com", nil, WithServiceURL(misc.Channels, "https://example.com")); err == nil {
+		t.Error("Expected an error for an unsupported service")
+	}
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{"group"}})
+	}))
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(testTokenValue)
+	if _, err := client.AppEngine.ListGroups(testRealmName); err == nil {
+		t.Fatal("Expected an error with a self-signed certificate")
+	}
+
+	client, err = NewClient(server.URL, nil, WithInsecureSkipVerify(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(testTokenValue)
+	if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig; tlsConfig != nil && tlsConfig.InsecureSkipVerify {
+		t.Error("The default transport was modified")
+	}
+}
+
+func TestWithUserAgent(t *testing.T) {
+	var received string
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		received = req.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusNoContent)
+	}, WithUserAgent("fleet-sync/1.2.0"))
+	defer server.Close()
+
+	if err := client.AppEngine.RemoveDeviceFromGroup(testRealmName, "group", testDevices[0], AstarteDeviceID); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(received, "fleet-sync/1.2.0 "+userAgent) {
+		t.Error("Unexpected User-Agent", received)
+	}
+
+	if _, err := NewClient(server.URL, nil, WithUserAgent("")); err == nil {
+		t.Error("Expected an error with an empty user agent")
+	}
+
+	// Without WithUserAgent, the library still reports its version
+	defaultClient, err := NewClient(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaultClient.UserAgent != libraryUserAgent() {
+		t.Error("Unexpected default User-Agent", defaultClient.UserAgent)
+	}
+}
@@ -15,9 +15,11 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -27,10 +29,59 @@ type ResultSetOrder int
 const (
 	// AscendingOrder means the Paginator will return results starting from the oldest.
 	AscendingOrder ResultSetOrder = iota
-	// DescendingOrder means the Paginator will return results starting from the oldest.
+	// DescendingOrder means the Paginator will return results starting from the newest.
 	DescendingOrder
 )
 
+// DatastreamQueryOptions represents the options of a Datastream query.
+type DatastreamQueryOptions struct {
+	// Since is the beginning of the time window. When zero, the query starts from the oldest value.
+	Since time.Time
+	// To is the end of the time window. When zero, it defaults to the moment the query is created.
+	To time.Time
+	// Order is the order in which values are returned. When nil, it defaults to DescendingOrder, as in Astarte,
+	// so that the latest values come first.
+	Order *ResultSetOrder
+	// PageSize is the maximum number of values returned per page. When <= 0, a default page size is used, or
+	// DownsampleTo when downsampling.
+	PageSize int
+	// DownsampleTo makes Astarte downsample the values in the time window to at most this number of points. When
+	// 0, values are not downsampled. Downsampling is applied to each page, so PageSize should not be smaller than
+	// DownsampleTo.
+	DownsampleTo int
+	// DownsampleKey is the key of object aggregated interfaces whose values drive the downsampling. It is
+	// required when downsampling objects, and ignored otherwise.
+	DownsampleKey string
+	// KeepMilliseconds makes Astarte return timestamps with millisecond precision rather than truncating them.
+	KeepMilliseconds bool
+}
+
+// order returns the order requested by o, or DescendingOrder if none was requested
+func (o DatastreamQueryOptions) order() ResultSetOrder {
+	if o.Order == nil {
+		return DescendingOrder
+	}
+	return *o.Order
+}
+
+// queryParameters returns the query parameters, besides the time window and the limit, requested by o
+func (o DatastreamQueryOptions) queryParameters() (url.Values, error) {
+	query := url.Values{}
+	if o.DownsampleTo < 0 {
+		return nil, fmt.Errorf("DownsampleTo must be positive, got %d", o.DownsampleTo)
+	}
+	if o.DownsampleTo > 0 {
+		query.Set("downsample_to", strconv.Itoa(o.DownsampleTo))
+		if o.DownsampleKey != "" {
+			query.Set("downsample_key", o.DownsampleKey)
+		}
+	}
+	if o.KeepMilliseconds {
+		query.Set("keep_milliseconds", "true")
+	}
+	return query, nil
+}
+
 // DatastreamPaginator handles a paginated set of results. It provides a one-directional iterator to call onto
 // Astarte AppEngine API and handle potentially extremely large sets of results in chunk. You should prefer
 // DatastreamPaginator rather than direct API calls if you expect your result set to be particularly large.
@@ -43,6 +94,8 @@ type DatastreamPaginator struct {
 	client         *Client
 	hasNextPage    bool
 	resultSetOrder ResultSetOrder
+	// extraQuery holds the query parameters set by DatastreamQueryOptions, added to each page request
+	extraQuery url.Values
 }
 
 // Rewind rewinds the simulator to the first page. GetNextPage will then return the first page of the call.
@@ -69,6 +122,11 @@ func (d *DatastreamPaginator) GetResultSetOrder() ResultSetOrder {
 // GetNextPage retrieves the next result page from the paginator. Returns the page as an array of DatastreamValue.
 // If no more results are available, HasNextPage will return false. GetNextPage throws an error if no more pages are available.
 func (d *DatastreamPaginator) GetNextPage() ([]DatastreamValue, error) {
+	return d.GetNextPageWithContext(context.Background())
+}
+
+// GetNextPageWithContext behaves like GetNextPage, but uses ctx for the underlying HTTP request.
+func (d *DatastreamPaginator) GetNextPageWithContext(ctx context.Context) ([]DatastreamValue, error) {
 	if !d.hasNextPage {
 		return nil, errors.New("No more pages available")
 	}
@@ -76,11 +134,15 @@ func (d *DatastreamPaginator) GetNextPage() ([]DatastreamValue, error) {
 	callURL, _ := d.setupCallURL()
 
 	page := []DatastreamValue{}
-	err := d.client.genericJSONDataAPIGET(&page, callURL.String(), 200)
+	err := d.client.genericJSONDataAPIGET(ctx, &page, callURL.String(), 200)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(page) == 0 {
+		d.hasNextPage = false
+		return page, nil
+	}
 	d.computePageState(len(page), page[len(page)-1].Timestamp)
 
 	return page, nil
@@ -90,6 +152,11 @@ func (d *DatastreamPaginator) GetNextPage() ([]DatastreamValue, error) {
 // Returns the page as an array of DatastreamAggregateValue.
 // If no more results are available, HasNextPage will return false. GetNextPage throws an error if no more pages are available.
 func (d *DatastreamPaginator) GetNextAggregatePage() ([]DatastreamAggregateValue, error) {
+	return d.GetNextAggregatePageWithContext(context.Background())
+}
+
+// GetNextAggregatePageWithContext behaves like GetNextAggregatePage, but uses ctx for the underlying HTTP request.
+func (d *DatastreamPaginator) GetNextAggregatePageWithContext(ctx context.Context) ([]DatastreamAggregateValue, error) {
 	if !d.hasNextPage {
 		return nil, errors.New("No more pages available")
 	}
@@ -97,11 +164,15 @@ func (d *DatastreamPaginator) GetNextAggregatePage() ([]DatastreamAggregateValue
 	callURL, _ := d.setupCallURL()
 
 	page := []DatastreamAggregateValue{}
-	err := d.client.genericJSONDataAPIGET(&page, callURL.String(), 200)
+	err := d.client.genericJSONDataAPIGET(ctx, &page, callURL.String(), 200)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(page) == 0 {
+		d.hasNextPage = false
+		return page, nil
+	}
 	d.computePageState(len(page), page[len(page)-1].Timestamp)
 
 	return page, nil
@@ -123,23 +194,26 @@ func (d *DatastreamPaginator) setupCallURL() (*url.URL, error) {
 	}
 	queryString := ""
 	if d.resultSetOrder == AscendingOrder {
-		queryString += fmt.Sprintf("page_size=%v&to=%v", d.pageSize, d.windowEnd.UTC().Format(time.RFC3339Nano))
+		queryString += fmt.Sprintf("limit=%v&to=%v", d.pageSize, formatQueryTime(d.windowEnd))
 		if d.windowStart != invalidTime && d.nextWindow == invalidTime {
-			queryString += fmt.Sprintf("&since=%v", d.windowStart.UTC().Format(time.RFC3339Nano))
+			queryString += fmt.Sprintf("&since=%v", formatQueryTime(d.windowStart))
 		} else if d.nextWindow != invalidTime {
-			queryString += fmt.Sprintf("&since_after=%v", d.nextWindow.UTC().Format(time.RFC3339Nano))
+			queryString += fmt.Sprintf("&since_after=%v", formatQueryTime(d.nextWindow))
 		}
 	} else {
 		queryString += fmt.Sprintf("limit=%v", d.pageSize)
 		if d.windowStart != invalidTime {
-			queryString += fmt.Sprintf("&since=%v", d.windowStart.UTC().Format(time.RFC3339Nano))
+			queryString += fmt.Sprintf("&since=%v", formatQueryTime(d.windowStart))
 		}
 		if d.nextWindow == invalidTime {
-			queryString += fmt.Sprintf("&to=%v", d.windowEnd.UTC().Format(time.RFC3339Nano))
+			queryString += fmt.Sprintf("&to=%v", formatQueryTime(d.windowEnd))
 		} else {
-			queryString += fmt.Sprintf("&to=%v", d.nextWindow.UTC().Format(time.RFC3339Nano))
+			queryString += fmt.Sprintf("&to=%v", formatQueryTime(d.nextWindow))
 		}
 	}
+	if len(d.extraQuery) > 0 {
+		queryString += "&" + d.extraQuery.Encode()
+	}
 	callURL.RawQuery = queryString
 
 	return callURL, nil
@@ -0,0 +1,114 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDatastreamPaginatorEmptyPage(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDatastreamsPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", AscendingOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, err := paginator.GetNextPage(); err != nil || len(page) != 0 {
+		t.Error("Unexpected page", page, err)
+	}
+	if paginator.HasNextPage() {
+		t.Error("Paginator should have no more pages")
+	}
+
+	paginator.Rewind()
+	if page, err := paginator.GetNextAggregatePage(); err != nil || len(page) != 0 {
+		t.Error("Unexpected aggregate page", page, err)
+	}
+	if paginator.HasNextPage() {
+		t.Error("Paginator should have no more pages")
+	}
+}
+
+func TestGetLastDatastreamsLimit(t *testing.T) {
+	// Astarte may return more values than requested, they must be trimmed to exactly limit
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"data":[{"value":3,"timestamp":"2020-03-12T19:30:00.000Z"},`+
+			`{"value":2,"timestamp":"2020-03-12T19:20:00.000Z"},{"value":1,"timestamp":"2020-03-12T19:10:00.000Z"}]}`)
+	})
+	defer server.Close()
+
+	values, err := client.AppEngine.GetLastDatastreams(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[1].Value != float64(2) {
+		t.Error("Wrong values returned", values)
+	}
+}
+
+func TestDatastreamQueryTimePrecision(t *testing.T) {
+	since := time.Date(2020, 3, 12, 19, 0, 0, 123456789, time.UTC)
+	to := since.Add(time.Hour)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("since") != "2020-03-12T19:00:00.123Z" || query.Get("to") != "2020-03-12T20:00:00.123Z" {
+			t.Error("Wrong time window in query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDatastreamsTimeWindowPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", since, to, AscendingOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paginator.GetNextPage(); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.AppEngine.GetAggregateDatastreamsTimeWindow(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Geolocation", "/gps", since, to); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDatastreamPaginatorAscendingPageSize(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("limit") != strconv.Itoa(defaultPageSize) || query.Get("page_size") != "" {
+			t.Error("Wrong page size in query", req.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+	defer server.Close()
+
+	paginator, err := client.AppEngine.GetDatastreamsPaginator(testRealmName, testDevices[0], AstarteDeviceID,
+		"org.astarte-platform.genericsensors.Values", "/sensor/value", AscendingOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paginator.GetNextPage(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// DeviceFilter reports whether a Device should be returned by a DeviceDetailsPaginator.
+type DeviceFilter func(device DeviceDetails) bool
+
+// FilterConnected returns a DeviceFilter matching Devices whose connection status is equal to connected.
+func FilterConnected(connected bool) DeviceFilter {
+	return func(device DeviceDetails) bool {
+		return device.Connected == connected
+	}
+}
+
+// FilterRegisteredAfter returns a DeviceFilter matching Devices which were first registered after t, e.g. to
+// monitor onboarding. Astarte can't filter nor sort Devices by registration time: all the Devices of the Realm
+// are still retrieved, and the filter only spares the caller from handling the older ones.
+func FilterRegisteredAfter(t time.Time) DeviceFilter {
+	return func(device DeviceDetails) bool {
+		return device.FirstRegistration.After(t)
+	}
+}
+
+// FilterFirstCredentialsRequestAfter returns a DeviceFilter matching Devices which requested their first credentials
+// after t, that is Devices which connected to Astarte for the first time after t. Devices which never requested
+// credentials are not matched. As with FilterRegisteredAfter, filtering happens on the client side.
+func FilterFirstCredentialsRequestAfter(t time.Time) DeviceFilter {
+	return func(device DeviceDetails) bool {
+		return device.FirstCredentialsRequest.After(t)
+	}
+}
+
+// DeviceDetailsPaginator is a DeviceListPaginator which always returns pages of DeviceDetails.
+// It spares the caller from type-asserting the page and from issuing a GetDevice call for each
+// Device ID returned by a DeviceIDFormat paginator.
+// Filtering happens on the client side: a page might hence contain less Devices than the page
+// size, or none at all, even when more pages are available.
+type DeviceDetailsPaginator struct {
+	paginator DeviceListPaginator
+	filters   []DeviceFilter
+}
+
+// Rewind rewinds the paginator to the first page. GetNextPage will then return the first page of the call.
+func (d *DeviceDetailsPaginator) Rewind() {
+	d.paginator.Rewind()
+}
+
+// HasNextPage returns whether this paginator can return more pages
+func (d *DeviceDetailsPaginator) HasNextPage() bool {
+	return d.paginator.HasNextPage()
+}
+
+// GetPageSize returns the page size for this paginator
+func (d *DeviceDetailsPaginator) GetPageSize() int {
+	return d.paginator.GetPageSize()
+}
+
+// GetNextPage retrieves the next result page from the paginator. Returns the page as an array of DeviceDetails.
+// If no more results are available, HasNextPage will return false. GetNextPage throws an error if no more pages are available.
+func (d *DeviceDetailsPaginator) GetNextPage() ([]DeviceDetails, error) {
+	return d.GetNextPageWithContext(context.Background())
+}
+
+// GetNextPageWithContext behaves like GetNextPage, but uses ctx for the underlying HTTP request.
+func (d *DeviceDetailsPaginator) GetNextPageWithContext(ctx context.Context) ([]DeviceDetails, error) {
+	page := []DeviceDetails{}
+	if err := d.paginator.GetNextPageWithContext(ctx, &page); err != nil {
+		return nil, err
+	}
+	if len(d.filters) == 0 {
+		return page, nil
+	}
+
+	filteredPage := []DeviceDetails{}
+	for _, device := range page {
+		if d.matches(device) {
+			filteredPage = append(filteredPage, device)
+		}
+	}
+
+	return filteredPage, nil
+}
+
+func (d *DeviceDetailsPaginator) matches(device DeviceDetails) bool {
+	for _, filter := range d.filters {
+		if !filter(device) {
+			return false
+		}
+	}
+	return true
+}
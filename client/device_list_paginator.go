@@ -15,8 +15,12 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
+	"strconv"
 )
 
 // DeviceResultFormat represents the format of the Device returned in the Device list.
@@ -35,6 +39,7 @@ const (
 // Astarte AppEngine API and handle potentially extremely large sets of results in chunk. You should prefer
 // DeviceListPaginator rather than direct API calls if you expect your result set to be particularly large.
 type DeviceListPaginator struct {
+	realm       string
 	baseURL     *url.URL
 	nextQuery   url.Values
 	format      DeviceResultFormat
@@ -43,7 +48,7 @@ type DeviceListPaginator struct {
 	hasNextPage bool
 }
 
-// Rewind rewinds the simulator to the first page. GetNextPage will then return the first page of the call.
+// Rewind rewinds the paginator to the first page. GetNextPage will then return the first page of the call.
 func (d *DeviceListPaginator) Rewind() {
 	d.nextQuery = url.Values{}
 	d.hasNextPage = true
@@ -67,6 +72,11 @@ func (d *DeviceListPaginator) GetPageSize() int {
 // If no more results are available, HasNextPage will return false. GetNextPage
 // throws an error if no more pages are available.
 func (d *DeviceListPaginator) GetNextPage(pagePtr interface{}) error {
+	return d.GetNextPageWithContext(context.Background(), pagePtr)
+}
+
+// GetNextPageWithContext behaves like GetNextPage, but uses ctx for the underlying HTTP request.
+func (d *DeviceListPaginator) GetNextPageWithContext(ctx context.Context, pagePtr interface{}) error {
 	if !d.hasNextPage {
 		return errors.New("No more pages available")
 	}
@@ -78,7 +88,7 @@ func (d *DeviceListPaginator) GetNextPage(pagePtr interface{}) error {
 	callURL, _ := d.setupCallURL()
 
 	links := Links{}
-	err := d.client.genericJSONDataAPIGETWithLinks(pagePtr, &links, callURL.String(), 200)
+	err := d.client.genericJSONDataAPIGETWithLinks(ctx, pagePtr, &links, callURL.String(), 200)
 	if err != nil {
 		return err
 	}
@@ -88,6 +98,66 @@ func (d *DeviceListPaginator) GetNextPage(pagePtr interface{}) error {
 	return nil
 }
 
+// ForEach retrieves all the remaining pages from the paginator, calling fn with the Device ID of each Device.
+// It stops at the first error returned by fn or by the underlying HTTP requests, and returns it. Call Rewind
+// first to iterate over the Devices from the beginning.
+func (d *DeviceListPaginator) ForEach(fn func(deviceID string) error) error {
+	return d.ForEachWithContext(context.Background(), fn)
+}
+
+// ForEachWithContext behaves like ForEach, but uses ctx for all the underlying HTTP requests.
+func (d *DeviceListPaginator) ForEachWithContext(ctx context.Context, fn func(deviceID string) error) error {
+	for d.hasNextPage {
+		deviceIDs := []string{}
+		switch d.format {
+		case DeviceIDFormat:
+			if err := d.GetNextPageWithContext(ctx, &deviceIDs); err != nil {
+				return err
+			}
+		case DeviceDetailsFormat:
+			page := []DeviceDetails{}
+			if err := d.GetNextPageWithContext(ctx, &page); err != nil {
+				return err
+			}
+			for _, details := range page {
+				deviceIDs = append(deviceIDs, details.DeviceID)
+			}
+		default:
+			return fmt.Errorf("invalid device result format %d", d.format)
+		}
+
+		for _, deviceID := range deviceIDs {
+			if err := fn(deviceID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deviceListPaginatorState is the serialized position of a DeviceListPaginator, see State
+type deviceListPaginatorState struct {
+	Realm       string             `json:"realm"`
+	NextQuery   string             `json:"next_query"`
+	PageSize    int                `json:"page_size"`
+	Format      DeviceResultFormat `json:"format"`
+	HasNextPage bool               `json:"has_next_page"`
+}
+
+// State returns the current position of the paginator, including its cursor and page size, as an opaque
+// JSON document. It can be stored e.g. after processing each page, and passed to RestoreDeviceListPaginator
+// to resume iterating from the next page after a restart.
+func (d *DeviceListPaginator) State() ([]byte, error) {
+	return json.Marshal(deviceListPaginatorState{
+		Realm:       d.realm,
+		NextQuery:   d.nextQuery.Encode(),
+		PageSize:    d.pageSize,
+		Format:      d.format,
+		HasNextPage: d.hasNextPage,
+	})
+}
+
 func (d *DeviceListPaginator) checkPageFormat(pagePtr interface{}) error {
 	switch d.format {
 	case DeviceIDFormat:
@@ -101,6 +171,9 @@ func (d *DeviceListPaginator) checkPageFormat(pagePtr interface{}) error {
 		if !ok {
 			return errors.New("pagePtr must be of type *[]DeviceDetails when using DeviceDetailsFormat")
 		}
+
+	default:
+		return fmt.Errorf("invalid device result format %d", d.format)
 	}
 
 	return nil
@@ -123,6 +196,9 @@ func (d *DeviceListPaginator) setupCallURL() (*url.URL, error) {
 	}
 
 	query := d.nextQuery
+	if d.pageSize > 0 {
+		query.Set("limit", strconv.Itoa(d.pageSize))
+	}
 	switch d.format {
 	case DeviceIDFormat:
 		query.Set("details", "false")
@@ -0,0 +1,71 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeviceListPaginatorPageSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if limit := req.URL.Query().Get("limit"); limit != "2" {
+			t.Error("Wrong limit in query", req.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":  []string{testDevices[0], testDevices[1]},
+			"links": map[string]string{"self": req.URL.String()},
+		})
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(testTokenValue)
+
+	paginator, err := client.AppEngine.GetDeviceListPaginator(testRealmName, 2, DeviceIDFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := []string{}
+	if err := paginator.GetNextPage(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 {
+		t.Error("Wrong page returned", page)
+	}
+}
+
+func TestDeviceListPaginatorInvalidFormat(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	if _, err := client.AppEngine.GetDeviceListPaginator(testRealmName, 2, DeviceResultFormat(42)); err == nil {
+		t.Error("Expected an error for an invalid format")
+	}
+	state := []byte(`{"realm":"test","next_query":"","page_size":2,"format":42,"has_next_page":true}`)
+	if _, err := client.AppEngine.RestoreDeviceListPaginator(testRealmName, state); err == nil {
+		t.Error("Expected an error restoring an invalid format")
+	}
+
+	// ForEach must not spin on a paginator whose format is invalid
+	paginator := DeviceListPaginator{client: client, format: DeviceResultFormat(42), hasNextPage: true}
+	if err := paginator.ForEach(func(string) error { return nil }); err == nil {
+		t.Error("Expected an error iterating with an invalid format")
+	}
+}
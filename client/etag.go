@@ -0,0 +1,81 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// maxETagCacheEntries bounds the number of ETags remembered by a Client, so that walking through large paginated
+// collections doesn't make the cache grow indefinitely
+const maxETagCacheEntries = 1024
+
+// conditionalRequestKey is the context key marking the requests which must use the ETag cache, see
+// WithIfNoneMatch.
+type conditionalRequestKey struct{}
+
+func withConditionalRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conditionalRequestKey{}, true)
+}
+
+func isConditionalRequest(req *http.Request) bool {
+	conditional, _ := req.Context().Value(conditionalRequestKey{}).(bool)
+	return conditional
+}
+
+// etagCache remembers the ETags of successful GET responses to conditional requests, keyed by URL, see
+// WithConditionalRequests. Other requests neither use nor update it, so that calls performed without
+// WithIfNoneMatch, including the ones performed internally by the Client, always get the data.
+type etagCache struct {
+	lock  sync.Mutex
+	etags map[string]string
+}
+
+func newETagCache() *etagCache {
+	return &etagCache{etags: map[string]string{}}
+}
+
+// prepare makes req conditional if it is a conditional GET for which an ETag is known, unless the caller set its
+// own If-None-Match header.
+func (e *etagCache) prepare(req *http.Request) {
+	if req.Method != http.MethodGet || !isConditionalRequest(req) || req.Header.Get("If-None-Match") != "" {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if etag, ok := e.etags[req.URL.String()]; ok {
+		req.Header.Set("If-None-Match", etag)
+	}
+}
+
+// store remembers the ETag of resp, the successful response to req.
+func (e *etagCache) store(req *http.Request, resp *http.Response) {
+	etag := resp.Header.Get("ETag")
+	if req.Method != http.MethodGet || !isConditionalRequest(req) || resp.StatusCode != http.StatusOK || etag == "" {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.etags[req.URL.String()]; !ok && len(e.etags) >= maxETagCacheEntries {
+		// Evict an arbitrary entry: at worst, the corresponding data is downloaded again
+		for url := range e.etags {
+			delete(e.etags, url)
+			break
+		}
+	}
+	e.etags[req.URL.String()] = etag
+}
@@ -0,0 +1,172 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func TestWithConditionalRequests(t *testing.T) {
+	etag := `"v1"`
+	ifNoneMatch := []string{}
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, req.Header.Get("If-None-Match"))
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`{"data":["group"]}`))
+	}
+
+	client, server := getTestContextWithHandler(t, handler, WithConditionalRequests())
+	defer server.Close()
+
+	ctx := context.Background()
+	if groups, err := client.AppEngine.ListGroupsWithContext(ctx, testRealmName, WithIfNoneMatch()); err != nil || len(groups) != 1 {
+		t.Fatal("Unexpected reply", groups, err)
+	}
+	if _, err := client.AppEngine.ListGroupsWithContext(ctx, testRealmName, WithIfNoneMatch()); !errors.Is(err, ErrNotModified) {
+		t.Error("Expected ErrNotModified, got", err)
+	}
+	// Calls made without WithIfNoneMatch always get the data
+	if groups, err := client.AppEngine.ListGroups(testRealmName); err != nil || len(groups) != 1 {
+		t.Fatal("Unexpected reply", groups, err)
+	}
+	etag = `"v2"`
+	if groups, err := client.AppEngine.ListGroupsWithContext(ctx, testRealmName, WithIfNoneMatch()); err != nil || len(groups) != 1 {
+		t.Fatal("Unexpected reply", groups, err)
+	}
+	expected := []string{"", `"v1"`, "", `"v1"`}
+	for i := range expected {
+		if ifNoneMatch[i] != expected[i] {
+			t.Errorf("Unexpected If-None-Match in request %d: %s", i, ifNoneMatch[i])
+		}
+	}
+
+	// Without the option, ETags are ignored
+	ifNoneMatch = []string{}
+	client, server = getTestContextWithHandler(t, handler)
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := client.AppEngine.ListGroupsWithContext(ctx, testRealmName, WithIfNoneMatch()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ifNoneMatch[1] != "" {
+		t.Error("Unexpected If-None-Match", ifNoneMatch[1])
+	}
+}
+
+// withETags makes handler reply to GET requests with an ETag, and with 304 Not Modified when it is sent back
+func withETags(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			etag := fmt.Sprintf(`"%s"`, req.URL.String())
+			if req.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		}
+		handler(w, req)
+	}
+}
+
+// TestConditionalRequestsInternalCalls checks that the calls performing requests on their own behalf are not
+// affected by the ETag cache, and can be repeated.
+func TestConditionalRequestsInternalCalls(t *testing.T) {
+	interfaceName := "org.astarte-platform.genericsensors.AvailableSensors"
+	deviceDetailsPath := fmt.Sprintf("/appengine/v1/%s/devices/%s", testRealmName, testDevices[0])
+	interfacePath := fmt.Sprintf("/realmmanagement/v1/%s/interfaces/%s", testRealmName, interfaceName)
+	realmPath := fmt.Sprintf("/housekeeping/v1/realms/%s", testRealmName)
+	realmGets := 0
+	client, server := getTestContextWithHandler(t, withETags(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/version", testRealmName):
+			fmt.Fprint(w, `{"data":"1.1.0"}`)
+		case req.URL.Path == realmPath:
+			// The Realm is deleted after being polled twice
+			realmGets++
+			if realmGets > 2 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"realm_name":"%s"}}`, testRealmName)
+		case req.URL.Path == deviceDetailsPath:
+			fmt.Fprintf(w, `{"data":{"id":"%s","introspection":{"%s":{"major":0,"minor":1}}}}`, testDevices[0], interfaceName)
+		case req.URL.Path == deviceDetailsPath+"/interfaces/"+interfaceName:
+			fmt.Fprint(w, `{"data":{}}`)
+		case strings.HasPrefix(req.URL.Path, interfacePath):
+			fmt.Fprintf(w, `{"data":%s}`, testInterfaces[interfaceName])
+		default:
+			astarteAPIMock(w, req)
+		}
+	}), WithConditionalRequests())
+	defer server.Close()
+
+	localInterface, err := interfaces.ParseInterface([]byte(testInterfaces[interfaceName]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := map[string]func() error{
+		"ListDevices": func() error {
+			_, err := client.AppEngine.ListDevices(testRealmName)
+			return err
+		},
+		"ResolveDevice": func() error {
+			_, _, err := client.AppEngine.ResolveDevice(testRealmName, "sensor-1")
+			return err
+		},
+		"GetDeviceIDFromAlias": func() error {
+			_, err := client.AppEngine.GetDeviceIDFromAlias(testRealmName, "sensor-1")
+			return err
+		},
+		"ExportDeviceData": func() error {
+			return client.AppEngine.ExportDeviceData(testRealmName, testDevices[0], AstarteDeviceID, &bytes.Buffer{})
+		},
+		"SyncInterfaces": func() error {
+			_, err := client.RealmManagement.SyncInterfaces(testRealmName, []interfaces.AstarteInterface{localInterface})
+			return err
+		},
+		"GetAstarteVersion": func() error {
+			_, err := client.GetAstarteVersion(context.Background(), testRealmName)
+			return err
+		},
+		"WaitForRealmDeletion": func() error {
+			realmGets = 0
+			if _, err := client.Housekeeping.GetRealm(testRealmName); err != nil {
+				return err
+			}
+			return client.Housekeeping.WaitForRealmDeletionWithContext(context.Background(), testRealmName, time.Millisecond)
+		},
+	}
+	for name, call := range calls {
+		for i := 0; i < 2; i++ {
+			if err := call(); err != nil {
+				t.Errorf("%s failed on call %d: %v", name, i+1, err)
+			}
+		}
+	}
+}
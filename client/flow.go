@@ -0,0 +1,147 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/astarte-platform/astarte-go/flow"
+)
+
+// FlowService is the API Client for Astarte Flow API
+type FlowService struct {
+	client  *Client
+	flowURL *url.URL
+}
+
+// ListFlows returns the names of all the Flows running in a Realm.
+func (s *FlowService) ListFlows(realm string) ([]string, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/flows", realm))
+
+	flowNames := []string{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &flowNames, callURL.String(), 200)
+
+	return flowNames, err
+}
+
+// GetFlow returns a Flow running in a Realm.
+func (s *FlowService) GetFlow(realm string, name string) (flow.Flow, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/flows/%s", realm, name))
+
+	ret := flow.Flow{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &ret, callURL.String(), 200)
+
+	return ret, err
+}
+
+// CreateFlow starts a new Flow in a Realm. The Flow is validated before being sent to Astarte.
+func (s *FlowService) CreateFlow(realm string, f flow.Flow) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/flows", realm))
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), f, 201)
+}
+
+// DeleteFlow stops and deletes a Flow from a Realm.
+func (s *FlowService) DeleteFlow(realm string, name string) error {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/flows/%s", realm, name))
+	return s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+}
+
+// ListPipelines returns the names of all the Pipelines in a Realm.
+func (s *FlowService) ListPipelines(realm string) ([]string, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/pipelines", realm))
+
+	pipelineNames := []string{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &pipelineNames, callURL.String(), 200)
+
+	return pipelineNames, err
+}
+
+// GetPipeline returns a Pipeline in a Realm.
+func (s *FlowService) GetPipeline(realm string, name string) (flow.Pipeline, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/pipelines/%s", realm, name))
+
+	ret := flow.Pipeline{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &ret, callURL.String(), 200)
+
+	return ret, err
+}
+
+// CreatePipeline creates a new Pipeline in a Realm. The Pipeline is validated before being sent to Astarte.
+func (s *FlowService) CreatePipeline(realm string, pipeline flow.Pipeline) error {
+	if err := pipeline.Validate(); err != nil {
+		return err
+	}
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/pipelines", realm))
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), pipeline, 201)
+}
+
+// DeletePipeline deletes a Pipeline from a Realm.
+func (s *FlowService) DeletePipeline(realm string, name string) error {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/pipelines/%s", realm, name))
+	return s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+}
+
+// ListBlocks returns the names of all the Blocks available in a Realm, including the built-in ones.
+func (s *FlowService) ListBlocks(realm string) ([]string, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/blocks", realm))
+
+	blockNames := []string{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &blockNames, callURL.String(), 200)
+
+	return blockNames, err
+}
+
+// GetBlock returns a Block available in a Realm.
+func (s *FlowService) GetBlock(realm string, name string) (flow.Block, error) {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/blocks/%s", realm, name))
+
+	ret := flow.Block{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &ret, callURL.String(), 200)
+
+	return ret, err
+}
+
+// CreateBlock creates a new custom Block in a Realm. The Block is validated before being sent to Astarte.
+func (s *FlowService) CreateBlock(realm string, block flow.Block) error {
+	if err := block.Validate(); err != nil {
+		return err
+	}
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/blocks", realm))
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), block, 201)
+}
+
+// DeleteBlock deletes a custom Block from a Realm. Built-in Blocks cannot be deleted.
+func (s *FlowService) DeleteBlock(realm string, name string) error {
+	callURL, _ := url.Parse(s.flowURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/blocks/%s", realm, name))
+	return s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+}
@@ -0,0 +1,147 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/flow"
+)
+
+// getFlowTestContext returns a Client backed by an in-memory Flow API, holding a resource map for each
+// collection (e.g. "flows")
+func getFlowTestContext(t *testing.T) (*Client, map[string]map[string]json.RawMessage, func()) {
+	collections := map[string]map[string]json.RawMessage{"flows": {}, "pipelines": {}, "blocks": {}}
+	prefix := fmt.Sprintf("/flow/v1/%s/", testRealmName)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		tokens := strings.Split(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		resources, ok := collections[tokens[0]]
+		if !strings.HasPrefix(req.URL.Path, prefix) || !ok || len(tokens) > 2 {
+			t.Error("Unexpected path", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case len(tokens) == 1 && req.Method == http.MethodGet:
+			names := []string{}
+			for name := range resources {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": names})
+		case len(tokens) == 1 && req.Method == http.MethodPost:
+			var body struct {
+				Data json.RawMessage `json:"data"`
+			}
+			var named struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			_ = json.Unmarshal(body.Data, &named)
+			resources[named.Name] = body.Data
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(body)
+		case resources[tokens[1]] == nil:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Not found"}}`)
+		case req.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": resources[tokens[1]]})
+		case req.Method == http.MethodDelete:
+			delete(resources, tokens[1])
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	return client, collections, server.Close
+}
+
+func TestFlows(t *testing.T) {
+	client, _, closeServer := getFlowTestContext(t)
+	defer closeServer()
+
+	f := flow.Flow{Name: "room-temperatures", Pipeline: "average", Config: map[string]interface{}{"window": 60.0}}
+	if err := client.Flow.CreateFlow(testRealmName, f); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flow.CreateFlow(testRealmName, flow.Flow{Name: "-invalid", Pipeline: "average"}); err == nil {
+		t.Error("Expected an error for an invalid Flow")
+	}
+
+	names, err := client.Flow.ListFlows(testRealmName)
+	if err != nil || !reflect.DeepEqual(names, []string{"room-temperatures"}) {
+		t.Error("Unexpected Flows", names, err)
+	}
+	got, err := client.Flow.GetFlow(testRealmName, f.Name)
+	if err != nil || !reflect.DeepEqual(got, f) {
+		t.Error("Unexpected Flow", got, err)
+	}
+
+	if err := client.Flow.DeleteFlow(testRealmName, f.Name); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.Flow.GetFlow(testRealmName, f.Name); err == nil {
+		t.Error("Expected an error getting a deleted Flow")
+	}
+}
+
+func TestPipelinesAndBlocks(t *testing.T) {
+	client, collections, closeServer := getFlowTestContext(t)
+	defer closeServer()
+
+	pipeline := flow.Pipeline{Name: "average", Source: "room_source | average_block | http_sink.url(${config.url})",
+		Schema: map[string]interface{}{"type": "object"}}
+	if err := client.Flow.CreatePipeline(testRealmName, pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := client.Flow.ListPipelines(testRealmName); err != nil || !reflect.DeepEqual(names, []string{"average"}) {
+		t.Error("Unexpected Pipelines", names, err)
+	}
+	if got, err := client.Flow.GetPipeline(testRealmName, pipeline.Name); err != nil || !reflect.DeepEqual(got, pipeline) {
+		t.Error("Unexpected Pipeline", got, err)
+	}
+
+	block := flow.Block{Name: "average-block", Source: "json_mapper | container", Type: flow.ProducerConsumerBlock}
+	if err := client.Flow.CreateBlock(testRealmName, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flow.CreateBlock(testRealmName, flow.Block{Name: "other", Source: "container"}); err == nil {
+		t.Error("Expected an error for a Block without a type")
+	}
+	if names, err := client.Flow.ListBlocks(testRealmName); err != nil || !reflect.DeepEqual(names, []string{"average-block"}) {
+		t.Error("Unexpected Blocks", names, err)
+	}
+	if got, err := client.Flow.GetBlock(testRealmName, block.Name); err != nil || !reflect.DeepEqual(got, block) {
+		t.Error("Unexpected Block", got, err)
+	}
+
+	if err := client.Flow.DeletePipeline(testRealmName, pipeline.Name); err != nil {
+		t.Error(err)
+	}
+	if err := client.Flow.DeleteBlock(testRealmName, block.Name); err != nil {
+		t.Error(err)
+	}
+	if len(collections["pipelines"]) != 0 || len(collections["blocks"]) != 0 {
+		t.Error("Resources were not deleted", collections)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/astarte-platform/astarte-go/misc"
+)
+
+// HealthReport holds the outcome of the health check of each Astarte service checked by HealthCheck: a nil error
+// means the service is healthy.
+type HealthReport map[misc.AstarteService]error
+
+// Healthy returns whether all the services in the report are healthy.
+func (r HealthReport) Healthy() bool {
+	return r.Err() == nil
+}
+
+// Err returns an error listing the unhealthy services in the report, or nil if all of them are healthy.
+func (r HealthReport) Err() error {
+	failures := []string{}
+	for service, err := range r {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", service, err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	return fmt.Errorf("unhealthy Astarte services: %s", strings.Join(failures, "; "))
+}
+
+// HousekeepingHealth returns nil if the Housekeeping API is healthy, that is if its health endpoint replies with 200.
+func (c *Client) HousekeepingHealth(ctx context.Context) error {
+	if c.Housekeeping == nil {
+		return errServiceNotAvailable(misc.Housekeeping)
+	}
+	return c.checkHealth(ctx, c.Housekeeping.housekeepingURL)
+}
+
+// AppEngineHealth returns nil if the AppEngine API is healthy, that is if its health endpoint replies with 200.
+func (c *Client) AppEngineHealth(ctx context.Context) error {
+	if c.AppEngine == nil {
+		return errServiceNotAvailable(misc.AppEngine)
+	}
+	return c.checkHealth(ctx, c.AppEngine.appEngineURL)
+}
+
+// RealmManagementHealth returns nil if the Realm Management API is healthy, that is if its health endpoint
+// replies with 200.
+func (c *Client) RealmManagementHealth(ctx context.Context) error {
+	if c.RealmManagement == nil {
+		return errServiceNotAvailable(misc.RealmManagement)
+	}
+	return c.checkHealth(ctx, c.RealmManagement.realmManagementURL)
+}
+
+// PairingHealth returns nil if the Pairing API is healthy, that is if its health endpoint replies with 200.
+func (c *Client) PairingHealth(ctx context.Context) error {
+	if c.Pairing == nil {
+		return errServiceNotAvailable(misc.Pairing)
+	}
+	return c.checkHealth(ctx, c.Pairing.pairingURL)
+}
+
+// HealthCheck checks concurrently the health of Housekeeping, AppEngine, Realm Management and Pairing, skipping
+// the ones which are not available in the Client (see NewClientWithIndividualURLs). It is meant to back readiness
+// probes: use Healthy or Err on the returned report to get the overall status.
+func (c *Client) HealthCheck(ctx context.Context) HealthReport {
+	checks := map[misc.AstarteService]func(context.Context) error{}
+	if c.Housekeeping != nil {
+		checks[misc.Housekeeping] = c.HousekeepingHealth
+	}
+	if c.AppEngine != nil {
+		checks[misc.AppEngine] = c.AppEngineHealth
+	}
+	if c.RealmManagement != nil {
+		checks[misc.RealmManagement] = c.RealmManagementHealth
+	}
+	if c.Pairing != nil {
+		checks[misc.Pairing] = c.PairingHealth
+	}
+
+	report := HealthReport{}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for service, check := range checks {
+		wg.Add(1)
+		go func(service misc.AstarteService, check func(context.Context) error) {
+			defer wg.Done()
+			err := check(ctx)
+			lock.Lock()
+			report[service] = err
+			lock.Unlock()
+		}(service, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkHealth queries the health endpoint of the service exposed at serviceURL. Health endpoints are not
+// versioned, and need no authentication: the headers set with WithRequestHeaders are sent anyway, as a gateway
+// in front of Astarte might require them.
+func (c *Client) checkHealth(ctx context.Context, serviceURL *url.URL) error {
+	callURL, _ := url.Parse(serviceURL.String())
+	callURL.Path = path.Join(callURL.Path, "/health")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, callURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	c.addRequestHeaders(req)
+
+	resp, err := c.doHTTPRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorFromJSONErrors(resp)
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+func errServiceNotAvailable(service misc.AstarteService) error {
+	return fmt.Errorf("%s is not available in the client", service)
+}
@@ -0,0 +1,91 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/misc"
+)
+
+func TestHealthCheck(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "" {
+			t.Error("Health checks must not be authenticated")
+		}
+		switch req.URL.Path {
+		case "/appengine/health", "/housekeeping/health", "/realmmanagement/health":
+			w.WriteHeader(http.StatusOK)
+		case "/pairing/health":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			t.Error("Unexpected request", req.URL.Path)
+		}
+	})
+	defer server.Close()
+
+	if err := client.AppEngineHealth(context.Background()); err != nil {
+		t.Error(err)
+	}
+	var apiError *AstarteAPIError
+	if err := client.PairingHealth(context.Background()); !errors.As(err, &apiError) || apiError.StatusCode != http.StatusServiceUnavailable {
+		t.Error("Expected a 503 error, got", err)
+	}
+
+	report := client.HealthCheck(context.Background())
+	if len(report) != 4 || report.Healthy() {
+		t.Error("Unexpected health report", report)
+	}
+	for service, err := range report {
+		if (service == misc.Pairing) != (err != nil) {
+			t.Errorf("Unexpected health of %s: %v", service, err)
+		}
+	}
+	if err := report.Err(); err == nil {
+		t.Error("Expected an error from an unhealthy report")
+	}
+
+	client, err := NewClientWithIndividualURLs(map[misc.AstarteService]string{misc.AppEngine: server.URL + "/appengine"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := client.HealthCheck(context.Background()); len(report) != 1 || !report.Healthy() {
+		t.Error("Unexpected health report", report)
+	}
+	if err := client.PairingHealth(context.Background()); err == nil {
+		t.Error("Expected an error for an unavailable service")
+	}
+}
+
+func TestHealthCheckRequestHeaders(t *testing.T) {
+	headers := http.Header{"X-Api-Key": {"secret"}, "Authorization": {"Bearer gateway"}}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Api-Key") != "secret" {
+			t.Error("Missing custom header in", req.URL.Path)
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Error("Health checks must not be authenticated")
+		}
+		w.WriteHeader(http.StatusOK)
+	}, WithRequestHeaders(headers))
+	defer server.Close()
+
+	if err := client.AppEngineHealth(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
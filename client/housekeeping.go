@@ -15,12 +15,18 @@
 package client
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
+	"time"
 )
 
+// defaultRealmDeletionPollInterval is how often WaitForRealmDeletion checks whether the Realm has been deleted
+const defaultRealmDeletionPollInterval = time.Second
+
 // HousekeepingService is the API Client for Housekeeping API
 type HousekeepingService struct {
 	client          *Client
@@ -32,19 +38,24 @@ func (s *HousekeepingService) ListRealms() ([]string, error) {
 	callURL, _ := url.Parse(s.housekeepingURL.String())
 	callURL.Path = path.Join(callURL.Path, "/v1/realms")
 	realmsList := []string{}
-	err := s.client.genericJSONDataAPIGET(&realmsList, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(context.Background(), &realmsList, callURL.String(), 200)
 
 	return realmsList, err
 }
 
-// GetRealm returns data about a single Realm.
+// GetRealm returns data about a single Realm, such as its public key and its replication settings.
+// If the Realm does not exist, the returned error wraps ErrRealmNotFound.
 func (s *HousekeepingService) GetRealm(realm string) (RealmDetails, error) {
+	return s.getRealm(context.Background(), realm)
+}
+
+func (s *HousekeepingService) getRealm(ctx context.Context, realm string) (RealmDetails, error) {
 	callURL, _ := url.Parse(s.housekeepingURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/realms/%s", realm))
 	realmDetails := RealmDetails{}
-	err := s.client.genericJSONDataAPIGET(&realmDetails, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(ctx, &realmDetails, callURL.String(), 200)
 
-	return realmDetails, err
+	return realmDetails, withErrorCause(err, http.StatusNotFound, ErrRealmNotFound)
 }
 
 // CreateRealm creates a new Realm in the Cluster with default parameters.
@@ -63,12 +74,82 @@ func (s *HousekeepingService) CreateRealmWithReplicationFactor(realm string, pub
 }
 
 // CreateRealmWithDatacenterReplication creates a new Realm in the Cluster with a custom,
-// per-datacenter Replication Factor. Both replicationClass and datacenterReplicationFactors must be provided.
+// per-datacenter Replication Factor. datacenterReplicationFactors must contain at least one datacenter,
+// and all of its Replication Factors must be > 0.
 func (s *HousekeepingService) CreateRealmWithDatacenterReplication(realm string, publicKeyString string,
 	datacenterReplicationFactors map[string]int) error {
+	if len(datacenterReplicationFactors) == 0 {
+		return errors.New("At least one datacenter replication factor should be provided")
+	}
+	for datacenter, replicationFactor := range datacenterReplicationFactors {
+		if replicationFactor <= 0 {
+			return fmt.Errorf("Replication factor for datacenter %s should be > 0", datacenter)
+		}
+	}
 	return s.createRealmInternal(realm, publicKeyString, 0, datacenterReplicationFactors)
 }
 
+// CreateRealmWithDetails creates a new Realm in the Cluster as described by details. The replication settings
+// are chosen according to details.ReplicationClass: NetworkTopologyStrategy requires
+// DatacenterReplicationFactors, whereas SimpleStrategy uses ReplicationFactor, or the default Replication
+// Factor when it is 0.
+func (s *HousekeepingService) CreateRealmWithDetails(details RealmDetails) error {
+	switch {
+	case details.ReplicationClass == NetworkTopologyStrategy:
+		return s.CreateRealmWithDatacenterReplication(details.Name, details.JwtPublicKeyPEM,
+			details.DatacenterReplicationFactors)
+	case details.ReplicationFactor != 0:
+		return s.CreateRealmWithReplicationFactor(details.Name, details.JwtPublicKeyPEM, details.ReplicationFactor)
+	default:
+		return s.CreateRealm(details.Name, details.JwtPublicKeyPEM)
+	}
+}
+
+// DeleteRealm requests the deletion of a Realm and all of its data. Realm deletion must be enabled in
+// Housekeeping, and it is performed asynchronously: use WaitForRealmDeletion to wait for it to complete.
+// If the Realm does not exist, the returned error wraps ErrRealmNotFound.
+func (s *HousekeepingService) DeleteRealm(realm string) error {
+	callURL, _ := url.Parse(s.housekeepingURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/realms/%s", realm))
+	err := s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+
+	return withErrorCause(err, http.StatusNotFound, ErrRealmNotFound)
+}
+
+// WaitForRealmDeletion polls Housekeeping every second until the Realm does not exist anymore, and returns an
+// error if this doesn't happen within timeout.
+func (s *HousekeepingService) WaitForRealmDeletion(realm string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.WaitForRealmDeletionWithContext(ctx, realm, defaultRealmDeletionPollInterval)
+}
+
+// WaitForRealmDeletionWithContext polls Housekeeping every pollInterval until the Realm does not exist anymore.
+// If ctx is done first, the returned error wraps its error, e.g. context.DeadlineExceeded.
+func (s *HousekeepingService) WaitForRealmDeletionWithContext(ctx context.Context, realm string, pollInterval time.Duration,
+	opts ...RequestOption) error {
+	if pollInterval <= 0 {
+		return errors.New("pollInterval must be > 0")
+	}
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
+	for {
+		_, err := s.getRealm(ctx, realm)
+		switch {
+		case errors.Is(err, ErrRealmNotFound):
+			return nil
+		case ctx.Err() != nil:
+			return fmt.Errorf("Realm %s was not deleted: %w", realm, ctx.Err())
+		case err != nil:
+			return err
+		}
+		if err := sleepWithContext(ctx, pollInterval); err != nil {
+			return fmt.Errorf("Realm %s was not deleted: %w", realm, err)
+		}
+	}
+}
+
 func (s *HousekeepingService) createRealmInternal(realm string, publicKeyString string, replicationFactor int,
 	datacenterReplicationFactors map[string]int) error {
 	callURL, _ := url.Parse(s.housekeepingURL.String())
@@ -87,5 +168,5 @@ func (s *HousekeepingService) createRealmInternal(realm string, publicKeyString
 		requestBody["datacenter_replication_factors"] = datacenterReplicationFactors
 	}
 
-	return s.client.genericJSONDataAPIPost(callURL.String(), requestBody, 201)
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), requestBody, 201)
 }
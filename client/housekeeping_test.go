@@ -0,0 +1,212 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPublicKey = "-----BEGIN PUBLIC KEY-----\ntest\n-----END PUBLIC KEY-----\n"
+
+func getHousekeepingTestContext(t *testing.T) (*Client, *map[string]interface{}, func()) {
+	lastCreatedRealm := map[string]interface{}{}
+	realms := map[string]RealmDetails{
+		testRealmName: {
+			Name:              testRealmName,
+			JwtPublicKeyPEM:   testPublicKey,
+			ReplicationClass:  SimpleStrategy,
+			ReplicationFactor: 1,
+		},
+	}
+	pendingDeletions := map[string]int{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/housekeeping/v1/realms" && req.Method == http.MethodGet:
+			names := []string{}
+			for name := range realms {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": names})
+		case req.URL.Path == "/housekeeping/v1/realms" && req.Method == http.MethodPost:
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			lastCreatedRealm = body.Data
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(body)
+		case strings.HasPrefix(req.URL.Path, "/housekeeping/v1/realms/") && req.Method == http.MethodGet:
+			name := strings.TrimPrefix(req.URL.Path, "/housekeeping/v1/realms/")
+			if remaining, ok := pendingDeletions[name]; ok {
+				if remaining == 0 {
+					delete(realms, name)
+				}
+				pendingDeletions[name] = remaining - 1
+			}
+			realm, ok := realms[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":{"detail":"Realm not found"}}`)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": realm})
+		case strings.HasPrefix(req.URL.Path, "/housekeeping/v1/realms/") && req.Method == http.MethodDelete:
+			name := strings.TrimPrefix(req.URL.Path, "/housekeeping/v1/realms/")
+			if _, ok := realms[name]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":{"detail":"Realm not found"}}`)
+				return
+			}
+			// Deletion is asynchronous: the realm disappears only after a few more requests
+			pendingDeletions[name] = 2
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return client, &lastCreatedRealm, server.Close
+}
+
+func TestCreateRealm(t *testing.T) {
+	client, lastCreatedRealm, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	if err := client.Housekeeping.CreateRealmWithReplicationFactor(testRealmName, testPublicKey, 3); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"realm_name":         testRealmName,
+		"jwt_public_key_pem": testPublicKey,
+		"replication_class":  "SimpleStrategy",
+		"replication_factor": float64(3),
+	}
+	if !reflect.DeepEqual(*lastCreatedRealm, expected) {
+		t.Error("Unexpected request", *lastCreatedRealm)
+	}
+
+	if err := client.Housekeeping.CreateRealmWithReplicationFactor(testRealmName, testPublicKey, 0); err == nil {
+		t.Error("A replication factor of 0 should be rejected")
+	}
+}
+
+func TestCreateRealmWithDetails(t *testing.T) {
+	client, lastCreatedRealm, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	err := client.Housekeeping.CreateRealmWithDetails(RealmDetails{
+		Name:                         testRealmName,
+		JwtPublicKeyPEM:              testPublicKey,
+		ReplicationClass:             NetworkTopologyStrategy,
+		DatacenterReplicationFactors: map[string]int{"dc1": 3, "dc2": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"realm_name":                     testRealmName,
+		"jwt_public_key_pem":             testPublicKey,
+		"replication_class":              "NetworkTopologyStrategy",
+		"datacenter_replication_factors": map[string]interface{}{"dc1": float64(3), "dc2": float64(1)},
+	}
+	if !reflect.DeepEqual(*lastCreatedRealm, expected) {
+		t.Error("Unexpected request", *lastCreatedRealm)
+	}
+
+	err = client.Housekeeping.CreateRealmWithDetails(RealmDetails{
+		Name:             testRealmName,
+		JwtPublicKeyPEM:  testPublicKey,
+		ReplicationClass: NetworkTopologyStrategy,
+	})
+	if err == nil {
+		t.Error("NetworkTopologyStrategy without datacenters should be rejected")
+	}
+}
+
+func TestListRealms(t *testing.T) {
+	client, _, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	realms, err := client.Housekeeping.ListRealms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(realms, []string{testRealmName}) {
+		t.Error("Unexpected realms", realms)
+	}
+}
+
+func TestGetRealm(t *testing.T) {
+	client, _, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	realm, err := client.Housekeeping.GetRealm(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if realm.JwtPublicKeyPEM != testPublicKey || realm.ReplicationClass != SimpleStrategy || realm.ReplicationFactor != 1 {
+		t.Error("Unexpected realm details", realm)
+	}
+
+	if _, err := client.Housekeeping.GetRealm("missing"); !errors.Is(err, ErrRealmNotFound) {
+		t.Error("Expected ErrRealmNotFound, got", err)
+	}
+}
+
+func TestDeleteRealm(t *testing.T) {
+	client, _, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	if err := client.Housekeeping.DeleteRealm("missing"); !errors.Is(err, ErrRealmNotFound) {
+		t.Error("Expected ErrRealmNotFound, got", err)
+	}
+
+	if err := client.Housekeeping.DeleteRealm(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Housekeeping.WaitForRealmDeletionWithContext(context.Background(), testRealmName, time.Millisecond); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWaitForRealmDeletionTimeout(t *testing.T) {
+	client, _, closeServer := getHousekeepingTestContext(t)
+	defer closeServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := client.Housekeeping.WaitForRealmDeletionWithContext(ctx, testRealmName, time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected a timeout, as the realm was never deleted, got", err)
+	}
+
+	if err := client.Housekeeping.WaitForRealmDeletion(testRealmName, 10*time.Millisecond); err == nil {
+		t.Error("Expected a timeout, as the realm was never deleted")
+	}
+	if err := client.Housekeeping.WaitForRealmDeletionWithContext(context.Background(), testRealmName, 0); err == nil {
+		t.Error("Expected an error for an invalid poll interval")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+)
+
+const redactedHeaderValue = "[REDACTED]"
+
+// RequestLogger is called by the Client after each HTTP request it performs, retries included. resp is nil
+// when err is not. req and resp are copies: their bodies are empty unless WithLoggedBodies is given, and
+// the Authorization header of req is redacted unless WithLoggedAuthorization is given. RequestLogger must
+// not retain them after returning. When WithDryRun is enabled, requests which are not sent are passed with
+// both resp and err nil.
+type RequestLogger func(req *http.Request, resp *http.Response, err error)
+
+// requestLogging holds the logging configuration of a Client. The zero value disables logging.
+type requestLogging struct {
+	logger           RequestLogger
+	logBodies        bool
+	logAuthorization bool
+}
+
+// doLoggedHTTPRequest performs req, and passes it along with its outcome to the logger of the Client, if any.
+func (c *Client) doLoggedHTTPRequest(req *http.Request) (*http.Response, error) {
+	if c.requestLogging.logger == nil {
+		return c.httpClient.Do(req)
+	}
+
+	loggedReq := c.loggableRequest(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.requestLogging.logger(loggedReq, nil, err)
+		return resp, err
+	}
+
+	loggedResp := *resp
+	loggedResp.Body = http.NoBody
+	if c.requestLogging.logBodies {
+		body, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			c.requestLogging.logger(loggedReq, nil, readErr)
+			return nil, readErr
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		loggedResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	c.requestLogging.logger(loggedReq, &loggedResp, nil)
+
+	return resp, nil
+}
+
+// logDryRunRequest passes req, which is not going to be sent, to the logger of the Client, if any.
+func (c *Client) logDryRunRequest(req *http.Request) {
+	if c.requestLogging.logger != nil {
+		c.requestLogging.logger(c.loggableRequest(req), nil, nil)
+	}
+}
+
+// loggableRequest returns a copy of req to be passed to the logger, honoring WithLoggedBodies and
+// WithLoggedAuthorization.
+func (c *Client) loggableRequest(req *http.Request) *http.Request {
+	loggedReq := req.Clone(req.Context())
+	loggedReq.Body = http.NoBody
+	if c.requestLogging.logBodies && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			loggedReq.Body = body
+		}
+	}
+	if !c.requestLogging.logAuthorization && loggedReq.Header.Get("Authorization") != "" {
+		loggedReq.Header.Set("Authorization", redactedHeaderValue)
+	}
+	return loggedReq
+}
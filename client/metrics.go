@@ -0,0 +1,44 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/misc"
+)
+
+// Collector receives metrics about the API calls performed by a Client, see WithMetricsCollector. It is
+// meant to be backed by a metrics library: for instance, a Prometheus implementation would increment a
+// CounterVec labeled by service, method and status, and observe duration in a HistogramVec labeled by
+// service and method. Implementations must be safe for concurrent use.
+type Collector interface {
+	// ObserveRequest is called once per API call, after it completes. method is the HTTP method, and
+	// statusCode is the status of the last response received, or 0 if no response was received at all.
+	// duration includes retries, if any.
+	ObserveRequest(service misc.AstarteService, method string, statusCode int, duration time.Duration)
+}
+
+func (c *Client) observeRequest(service misc.AstarteService, req *http.Request, resp *http.Response, duration time.Duration) {
+	if c.metricsCollector == nil {
+		return
+	}
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	c.metricsCollector.ObserveRequest(service, req.Method, statusCode, duration)
+}
@@ -0,0 +1,75 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/misc"
+)
+
+type observedRequest struct {
+	service    misc.AstarteService
+	method     string
+	statusCode int
+	duration   time.Duration
+}
+
+type recordingCollector struct {
+	lock     sync.Mutex
+	observed []observedRequest
+}
+
+func (c *recordingCollector) ObserveRequest(service misc.AstarteService, method string, statusCode int, duration time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.observed = append(c.observed, observedRequest{service, method, statusCode, duration})
+}
+
+func TestWithMetricsCollector(t *testing.T) {
+	collector := &recordingCollector{}
+	client, server, calls := getFlakyTestContext(t, 1, WithMetricsCollector(collector),
+		WithRetryPolicy(2, time.Millisecond))
+	defer server.Close()
+
+	if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", *calls)
+	}
+	// Retries are part of the same API call
+	if len(collector.observed) != 1 {
+		t.Fatalf("Expected 1 observed request, got %d", len(collector.observed))
+	}
+	observed := collector.observed[0]
+	if observed.service != misc.AppEngine || observed.method != http.MethodGet || observed.statusCode != http.StatusOK {
+		t.Errorf("Unexpected observed request %+v", observed)
+	}
+	if observed.duration <= 0 {
+		t.Error("Unexpected duration", observed.duration)
+	}
+
+	server.Close()
+	if _, err := client.AppEngine.ListGroups(testRealmName); err == nil {
+		t.Fatal("Expected an error with a closed server")
+	}
+	if len(collector.observed) != 2 || collector.observed[1].statusCode != 0 {
+		t.Errorf("Unexpected observed requests %+v", collector.observed)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/misc"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// ClientOption customizes the behavior of a Client. Options can be passed to NewClient and
+// NewClientWithIndividualURLs, and are applied in order after the Client has been created.
+type ClientOption func(c *Client) error
+
+func (c *Client) applyOptions(options []ClientOption) error {
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithHTTPClient makes the Client use httpClient for all requests, e.g. to configure mTLS, custom CA pools,
+// proxies or timeouts. It takes precedence over the httpClient passed to NewClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) error {
+		if httpClient == nil {
+			return errors.New("httpClient must not be nil")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify controls whether the Client verifies the TLS certificate chain and host name of Astarte.
+//
+// WARNING: disabling verification makes the Client vulnerable to man-in-the-middle attacks, and leaks its
+// credentials to anyone able to intercept its traffic. Use it only against local or development clusters
+// with self-signed certificates, and never in production.
+//
+// The option applies to the transport of the HTTP client in use when it is given, which is copied rather
+// than modified: pass it after WithHTTPClient, if any. An error is returned if that client has a custom
+// RoundTripper which is not an *http.Transport.
+func WithInsecureSkipVerify(insecureSkipVerify bool) ClientOption {
+	return func(c *Client) error {
+		var transport *http.Transport
+		switch t := c.httpClient.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return errors.New("cannot configure TLS verification of a custom RoundTripper")
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = insecureSkipVerify
+
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+		return nil
+	}
+}
+
+// WithServiceURL makes the Client reach service at rawURL, overriding the URL derived by NewClient from the
+// base URL, e.g. when a single service is exposed on a different host. With NewClientWithIndividualURLs, it
+// can be used to add a service to the Client.
+func WithServiceURL(service misc.AstarteService, rawURL string) ClientOption {
+	return func(c *Client) error {
+		serviceURL, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		if !c.setServiceURL(service, serviceURL) {
+			return errors.New("the client has no API Service for the given Astarte service")
+		}
+		return nil
+	}
+}
+
+// WithRetryPolicy makes the Client retry idempotent (GET) requests up to maxRetries times when they fail
+// due to a connection error or to a 429, 502, 503 or 504 status code. Retries are spaced with an exponential
+// backoff starting from baseDelay, with some random jitter, unless the response carries a Retry-After header.
+// Neither the backoff nor Retry-After make a retry wait longer than a minute. The request context is honored
+// while waiting. PATCH requests are retried only if WithPatchRetries is also given.
+func WithRetryPolicy(maxRetries int, baseDelay time.Duration) ClientOption {
+	return func(c *Client) error {
+		if maxRetries < 0 {
+			return errors.New("maxRetries must be >= 0")
+		}
+		if baseDelay <= 0 {
+			return errors.New("baseDelay must be > 0")
+		}
+		c.retryPolicy.maxRetries = maxRetries
+		c.retryPolicy.baseDelay = baseDelay
+		return nil
+	}
+}
+
+// WithRateLimit throttles the requests performed by the Client to rps requests per second on average, with bursts
+// of up to burst requests. Requests wait for their turn, honoring their context. Retries are throttled as well.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) error {
+		if rps <= 0 {
+			return errors.New("rps must be > 0")
+		}
+		if burst <= 0 {
+			return errors.New("burst must be > 0")
+		}
+		c.rateLimiter = rate.NewLimiter(rate.Limit(rps), burst)
+		return nil
+	}
+}
+
+// WithPatchRetries controls whether PATCH requests (e.g. alias and metadata writes) are retried according
+// to the retry policy set with WithRetryPolicy. Enable it only if you know your writes are idempotent.
+func WithPatchRetries(enabled bool) ClientOption {
+	return func(c *Client) error {
+		c.retryPolicy.retryPatch = enabled
+		return nil
+	}
+}
+
+// WithTokenProvider makes the Client call provider to obtain the token before each request, rather than
+// using the token set with SetToken. When provider returns a JWT with an expiry, the token is cached and
+// provider is called again only shortly before the token expires, or if Astarte rejects it.
+func WithTokenProvider(provider TokenProvider) ClientOption {
+	return func(c *Client) error {
+		if provider == nil {
+			return errors.New("provider must not be nil")
+		}
+		if c.realmTokenProvider != nil {
+			return errors.New("WithTokenProvider cannot be combined with WithRealmTokenProvider")
+		}
+		c.tokenProvider = &cachingTokenProvider{provider: provider}
+		return nil
+	}
+}
+
+// WithRealmTokenProvider makes the Client call provider to obtain the token for the Realm targeted by each
+// request, so that a single Client can serve many Realms. Tokens are cached per Realm as with
+// WithTokenProvider, and provider is called again only shortly before a token expires, or if Astarte rejects it.
+// It cannot be combined with WithTokenProvider.
+func WithRealmTokenProvider(provider RealmTokenProvider) ClientOption {
+	return func(c *Client) error {
+		if provider == nil {
+			return errors.New("provider must not be nil")
+		}
+		if c.tokenProvider != nil {
+			return errors.New("WithRealmTokenProvider cannot be combined with WithTokenProvider")
+		}
+		c.realmTokenProvider = &realmTokenProvider{provider: provider}
+		return nil
+	}
+}
+
+// WithDefaultPageSize sets the page size used by the methods listing all the Devices of a Realm or a group,
+// such as ListDevices. Smaller pages suit slow links, larger ones speed up bulk exports. pageSize must be > 0,
+// and it is capped to the maximum page size supported by Astarte.
+func WithDefaultPageSize(pageSize int) ClientOption {
+	return func(c *Client) error {
+		if pageSize <= 0 {
+			return errors.New("pageSize must be > 0")
+		}
+		if pageSize > maxPageSize {
+			pageSize = maxPageSize
+		}
+		c.devicesPageSize = pageSize
+		return nil
+	}
+}
+
+// WithRequestHeaders makes the Client add headers to every request, e.g. to pass an API key to a gateway in
+// front of Astarte. Headers set by the Client itself, such as Content-Type, take precedence, and Authorization
+// is always set from the Client token.
+func WithRequestHeaders(headers http.Header) ClientOption {
+	return func(c *Client) error {
+		c.requestHeaders = http.Header{}
+		for key, values := range headers {
+			if http.CanonicalHeaderKey(key) == "Authorization" {
+				continue
+			}
+			c.requestHeaders[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		return nil
+	}
+}
+
+// WithLogger makes the Client call logger after each HTTP request it performs, e.g. to debug its interactions
+// with Astarte. By default, bodies are not passed to logger and the Authorization header is redacted: use
+// WithLoggedBodies and WithLoggedAuthorization to change that.
+func WithLogger(logger RequestLogger) ClientOption {
+	return func(c *Client) error {
+		if logger == nil {
+			return errors.New("logger must not be nil")
+		}
+		c.requestLogging.logger = logger
+		return nil
+	}
+}
+
+// WithLoggedBodies controls whether the bodies of requests and responses are passed to the logger set with
+// WithLogger. Enabling it makes the Client buffer whole responses in memory.
+func WithLoggedBodies(enabled bool) ClientOption {
+	return func(c *Client) error {
+		c.requestLogging.logBodies = enabled
+		return nil
+	}
+}
+
+// WithLoggedAuthorization controls whether the Authorization header is passed as is to the logger set with
+// WithLogger, rather than redacted. Enable it with care, as logs would then contain valid credentials.
+func WithLoggedAuthorization(enabled bool) ClientOption {
+	return func(c *Client) error {
+		c.requestLogging.logAuthorization = enabled
+		return nil
+	}
+}
+
+// WithTracerProvider makes the Client trace each API call with a span created by a Tracer of provider, tagged
+// with the HTTP method, the Astarte service, the Realm and the response status. Spans are children of the span
+// in the context passed to the WithContext methods, if any. By default, API calls are not traced.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *Client) error {
+		if provider == nil {
+			return errors.New("tracer provider must not be nil")
+		}
+		c.tracer = provider.Tracer(tracerName)
+		return nil
+	}
+}
+
+// WithMetricsCollector makes the Client report each API call to collector, e.g. to count requests by service,
+// method and status, and to track their duration.
+func WithMetricsCollector(collector Collector) ClientOption {
+	return func(c *Client) error {
+		if collector == nil {
+			return errors.New("metrics collector must not be nil")
+		}
+		c.metricsCollector = collector
+		return nil
+	}
+}
+
+// WithUserAgent makes the Client identify itself as userAgent, e.g. "fleet-sync/1.2.0", so that operators can tell
+// which tool performed a request. The name and version of this library are appended to it.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) error {
+		if userAgent == "" {
+			return errors.New("user agent must not be empty")
+		}
+		c.UserAgent = userAgent + " " + libraryUserAgent()
+		return nil
+	}
+}
+
+// WithConditionalRequests makes the Client remember the ETag of GET responses to the calls made with
+// WithIfNoneMatch, and send it back in If-None-Match when repeating the same call. If the data did not change,
+// Astarte replies with 304 Not Modified and the call returns ErrNotModified without downloading it again, so that
+// callers polling Astarte can reuse the data they already have. Calls made without WithIfNoneMatch are not
+// affected.
+func WithConditionalRequests() ClientOption {
+	return func(c *Client) error {
+		c.etags = newETagCache()
+		return nil
+	}
+}
+
+// WithDryRun makes the Client skip all the calls which would modify the state of Astarte, such as
+// InstallInterface, UpdateInterface, DeleteDevice or AddDeviceAlias: their request is passed to the logger set
+// with WithLogger, if any, and they return success without contacting Astarte. Read-only calls are performed
+// as usual. Calls returning data from a modification, such as RegisterDevice, return empty values.
+func WithDryRun(enabled bool) ClientOption {
+	return func(c *Client) error {
+		c.dryRun = enabled
+		return nil
+	}
+}
@@ -15,9 +15,14 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
 )
 
 // PairingService is the API Client for Pairing API
@@ -27,41 +32,75 @@ type PairingService struct {
 }
 
 // RegisterDevice registers a new device into the Realm.
-// Returns the Credential Secret of the Device when successful.
-// TODO: add support for initial_introspection
+// Returns the Credential Secret of the Device when successful. If the Device has already been registered,
+// the returned error wraps ErrDeviceAlreadyRegistered.
 func (s *PairingService) RegisterDevice(realm string, deviceID string) (string, error) {
+	return s.RegisterDeviceWithIntrospection(realm, deviceID, nil)
+}
+
+// RegisterDeviceWithIntrospection is the same as RegisterDevice, but also sets the initial introspection of the
+// Device, so that its Interfaces are known to Astarte before it connects for the first time. introspection maps
+// Interface names to their versions.
+func (s *PairingService) RegisterDeviceWithIntrospection(realm string, deviceID string,
+	introspection map[string]interfaces.InterfaceVersion) (string, error) {
 	callURL, _ := url.Parse(s.pairingURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/agent/devices", realm))
 
 	var requestBody struct {
-		HwID string `json:"hw_id"`
+		HwID                 string                                 `json:"hw_id"`
+		InitialIntrospection map[string]interfaces.InterfaceVersion `json:"initial_introspection,omitempty"`
 	}
 	requestBody.HwID = deviceID
+	requestBody.InitialIntrospection = introspection
 
 	ret := deviceRegistrationResponse{}
-	err := s.client.genericJSONDataAPIPostWithResponse(&ret, callURL.String(), requestBody, 201)
+	err := s.client.genericJSONDataAPIPostWithResponse(context.Background(), &ret, callURL.String(), requestBody, 201)
+	if isDeviceAlreadyRegistered(err) {
+		err = withErrorCause(err, http.StatusUnprocessableEntity, ErrDeviceAlreadyRegistered)
+	}
 
 	return ret.CredentialsSecret, err
 }
 
+// isDeviceAlreadyRegistered tells whether err is the 422 Astarte replies with when registering a Device which
+// has already been registered, as opposed to other validation errors such as a malformed Device ID.
+func isDeviceAlreadyRegistered(err error) bool {
+	var apiError *AstarteAPIError
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusUnprocessableEntity &&
+		apiError.Detail == "Device already registered"
+}
+
 // UnregisterDevice resets the registration state of a device. This makes it possible to register it again.
-// All data belonging to the device will be left as is in Astarte.
+// All data belonging to the device will be left as is in Astarte. If the Device does not exist, the returned
+// error wraps ErrDeviceNotFound.
 func (s *PairingService) UnregisterDevice(realm string, deviceID string) error {
 	callURL, _ := url.Parse(s.pairingURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/agent/devices/%s", realm, deviceID))
 
-	err := s.client.genericJSONDataAPIDelete(callURL.String(), 204)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	err := s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+	return withErrorCause(err, http.StatusNotFound, ErrDeviceNotFound)
 }
 
 // ObtainNewMQTTv1CertificateForDevice returns a valid SSL Certificate for Devices running on astarte_mqtt_v1.
 // This API is meant to be called by the device, and your Client needs to have the Device's Credentials Secret
 // as its token. Always call SetToken with the Credentials Secret before calling this function.
 func (s *PairingService) ObtainNewMQTTv1CertificateForDevice(realm, deviceID, csr string) (string, error) {
+	return s.obtainMQTTv1Certificate(context.Background(), realm, deviceID, csr)
+}
+
+// ObtainCredentials is the same as ObtainNewMQTTv1CertificateForDevice, but the request is authenticated with
+// credentialsSecret in place of the token of the Client. csrPEM is a PEM encoded Certificate Signing Request of the
+// Device, and the returned certificate is PEM encoded as well.
+func (s *PairingService) ObtainCredentials(realm, deviceID, credentialsSecret string, csrPEM []byte) ([]byte, error) {
+	certificate, err := s.obtainMQTTv1Certificate(withCredentialsSecret(context.Background(), credentialsSecret),
+		realm, deviceID, string(csrPEM))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(certificate), nil
+}
+
+func (s *PairingService) obtainMQTTv1Certificate(ctx context.Context, realm, deviceID, csr string) (string, error) {
 	callURL, _ := url.Parse(s.pairingURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials", realm, deviceID))
 
@@ -71,7 +110,7 @@ func (s *PairingService) ObtainNewMQTTv1CertificateForDevice(realm, deviceID, cs
 	requestBody.CSR = csr
 
 	ret := getMQTTv1CertificateResponse{}
-	err := s.client.genericJSONDataAPIPostWithResponse(&ret, callURL.String(), requestBody, 201)
+	err := s.client.genericJSONDataAPIPostWithResponse(ctx, &ret, callURL.String(), requestBody, 201)
 
 	return ret.ClientCertificate, err
 }
@@ -85,7 +124,58 @@ func (s *PairingService) GetMQTTv1ProtocolInformationForDevice(realm, deviceID s
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s", realm, deviceID))
 
 	ret := getDeviceProtocolStatusResponse{}
-	err := s.client.genericJSONDataAPIGET(&ret, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(context.Background(), &ret, callURL.String(), 200)
 
 	return ret.Protocols.AstarteMQTTv1, err
 }
+
+// GetMQTTv1Transport returns the URL of the broker a Device running on astarte_mqtt_v1 must connect to. The request
+// is authenticated with credentialsSecret in place of the token of the Client.
+func (s *PairingService) GetMQTTv1Transport(realm, deviceID, credentialsSecret string) (string, error) {
+	status, err := s.GetDeviceCredentialsStatus(realm, deviceID, credentialsSecret)
+	if err != nil {
+		return "", err
+	}
+	return status.MQTTv1.BrokerURL, nil
+}
+
+// GetDeviceCredentialsStatus returns the status of a Device as seen by the Device itself, including whether its
+// credentials are inhibited and the information needed to connect through astarte_mqtt_v1. The request is
+// authenticated with credentialsSecret in place of the token of the Client. Use VerifyMQTTv1Certificate to check
+// whether a certificate of the Device is still valid.
+func (s *PairingService) GetDeviceCredentialsStatus(realm, deviceID, credentialsSecret string) (DeviceCredentialsStatus, error) {
+	callURL, _ := url.Parse(s.pairingURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s", realm, deviceID))
+
+	ret := getDeviceProtocolStatusResponse{}
+	ctx := withCredentialsSecret(context.Background(), credentialsSecret)
+	if err := s.client.genericJSONDataAPIGET(ctx, &ret, callURL.String(), 200); err != nil {
+		return DeviceCredentialsStatus{}, err
+	}
+
+	return DeviceCredentialsStatus{
+		Status:    ret.Status,
+		Inhibited: ret.Status == "inhibited",
+		Version:   ret.Version,
+		MQTTv1:    ret.Protocols.AstarteMQTTv1,
+	}, nil
+}
+
+// VerifyMQTTv1Certificate asks Astarte whether certificate, a PEM encoded astarte_mqtt_v1 certificate of a Device,
+// is valid, and returns the outcome along with its expiration time. The request is authenticated with
+// credentialsSecret in place of the token of the Client.
+func (s *PairingService) VerifyMQTTv1Certificate(realm, deviceID, credentialsSecret, certificate string) (MQTTv1CertificateVerification, error) {
+	callURL, _ := url.Parse(s.pairingURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/devices/%s/protocols/astarte_mqtt_v1/credentials/verify", realm, deviceID))
+
+	var requestBody struct {
+		ClientCertificate string `json:"client_crt"`
+	}
+	requestBody.ClientCertificate = certificate
+
+	ret := MQTTv1CertificateVerification{}
+	ctx := withCredentialsSecret(context.Background(), credentialsSecret)
+	err := s.client.genericJSONDataAPIPostWithResponse(ctx, &ret, callURL.String(), requestBody, 200)
+
+	return ret, err
+}
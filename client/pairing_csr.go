@@ -0,0 +1,55 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+)
+
+// GenerateDeviceCSR generates a new private key for a Device and a Certificate Signing Request for it, to be passed
+// to ObtainCredentials. The Common Name of the CSR is set to "<realm>/<deviceID>", as required by Astarte. Both the
+// CSR and the ECDSA P-256 private key are returned PEM encoded, the latter in PKCS #8 form: along with the obtained
+// certificate, they can be loaded with tls.X509KeyPair.
+func GenerateDeviceCSR(realm, deviceID string) (csrPEM []byte, privateKeyPEM []byte, err error) {
+	if realm == "" || deviceID == "" {
+		return nil, nil, fmt.Errorf("realm and deviceID must not be empty")
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: fmt.Sprintf("%s/%s", realm, deviceID)},
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	encodedKey, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csrPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})
+	privateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encodedKey})
+	return csrPEM, privateKeyPEM, nil
+}
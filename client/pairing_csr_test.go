@@ -0,0 +1,61 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateDeviceCSR(t *testing.T) {
+	csrPEM, privateKeyPEM, err := GenerateDeviceCSR(testRealmName, testDevices[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatal("Invalid CSR PEM", string(csrPEM))
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Error(err)
+	}
+	if csr.Subject.CommonName != testRealmName+"/"+testDevices[0] {
+		t.Error("Wrong Common Name", csr.Subject.CommonName)
+	}
+
+	block, _ = pem.Decode(privateKeyPEM)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatal("Invalid private key PEM", string(privateKeyPEM))
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(privateKey.(crypto.Signer).Public(), csr.PublicKey) {
+		t.Error("The private key does not match the CSR")
+	}
+
+	if _, _, err := GenerateDeviceCSR(testRealmName, ""); err == nil {
+		t.Error("Expected an error for an empty Device ID")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/deviceid"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+const testCredentialsSecret = "TTkd5OgB13X/3qU0LXU7OCxyTXz5QHM2NY1IgidtPOs="
+
+// lastIntrospection holds the last initial introspection received by the Pairing mock
+var lastIntrospection interface{}
+
+func getPairingTestContext(t *testing.T) (*Client, func()) {
+	registered := map[string]bool{testDevices[0]: true}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		devicesPath := fmt.Sprintf("/pairing/v1/%s/agent/devices", testRealmName)
+		if req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, devicesPath+"/") {
+			deviceID := strings.TrimPrefix(req.URL.Path, devicesPath+"/")
+			if !registered[deviceID] {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":{"detail":"Device not found"}}`)
+				return
+			}
+			delete(registered, deviceID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if req.URL.Path != devicesPath || req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		deviceID, _ := body.Data["hw_id"].(string)
+		if registered[deviceID] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"errors":{"detail":"Device already registered"}}`)
+			return
+		}
+		if !deviceid.ValidateDeviceID(deviceID) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"errors":{"hw_id":["is invalid"]}}`)
+			return
+		}
+		if introspection, ok := body.Data["initial_introspection"]; ok {
+			lastIntrospection = introspection
+		}
+		registered[deviceID] = true
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"data":{"credentials_secret":"%s"}}`, testCredentialsSecret)
+	})
+	return client, server.Close
+}
+
+func TestRegisterDevice(t *testing.T) {
+	client, closeServer := getPairingTestContext(t)
+	defer closeServer()
+
+	credentialsSecret, err := client.Pairing.RegisterDevice(testRealmName, testDevices[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentialsSecret != testCredentialsSecret {
+		t.Error("Unexpected credentials secret", credentialsSecret)
+	}
+
+	_, err = client.Pairing.RegisterDevice(testRealmName, testDevices[0])
+	if !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Error("Expected ErrDeviceAlreadyRegistered, got", err)
+	}
+}
+
+func TestRegisterDeviceInvalidID(t *testing.T) {
+	client, closeServer := getPairingTestContext(t)
+	defer closeServer()
+
+	_, err := client.Pairing.RegisterDevice(testRealmName, "not-a-device-id")
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatal("Expected a 422 AstarteAPIError, got", err)
+	}
+	if errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Error("A malformed Device ID was reported as ErrDeviceAlreadyRegistered")
+	}
+}
+
+func TestUnregisterDevice(t *testing.T) {
+	client, closeServer := getPairingTestContext(t)
+	defer closeServer()
+
+	if err := client.Pairing.UnregisterDevice(testRealmName, testDevices[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Pairing.RegisterDevice(testRealmName, testDevices[0]); err != nil {
+		t.Error("Device could not be registered again", err)
+	}
+
+	err := client.Pairing.UnregisterDevice(testRealmName, testDevices[2])
+	if !errors.Is(err, ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+}
+
+func TestRegisterDeviceWithIntrospection(t *testing.T) {
+	client, closeServer := getPairingTestContext(t)
+	defer closeServer()
+
+	introspection := map[string]interfaces.InterfaceVersion{
+		"org.astarte-platform.genericsensors.Values": {Major: 1, Minor: 2},
+	}
+	if _, err := client.Pairing.RegisterDeviceWithIntrospection(testRealmName, testDevices[1], introspection); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"org.astarte-platform.genericsensors.Values": map[string]interface{}{"major": float64(1), "minor": float64(2)},
+	}
+	if !reflect.DeepEqual(lastIntrospection, expected) {
+		t.Error("Unexpected initial introspection", lastIntrospection)
+	}
+}
+
+func TestGetDeviceCredentialsStatus(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer "+testCredentialsSecret {
+			t.Error("Request not authenticated with the credentials secret", req.Header.Get("Authorization"))
+		}
+		if req.URL.Path != fmt.Sprintf("/pairing/v1/%s/devices/%s", testRealmName, testDevices[0]) {
+			t.Error("Unexpected path", req.URL.Path)
+		}
+		fmt.Fprint(w, `{"data":{"version":"1.0.0","status":"inhibited","protocols":{"astarte_mqtt_v1":{"broker_url":"mqtts://broker.astarte.example.com:8883/"}}}}`)
+	})
+	defer server.Close()
+
+	status, err := client.Pairing.GetDeviceCredentialsStatus(testRealmName, testDevices[0], testCredentialsSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DeviceCredentialsStatus{
+		Status:    "inhibited",
+		Inhibited: true,
+		Version:   "1.0.0",
+		MQTTv1:    AstarteMQTTv1ProtocolInformation{BrokerURL: "mqtts://broker.astarte.example.com:8883/"},
+	}
+	if status != expected {
+		t.Error("Wrong status returned", status)
+	}
+}
+
+func TestVerifyMQTTv1Certificate(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer "+testCredentialsSecret {
+			t.Error("Request not authenticated with the credentials secret", req.Header.Get("Authorization"))
+		}
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if body.Data["client_crt"] == "expired" {
+			fmt.Fprint(w, `{"data":{"valid":false,"timestamp":"2020-03-12T19:00:00Z","cause":"EXPIRED","details":"certificate expired"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"valid":true,"timestamp":"2020-03-12T19:00:00Z","until":"2020-06-12T19:00:00Z"}}`)
+	})
+	defer server.Close()
+
+	verification, err := client.Pairing.VerifyMQTTv1Certificate(testRealmName, testDevices[0], testCredentialsSecret, "valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid || verification.Until == nil || verification.Until.Month() != time.June {
+		t.Error("Wrong verification returned", verification)
+	}
+
+	verification, err = client.Pairing.VerifyMQTTv1Certificate(testRealmName, testDevices[0], testCredentialsSecret, "expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification.Valid || verification.Cause != "EXPIRED" {
+		t.Error("Wrong verification returned", verification)
+	}
+}
+
+func TestDevicePairingFlow(t *testing.T) {
+	const brokerURL = "mqtts://broker.astarte.example.com:8883/"
+	const csr = "-----BEGIN CERTIFICATE REQUEST-----\nMIIB\n-----END CERTIFICATE REQUEST-----\n"
+	const certificate = "-----BEGIN CERTIFICATE-----\nMIIC\n-----END CERTIFICATE-----\n"
+	devicePath := fmt.Sprintf("/pairing/v1/%s/devices/%s", testRealmName, testDevices[0])
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer "+testCredentialsSecret {
+			t.Error("Request not authenticated with the credentials secret", req.Header.Get("Authorization"))
+		}
+		switch req.URL.Path {
+		case devicePath:
+			fmt.Fprintf(w, `{"data":{"version":"1.0.0","status":"pending","protocols":{"astarte_mqtt_v1":{"broker_url":"%s"}}}}`, brokerURL)
+		case devicePath + "/protocols/astarte_mqtt_v1/credentials":
+			var body struct {
+				Data map[string]string `json:"data"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			if body.Data["csr"] != csr {
+				t.Error("Unexpected CSR", body.Data["csr"])
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"client_crt": certificate}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	url, err := client.Pairing.GetMQTTv1Transport(testRealmName, testDevices[0], testCredentialsSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != brokerURL {
+		t.Error("Wrong broker URL", url)
+	}
+
+	certPEM, err := client.Pairing.ObtainCredentials(testRealmName, testDevices[0], testCredentialsSecret, []byte(csr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(certPEM) != certificate {
+		t.Error("Wrong certificate", string(certPEM))
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// phoenixHeartbeatInterval is how often the socket is checked to be alive. If a heartbeat is not
+	// acknowledged within phoenixReplyTimeout, the connection is considered lost and closed
+	phoenixHeartbeatInterval = 30 * time.Second
+	phoenixReplyTimeout      = 10 * time.Second
+)
+
+// errPhoenixSocketClosed is returned when pushing on a socket whose connection has been lost
+var errPhoenixSocketClosed = errors.New("the socket connection has been closed")
+
+// phoenixMessage is a message of the Phoenix Channels protocol, as serialized by its v1 JSON serializer
+type phoenixMessage struct {
+	Topic   string          `json:"topic"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	Ref     *string         `json:"ref"`
+}
+
+type phoenixReply struct {
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// phoenixSocket is a single connection to a Phoenix socket. Messages which are not replies to a push are
+// passed to onMessage, from the goroutine reading the socket. The socket is unusable once done is closed,
+// and a new one must be dialed.
+type phoenixSocket struct {
+	conn      *websocket.Conn
+	onMessage func(phoenixMessage)
+
+	writeLock sync.Mutex
+	lock      sync.Mutex
+	lastRef   int
+	pending   map[string]chan phoenixReply
+
+	done chan struct{}
+	err  error
+}
+
+// newWebsocketDialer returns a dialer sharing the TLS configuration, proxy, cookies and timeout of httpClient,
+// as long as its transport is an *http.Transport.
+func newWebsocketDialer(httpClient *http.Client) *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = httpClient.Timeout
+	dialer.Jar = httpClient.Jar
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if httpClient.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if ok {
+		dialer.Proxy = transport.Proxy
+		dialer.NetDialContext = transport.DialContext
+		if transport.TLSClientConfig != nil {
+			dialer.TLSClientConfig = transport.TLSClientConfig.Clone()
+		}
+	}
+	return &dialer
+}
+
+func dialPhoenixSocket(ctx context.Context, dialer *websocket.Dialer, url string, header http.Header,
+	onMessage func(phoenixMessage)) (*phoenixSocket, error) {
+	conn, _, err := dialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &phoenixSocket{
+		conn:      conn,
+		onMessage: onMessage,
+		pending:   map[string]chan phoenixReply{},
+		done:      make(chan struct{}),
+	}
+	go s.readLoop()
+	go s.heartbeatLoop()
+
+	return s, nil
+}
+
+func (s *phoenixSocket) readLoop() {
+	defer close(s.done)
+
+	for {
+		message := phoenixMessage{}
+		if err := s.conn.ReadJSON(&message); err != nil {
+			s.err = err
+			return
+		}
+
+		if message.Event == "phx_reply" && message.Ref != nil {
+			s.lock.Lock()
+			replyChan, ok := s.pending[*message.Ref]
+			delete(s.pending, *message.Ref)
+			s.lock.Unlock()
+
+			if ok {
+				reply := phoenixReply{}
+				if err := json.Unmarshal(message.Payload, &reply); err != nil {
+					reply.Status = "error"
+				}
+				replyChan <- reply
+			}
+			continue
+		}
+
+		s.onMessage(message)
+	}
+}
+
+func (s *phoenixSocket) heartbeatLoop() {
+	ticker := time.NewTicker(phoenixHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// The connection might be stale without the read loop noticing: drop it, so that it terminates
+			if _, err := s.push(context.Background(), "phoenix", "heartbeat", struct{}{}); err != nil {
+				s.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// push sends event on topic and waits for its reply. An error is returned if the reply status is not ok.
+func (s *phoenixSocket) push(ctx context.Context, topic, event string, payload interface{}) (json.RawMessage, error) {
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.lastRef++
+	ref := strconv.Itoa(s.lastRef)
+	replyChan := make(chan phoenixReply, 1)
+	s.pending[ref] = replyChan
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.pending, ref)
+		s.lock.Unlock()
+	}()
+
+	s.writeLock.Lock()
+	err = s.conn.WriteJSON(phoenixMessage{Topic: topic, Event: event, Payload: encodedPayload, Ref: &ref})
+	s.writeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(phoenixReplyTimeout)
+	defer timer.Stop()
+
+	select {
+	case reply := <-replyChan:
+		if reply.Status != "ok" {
+			return nil, fmt.Errorf("%s on %s failed: %s", event, topic, reply.Response)
+		}
+		return reply.Response, nil
+	case <-s.done:
+		return nil, errPhoenixSocketClosed
+	case <-timer.C:
+		return nil, fmt.Errorf("timed out waiting for a reply to %s on %s", event, topic)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// close closes the underlying connection, and waits for the read loop to terminate.
+func (s *phoenixSocket) close() {
+	s.writeLock.Lock()
+	_ = s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	s.writeLock.Unlock()
+	s.conn.Close()
+	<-s.done
+}
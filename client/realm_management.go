@@ -15,11 +15,17 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/policies"
+	"github.com/astarte-platform/astarte-go/triggers"
 )
 
 // RealmManagementService is the API Client for RealmManagement API
@@ -34,52 +40,86 @@ func (s *RealmManagementService) ListInterfaces(realm string) ([]string, error)
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces", realm))
 
 	interfacesList := []string{}
-	err := s.client.genericJSONDataAPIGET(&interfacesList, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(context.Background(), &interfacesList, callURL.String(), 200)
 
 	return interfacesList, err
 }
 
-// ListInterfaceMajorVersions returns all available major versions for a given Interface in a Realm.
+// ListInterfaceMajorVersions returns all available major versions for a given Interface in a Realm. If the
+// Interface is not installed, the returned error wraps ErrInterfaceNotFound.
 func (s *RealmManagementService) ListInterfaceMajorVersions(realm string, interfaceName string) ([]int, error) {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces/%s", realm, interfaceName))
 
 	interfaceMajorVersions := []int{}
-	err := s.client.genericJSONDataAPIGET(&interfaceMajorVersions, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(context.Background(), &interfaceMajorVersions, callURL.String(), 200)
 
-	return interfaceMajorVersions, err
+	return interfaceMajorVersions, withErrorCause(err, http.StatusNotFound, ErrInterfaceNotFound)
 }
 
-// GetInterface returns an interface, identified by a Major version, in a Realm
+// GetInterface returns an interface, identified by a Major version, in a Realm. If that major version is not
+// installed, the returned error wraps ErrInterfaceNotFound: along with interfaces.Diff, this allows to tell
+// whether a local definition needs to be installed, to be updated or is already up to date.
 func (s *RealmManagementService) GetInterface(realm string, interfaceName string, interfaceMajor int) (interfaces.AstarteInterface, error) {
+	return s.GetInterfaceWithContext(context.Background(), realm, interfaceName, interfaceMajor)
+}
+
+// GetInterfaceWithContext is the same as GetInterface, but ctx is used for the underlying HTTP request.
+func (s *RealmManagementService) GetInterfaceWithContext(ctx context.Context, realm string, interfaceName string,
+	interfaceMajor int, opts ...RequestOption) (interfaces.AstarteInterface, error) {
+	ctx, cancel := withRequestOptions(ctx, opts)
+	defer cancel()
+
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces/%s/%v", realm, interfaceName, interfaceMajor))
 
 	iface := interfaces.AstarteInterface{}
-	err := s.client.genericJSONDataAPIGET(&iface, callURL.String(), 200)
+	err := s.client.genericJSONDataAPIGET(ctx, &iface, callURL.String(), 200)
 
-	return interfaces.EnsureInterfaceDefaults(iface), err
+	return interfaces.EnsureInterfaceDefaults(iface), withErrorCause(err, http.StatusNotFound, ErrInterfaceNotFound)
 }
 
-// InstallInterface installs a new major version of an Interface into the Realm
+// InstallInterface installs a new major version of an Interface into the Realm. If the major version
+// is already installed, the returned error wraps ErrInterfaceAlreadyInstalled.
 func (s *RealmManagementService) InstallInterface(realm string, interfacePayload interfaces.AstarteInterface) error {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces", realm))
-	return s.client.genericJSONDataAPIPost(callURL.String(), interfacePayload, 201)
+	err := s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), interfacePayload, 201)
+	return withErrorCause(err, http.StatusConflict, ErrInterfaceAlreadyInstalled)
 }
 
-// DeleteInterface deletes a draft Interface from the Realm
+// DeleteInterface deletes a draft Interface from the Realm. If Astarte refuses to delete the Interface,
+// the returned error wraps ErrInterfaceNotDeletable.
 func (s *RealmManagementService) DeleteInterface(realm string, interfaceName string, interfaceMajor int) error {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces/%s/%v", realm, interfaceName, interfaceMajor))
-	return s.client.genericJSONDataAPIDelete(callURL.String(), 204)
+	err := s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+	if isInterfaceNotDeletable(err) {
+		err = withErrorCause(err, http.StatusForbidden, ErrInterfaceNotDeletable)
+	}
+	return err
+}
+
+// isInterfaceNotDeletable tells whether err is the 403 Astarte returns when it refuses to delete an Interface,
+// as opposed to a 403 caused by missing authorization.
+func isInterfaceNotDeletable(err error) bool {
+	var apiError *AstarteAPIError
+	return errors.As(err, &apiError) && apiError.StatusCode == http.StatusForbidden &&
+		strings.Contains(strings.ToLower(apiError.Detail), "delete")
 }
 
 // UpdateInterface updates an existing major version of an Interface to a new minor.
+// Astarte allows only minor version bumps with additive changes to the mappings: if the update is rejected,
+// the returned error wraps ErrInvalidInterface, and its AstarteAPIError carries the reasons of the rejection.
 func (s *RealmManagementService) UpdateInterface(realm string, interfaceName string, interfaceMajor int, interfacePayload interfaces.AstarteInterface) error {
+	if interfacePayload.Name != interfaceName || interfacePayload.MajorVersion != interfaceMajor {
+		return fmt.Errorf("interface payload %s v%d does not match %s v%d", interfacePayload.Name, interfacePayload.MajorVersion,
+			interfaceName, interfaceMajor)
+	}
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/interfaces/%s/%v", realm, interfaceName, interfaceMajor))
-	return s.client.genericJSONDataAPIPut(callURL.String(), interfacePayload, 204)
+	err := s.client.genericJSONDataAPIPut(context.Background(), callURL.String(), interfacePayload, 204)
+	return withErrorCause(err, http.StatusUnprocessableEntity, ErrInvalidInterface)
 }
 
 // ListTriggers returns all triggers in a Realm.
@@ -87,33 +127,90 @@ func (s *RealmManagementService) ListTriggers(realm string) ([]string, error) {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/triggers", realm))
 
-	triggers := []string{}
-	err := s.client.genericJSONDataAPIGET(&triggers, callURL.String(), 200)
+	triggerNames := []string{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &triggerNames, callURL.String(), 200)
 
-	return triggers, err
+	return triggerNames, err
 }
 
 // GetTrigger returns a trigger installed in a Realm
-func (s *RealmManagementService) GetTrigger(realm string, triggerName string) (map[string]interface{}, error) {
+func (s *RealmManagementService) GetTrigger(realm string, triggerName string) (triggers.AstarteTrigger, error) {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/triggers/%s", realm, triggerName))
 
-	trigger := map[string]interface{}{}
-	err := s.client.genericJSONDataAPIGET(&trigger, callURL.String(), 200)
+	trigger := triggers.AstarteTrigger{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &trigger, callURL.String(), 200)
 
 	return trigger, err
 }
 
-// InstallTrigger installs a Trigger into the Realm
-func (s *RealmManagementService) InstallTrigger(realm string, triggerPayload interface{}) error {
+// InstallTrigger installs a Trigger into the Realm. The Trigger is validated before being sent to Astarte. If the
+// Trigger uses a Trigger Delivery Policy and Astarte does not support them, the returned error wraps
+// ErrUnsupportedByAstarte.
+func (s *RealmManagementService) InstallTrigger(realm string, trigger triggers.AstarteTrigger) error {
+	if err := trigger.Validate(); err != nil {
+		return err
+	}
+	if trigger.Policy != "" {
+		if err := s.client.requireAstarteVersion(context.Background(), realm, minTriggerDeliveryPoliciesVersion,
+			"trigger delivery policies"); err != nil {
+			return err
+		}
+	}
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/triggers", realm))
-	return s.client.genericJSONDataAPIPost(callURL.String(), triggerPayload, 201)
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), trigger, 201)
 }
 
 // DeleteTrigger deletes a Trigger from the Realm
 func (s *RealmManagementService) DeleteTrigger(realm string, triggerName string) error {
 	callURL, _ := url.Parse(s.realmManagementURL.String())
 	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/triggers/%s", realm, triggerName))
-	return s.client.genericJSONDataAPIDelete(callURL.String(), 204)
+	return s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
+}
+
+// ListTriggerDeliveryPolicies returns all Trigger Delivery Policies in a Realm.
+func (s *RealmManagementService) ListTriggerDeliveryPolicies(realm string) ([]string, error) {
+	callURL, _ := url.Parse(s.realmManagementURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/policies", realm))
+
+	policyNames := []string{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &policyNames, callURL.String(), 200)
+
+	return policyNames, err
+}
+
+// GetTriggerDeliveryPolicy returns a Trigger Delivery Policy installed in a Realm
+func (s *RealmManagementService) GetTriggerDeliveryPolicy(realm string, policyName string) (policies.Policy, error) {
+	callURL, _ := url.Parse(s.realmManagementURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/policies/%s", realm, policyName))
+
+	policy := policies.Policy{}
+	err := s.client.genericJSONDataAPIGET(context.Background(), &policy, callURL.String(), 200)
+
+	return policy, err
+}
+
+// InstallTriggerDeliveryPolicy installs a Trigger Delivery Policy into the Realm. The Policy is validated before
+// being sent to Astarte. If Astarte does not support Trigger Delivery Policies, the returned error wraps
+// ErrUnsupportedByAstarte.
+func (s *RealmManagementService) InstallTriggerDeliveryPolicy(realm string, policy policies.Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if err := s.client.requireAstarteVersion(context.Background(), realm, minTriggerDeliveryPoliciesVersion,
+		"trigger delivery policies"); err != nil {
+		return err
+	}
+	callURL, _ := url.Parse(s.realmManagementURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/policies", realm))
+	return s.client.genericJSONDataAPIPost(context.Background(), callURL.String(), policy, 201)
+}
+
+// DeleteTriggerDeliveryPolicy deletes a Trigger Delivery Policy from the Realm. Astarte refuses to delete
+// Policies which are still used by a Trigger.
+func (s *RealmManagementService) DeleteTriggerDeliveryPolicy(realm string, policyName string) error {
+	callURL, _ := url.Parse(s.realmManagementURL.String())
+	callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/policies/%s", realm, policyName))
+	return s.client.genericJSONDataAPIDelete(context.Background(), callURL.String(), 204)
 }
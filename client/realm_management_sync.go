@@ -0,0 +1,154 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// SyncAction is the action taken by SyncInterfaces on an Interface
+type SyncAction string
+
+const (
+	// InterfaceInstalled means the Interface major version was not installed, and has been installed
+	InterfaceInstalled SyncAction = "installed"
+	// InterfaceUpdated means the installed Interface has been updated to a newer minor version
+	InterfaceUpdated SyncAction = "updated"
+	// InterfaceUnchanged means the installed Interface is identical to the local one, and has been left untouched
+	InterfaceUnchanged SyncAction = "unchanged"
+	// InterfaceSyncFailed means the Interface could not be synced. The reason is reported in Err
+	InterfaceSyncFailed SyncAction = "failed"
+)
+
+// InterfaceSyncResult reports what SyncInterfaces did with a single Interface
+type InterfaceSyncResult struct {
+	Name         string
+	MajorVersion int
+	Action       SyncAction
+	// Changes are the changes from the installed Interface to the local one, if it was already installed
+	Changes []interfaces.Change
+	// Err is set when Action is InterfaceSyncFailed
+	Err error
+}
+
+// SyncReport reports the actions taken by SyncInterfaces, in the order the Interfaces were given
+type SyncReport struct {
+	Interfaces []InterfaceSyncResult
+}
+
+// Failed returns the results of the Interfaces which could not be synced
+func (r SyncReport) Failed() []InterfaceSyncResult {
+	failed := []InterfaceSyncResult{}
+	for _, result := range r.Interfaces {
+		if result.Action == InterfaceSyncFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// SyncOption customizes the behavior of SyncInterfaces
+type SyncOption func(o *syncOptions)
+
+type syncOptions struct {
+	continueOnError bool
+}
+
+// WithContinueOnError makes SyncInterfaces go on syncing the remaining Interfaces when one of them fails,
+// rather than stopping at the first failure.
+func WithContinueOnError(continueOnError bool) SyncOption {
+	return func(o *syncOptions) {
+		o.continueOnError = continueOnError
+	}
+}
+
+// SyncInterfaces makes the Interfaces installed in realm match localInterfaces: major versions which are not
+// installed are installed, installed ones are updated if the local definition has a newer minor version, and
+// identical ones are left untouched. Local definitions with changes Astarte would reject, as reported by
+// interfaces.Diff, fail without contacting Astarte. Interfaces installed in realm but missing from
+// localInterfaces are never deleted.
+//
+// The returned SyncReport lists the action taken on each Interface. By default, SyncInterfaces stops at the first
+// Interface which cannot be synced, use WithContinueOnError to sync all of them anyway. In both cases, an error is
+// returned if any Interface failed.
+func (s *RealmManagementService) SyncInterfaces(realm string, localInterfaces []interfaces.AstarteInterface,
+	opts ...SyncOption) (SyncReport, error) {
+	o := syncOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	report := SyncReport{Interfaces: []InterfaceSyncResult{}}
+	var firstErr error
+	for _, localInterface := range localInterfaces {
+		result := s.syncInterface(realm, localInterface)
+		report.Interfaces = append(report.Interfaces, result)
+		if result.Err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = result.Err
+		}
+		if !o.continueOnError {
+			break
+		}
+	}
+
+	if firstErr != nil {
+		return report, fmt.Errorf("%d interfaces failed to sync, first error: %w", len(report.Failed()), firstErr)
+	}
+	return report, nil
+}
+
+func (s *RealmManagementService) syncInterface(realm string, localInterface interfaces.AstarteInterface) InterfaceSyncResult {
+	result := InterfaceSyncResult{Name: localInterface.Name, MajorVersion: localInterface.MajorVersion}
+	fail := func(err error) InterfaceSyncResult {
+		result.Action = InterfaceSyncFailed
+		result.Err = fmt.Errorf("%s v%d: %w", localInterface.Name, localInterface.MajorVersion, err)
+		return result
+	}
+
+	installed, err := s.GetInterface(realm, localInterface.Name, localInterface.MajorVersion)
+	if errors.Is(err, ErrInterfaceNotFound) {
+		if err := s.InstallInterface(realm, localInterface); err != nil {
+			return fail(err)
+		}
+		result.Action = InterfaceInstalled
+		return result
+	} else if err != nil {
+		return fail(err)
+	}
+
+	if result.Changes, err = interfaces.Diff(installed, localInterface); err != nil {
+		return fail(err)
+	}
+	if len(result.Changes) == 0 {
+		result.Action = InterfaceUnchanged
+		return result
+	}
+	for _, change := range result.Changes {
+		if change.Breaking {
+			return fail(fmt.Errorf("cannot update the installed interface: %s", change))
+		}
+	}
+	if err := s.UpdateInterface(realm, localInterface.Name, localInterface.MajorVersion, localInterface); err != nil {
+		return fail(err)
+	}
+	result.Action = InterfaceUpdated
+	return result
+}
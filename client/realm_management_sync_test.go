@@ -0,0 +1,119 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+func testSyncInterface(name string, minor int, endpoints ...string) interfaces.AstarteInterface {
+	iface := interfaces.AstarteInterface{
+		Name:         name,
+		MajorVersion: 1,
+		MinorVersion: minor,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.DeviceOwnership,
+	}
+	for _, endpoint := range endpoints {
+		iface.Mappings = append(iface.Mappings, interfaces.AstarteInterfaceMapping{Endpoint: endpoint, Type: interfaces.Double})
+	}
+	return interfaces.EnsureInterfaceDefaults(iface)
+}
+
+func TestSyncInterfaces(t *testing.T) {
+	installed := map[string]interfaces.AstarteInterface{
+		"org.astarte-platform.Unchanged": testSyncInterface("org.astarte-platform.Unchanged", 1, "/value"),
+		"org.astarte-platform.Updated":   testSyncInterface("org.astarte-platform.Updated", 1, "/value"),
+		"org.astarte-platform.Breaking":  testSyncInterface("org.astarte-platform.Breaking", 1, "/value"),
+	}
+	calls := []string{}
+	interfacesPath := fmt.Sprintf("/realmmanagement/v1/%s/interfaces", testRealmName)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, req.Method+" "+strings.TrimPrefix(req.URL.Path, interfacesPath))
+		if req.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		} else if req.Method == http.MethodPut {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		name := strings.Split(strings.TrimPrefix(req.URL.Path, interfacesPath+"/"), "/")[0]
+		iface, ok := installed[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":{"detail":"Interface not found"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": iface})
+	})
+	defer server.Close()
+
+	local := []interfaces.AstarteInterface{
+		testSyncInterface("org.astarte-platform.Breaking", 2),
+		testSyncInterface("org.astarte-platform.New", 1, "/value"),
+		testSyncInterface("org.astarte-platform.Unchanged", 1, "/value"),
+		testSyncInterface("org.astarte-platform.Updated", 2, "/value", "/other"),
+	}
+
+	// Stop at the first failure
+	report, err := client.RealmManagement.SyncInterfaces(testRealmName, local)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	if len(report.Interfaces) != 1 || report.Interfaces[0].Action != InterfaceSyncFailed {
+		t.Errorf("Wrong report: %+v", report)
+	}
+
+	calls = []string{}
+	report, err = client.RealmManagement.SyncInterfaces(testRealmName, local, WithContinueOnError(true))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	expected := []SyncAction{InterfaceSyncFailed, InterfaceInstalled, InterfaceUnchanged, InterfaceUpdated}
+	if len(report.Interfaces) != len(expected) {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+	for i, result := range report.Interfaces {
+		if result.Action != expected[i] {
+			t.Errorf("%s: expected %s, got %s (%v)", result.Name, expected[i], result.Action, result.Err)
+		}
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Name != "org.astarte-platform.Breaking" {
+		t.Errorf("Wrong failed interfaces: %+v", failed)
+	}
+
+	// Interfaces with breaking changes must not reach Astarte
+	expectedCalls := []string{
+		"GET /org.astarte-platform.Breaking/1",
+		"GET /org.astarte-platform.New/1",
+		"POST ",
+		"GET /org.astarte-platform.Unchanged/1",
+		"GET /org.astarte-platform.Updated/1",
+		"PUT /org.astarte-platform.Updated/1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expectedCalls, "\n") {
+		t.Errorf("Wrong calls: %v", calls)
+	}
+	if !errors.Is(err, report.Interfaces[0].Err) {
+		t.Error("Expected the error of the failed interface, got", err)
+	}
+}
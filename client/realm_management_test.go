@@ -0,0 +1,289 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+	"github.com/astarte-platform/astarte-go/policies"
+	"github.com/astarte-platform/astarte-go/triggers"
+)
+
+func TestListInterfaces(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	interfaces, err := client.RealmManagement.ListInterfaces(testRealmName)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{
+		"org.astarte-platform.genericsensors.AvailableSensors",
+		"org.astarte-platform.genericsensors.SamplingRate",
+		"org.astarte-platform.genericsensors.Values",
+	}
+	if !reflect.DeepEqual(interfaces, expected) {
+		t.Error("Wrong interfaces returned", interfaces)
+	}
+}
+
+func TestListInterfaceMajorVersions(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	majors, err := client.RealmManagement.ListInterfaceMajorVersions(testRealmName, "org.astarte-platform.genericsensors.Values")
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(majors, []int{0}) {
+		t.Error("Wrong major versions returned", majors)
+	}
+
+	_, err = client.RealmManagement.ListInterfaceMajorVersions(testRealmName, "org.astarte-platform.Missing")
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusNotFound {
+		t.Error("Expected a not found error, got", err)
+	}
+	if !errors.Is(err, ErrInterfaceNotFound) {
+		t.Error("Expected ErrInterfaceNotFound, got", err)
+	}
+}
+
+func TestGetInterface(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	name := "org.astarte-platform.genericsensors.Values"
+	installed, err := client.RealmManagement.GetInterface(testRealmName, name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := interfaces.ParseInterfaceFromString(testInterfaces[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := interfaces.Diff(installed, local); err != nil || len(changes) != 0 {
+		t.Error("Expected no changes from the installed interface", changes, err)
+	}
+
+	if _, err := client.RealmManagement.GetInterface(testRealmName, name, 1); !errors.Is(err, ErrInterfaceNotFound) {
+		t.Error("Expected ErrInterfaceNotFound, got", err)
+	}
+	if _, err := client.RealmManagement.GetInterface(testRealmName, "org.astarte-platform.Missing", 0); !errors.Is(err, ErrInterfaceNotFound) {
+		t.Error("Expected ErrInterfaceNotFound, got", err)
+	}
+}
+
+func TestInstallInterface(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	iface := interfaces.AstarteInterface{
+		Name:      "org.astarte-platform.genericsensors.New",
+		Type:      interfaces.DatastreamType,
+		Ownership: interfaces.DeviceOwnership,
+		Mappings:  []interfaces.AstarteInterfaceMapping{{Endpoint: "/value", Type: interfaces.Double}},
+	}
+	if err := client.RealmManagement.InstallInterface(testRealmName, iface); err != nil {
+		t.Error(err)
+	}
+
+	iface.Name = "org.astarte-platform.genericsensors.Values"
+	err := client.RealmManagement.InstallInterface(testRealmName, iface)
+	if !errors.Is(err, ErrInterfaceAlreadyInstalled) {
+		t.Error("Expected ErrInterfaceAlreadyInstalled, got", err)
+	}
+}
+
+func TestUpdateInterfaceRejected(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprint(w, `{"errors":{"detail":"Interface minor version was not increased"}}`)
+	})
+	defer server.Close()
+
+	iface := interfaces.AstarteInterface{Name: "org.astarte-platform.genericsensors.Values", MajorVersion: 0, MinorVersion: 1}
+	err := client.RealmManagement.UpdateInterface(testRealmName, iface.Name, 0, iface)
+	if !errors.Is(err, ErrInvalidInterface) {
+		t.Error("Expected ErrInvalidInterface, got", err)
+	}
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) || apiError.Detail != "Interface minor version was not increased" {
+		t.Error("Wrong error details", err)
+	}
+
+	if err := client.RealmManagement.UpdateInterface(testRealmName, iface.Name, 1, iface); err == nil {
+		t.Error("Expected an error for mismatching major versions")
+	}
+}
+
+func TestDeleteInterfaceRefused(t *testing.T) {
+	detail := "Cannot delete currently used interface"
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodDelete {
+			t.Error("Unexpected method", req.Method)
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"errors":{"detail":%q}}`, detail)
+	})
+	defer server.Close()
+
+	err := client.RealmManagement.DeleteInterface(testRealmName, "org.astarte-platform.genericsensors.Values", 0)
+	if !errors.Is(err, ErrInterfaceNotDeletable) {
+		t.Error("Expected ErrInterfaceNotDeletable, got", err)
+	}
+
+	// A 403 caused by a token lacking the required claims is not about the Interface
+	detail = "Forbidden"
+	err = client.RealmManagement.DeleteInterface(testRealmName, "org.astarte-platform.genericsensors.Values", 0)
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusForbidden {
+		t.Error("Expected a 403 AstarteAPIError, got", err)
+	}
+	if errors.Is(err, ErrInterfaceNotDeletable) {
+		t.Error("Unexpected ErrInterfaceNotDeletable for an authorization failure")
+	}
+}
+
+func TestInstallAndGetTrigger(t *testing.T) {
+	installed := map[string]json.RawMessage{}
+	triggersPath := fmt.Sprintf("/realmmanagement/v1/%s/triggers", testRealmName)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == triggersPath && req.Method == http.MethodPost:
+			var body struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			var trigger triggers.AstarteTrigger
+			if err := json.Unmarshal(body.Data, &trigger); err != nil {
+				t.Error(err)
+			}
+			installed[trigger.Name] = body.Data
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":%s}`, body.Data)
+		case req.URL.Path == triggersPath+"/connections" && req.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"data":%s}`, installed["connections"])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	trigger := triggers.AstarteTrigger{
+		Name:           "connections",
+		Action:         triggers.NewAMQPAction("astarte_events_test_exchange", "connections", 60000),
+		SimpleTriggers: []triggers.SimpleTrigger{{Type: triggers.DeviceTrigger, On: triggers.DeviceConnected}},
+	}
+	if err := client.RealmManagement.InstallTrigger(testRealmName, trigger); err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := client.RealmManagement.GetTrigger(testRealmName, "connections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fetched, trigger) {
+		t.Error("Unexpected trigger", fetched)
+	}
+
+	trigger.Name = ""
+	if err := client.RealmManagement.InstallTrigger(testRealmName, trigger); err == nil {
+		t.Error("An invalid trigger should not be installed")
+	}
+	if len(installed) != 1 {
+		t.Error("Unexpected installed triggers", installed)
+	}
+}
+
+func TestTriggerDeliveryPolicies(t *testing.T) {
+	installed := map[string]json.RawMessage{}
+	policiesPath := fmt.Sprintf("/realmmanagement/v1/%s/policies", testRealmName)
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, policiesPath+"/")
+		switch {
+		case req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/version", testRealmName):
+			fmt.Fprint(w, `{"data":"1.1.0"}`)
+		case req.URL.Path == policiesPath && req.Method == http.MethodGet:
+			names := []string{}
+			for name := range installed {
+				names = append(names, name)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": names})
+		case req.URL.Path == policiesPath && req.Method == http.MethodPost:
+			var body struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			var policy policies.Policy
+			if err := json.Unmarshal(body.Data, &policy); err != nil {
+				t.Error(err)
+			}
+			installed[policy.Name] = body.Data
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"data":%s}`, body.Data)
+		case installed[name] != nil && req.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"data":%s}`, installed[name])
+		case installed[name] != nil && req.Method == http.MethodDelete:
+			delete(installed, name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	policy := policies.Policy{
+		Name:            "retry_server_errors",
+		MaximumCapacity: 100,
+		RetryTimes:      5,
+		ErrorHandlers: []policies.ErrorHandler{
+			{On: policies.ErrorRange{Keyword: policies.ServerError}, Strategy: policies.RetryStrategy},
+		},
+	}
+	if err := client.RealmManagement.InstallTriggerDeliveryPolicy(testRealmName, policy); err != nil {
+		t.Fatal(err)
+	}
+	names, err := client.RealmManagement.ListTriggerDeliveryPolicies(testRealmName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{policy.Name}) {
+		t.Error("Unexpected policies", names)
+	}
+	fetched, err := client.RealmManagement.GetTriggerDeliveryPolicy(testRealmName, policy.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fetched, policy) {
+		t.Error("Unexpected policy", fetched)
+	}
+	if err := client.RealmManagement.DeleteTriggerDeliveryPolicy(testRealmName, policy.Name); err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != 0 {
+		t.Error("The policy was not deleted")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// RequestOption customizes a single call to one of the AppEngineService methods taking a context, such as
+// GetDeviceWithContext. Unlike ClientOption, it affects only the call it is passed to.
+type RequestOption func(o *requestOptions)
+
+type requestOptions struct {
+	timeout          time.Duration
+	astarteInterface *interfaces.AstarteInterface
+	ifNoneMatch      bool
+}
+
+// WithTimeout makes the call fail if it does not complete within timeout. The timeout covers the whole call,
+// including retries and all the pages fetched by listing methods, and is applied on top of ctx and of the
+// timeout of the underlying http.Client. A timeout <= 0 is ignored.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithInterfaceValidation makes methods writing data on a single interface, such as SendDatastreamWithContext or
+// SetPropertyWithContext, check the path they are given against astarteInterface with interfaces.ValidatePath
+// before performing any request. The call fails if astarteInterface is not the interface the data is sent to.
+func WithInterfaceValidation(astarteInterface interfaces.AstarteInterface) RequestOption {
+	return func(o *requestOptions) {
+		o.astarteInterface = &astarteInterface
+	}
+}
+
+// WithIfNoneMatch makes the call conditional, when the Client was created with WithConditionalRequests: the ETag
+// Astarte replied with the last time the same call was made with WithIfNoneMatch is sent back in If-None-Match,
+// and the call returns ErrNotModified if the data did not change. It is meant for calls performing a single GET
+// request, such as GetDeviceWithContext: listing methods might return ErrNotModified after fetching only
+// part of their pages.
+func WithIfNoneMatch() RequestOption {
+	return func(o *requestOptions) {
+		o.ifNoneMatch = true
+	}
+}
+
+// withRequestOptions derives a context for a single call from ctx and opts. The returned
+// context.CancelFunc must always be called once the call completes.
+func withRequestOptions(ctx context.Context, opts []RequestOption) (context.Context, context.CancelFunc) {
+	o := applyRequestOptions(opts)
+	if o.ifNoneMatch {
+		ctx = withConditionalRequest(ctx)
+	}
+	if o.timeout > 0 {
+		return context.WithTimeout(ctx, o.timeout)
+	}
+	return ctx, func() {}
+}
+
+func applyRequestOptions(opts []RequestOption) requestOptions {
+	o := requestOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validateInterfacePath checks interfacePath against the interface passed to WithInterfaceValidation, if any.
+func validateInterfacePath(opts []RequestOption, interfaceName, interfacePath string) error {
+	astarteInterface, err := validationInterface(opts, interfaceName)
+	if astarteInterface == nil {
+		return err
+	}
+	return interfaces.ValidatePath(*astarteInterface, interfacePath)
+}
+
+// validateObjectPaths checks basePath and the keys of values against the interface passed to
+// WithInterfaceValidation, if any.
+func validateObjectPaths(opts []RequestOption, interfaceName, basePath string, values map[string]interface{}) error {
+	astarteInterface, err := validationInterface(opts, interfaceName)
+	if astarteInterface == nil {
+		return err
+	}
+	return interfaces.ValidateObjectPaths(*astarteInterface, basePath, values)
+}
+
+// validationInterface returns the interface passed to WithInterfaceValidation, or nil if there is none. An
+// error is returned if it is not interfaceName.
+func validationInterface(opts []RequestOption, interfaceName string) (*interfaces.AstarteInterface, error) {
+	o := applyRequestOptions(opts)
+	if o.astarteInterface != nil && o.astarteInterface.Name != interfaceName {
+		return nil, fmt.Errorf("cannot validate a path of %s against interface %s", interfaceName, o.astarteInterface.Name)
+	}
+	return o.astarteInterface, nil
+}
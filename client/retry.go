@@ -0,0 +1,168 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultMaxRetryDelay is the upper bound of the backoff of a retryPolicy with no maxDelay
+const defaultMaxRetryDelay = time.Minute
+
+// retryPolicy holds the retry configuration of a Client. The zero value disables retries.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	// maxDelay caps the exponential backoff. When zero, defaultMaxRetryDelay is used.
+	maxDelay   time.Duration
+	retryPatch bool
+}
+
+func (p retryPolicy) appliesTo(method string) bool {
+	if p.maxRetries == 0 {
+		return false
+	}
+	switch method {
+	case http.MethodGet:
+		return true
+	case http.MethodPatch:
+		return p.retryPatch
+	}
+	return false
+}
+
+// maxBackoff returns the longest delay the policy waits before a retry.
+func (p retryPolicy) maxBackoff() time.Duration {
+	if p.maxDelay <= 0 {
+		return defaultMaxRetryDelay
+	}
+	return p.maxDelay
+}
+
+// backoff returns the delay before the given retry attempt (starting from 0), exponentially
+// growing from baseDelay up to maxDelay, with a random jitter of up to half of the delay.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	maxDelay := p.maxBackoff()
+	// Compare against maxDelay shifted right, as shifting baseDelay left might overflow
+	delay := maxDelay
+	if p.baseDelay <= maxDelay>>uint(attempt) {
+		delay = p.baseDelay << uint(attempt)
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay/2) + 1)) //nolint:gosec
+	return delay/2 + jitter
+}
+
+func isRetriableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		// Connection errors are always retriable
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp, if any. Both the delay-seconds
+// and the HTTP-date forms are supported.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// doHTTPRequest performs req, retrying it according to the Client retry policy. When Astarte replies with
+// 429 Too Many Requests, the delay requested with Retry-After is honored instead of the backoff, up to the
+// maximum backoff of the policy.
+func (c *Client) doHTTPRequest(req *http.Request) (*http.Response, error) {
+	if !c.retryPolicy.appliesTo(req.Method) {
+		return c.doRateLimitedHTTPRequest(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := c.doRateLimitedHTTPRequest(req)
+		if attempt >= c.retryPolicy.maxRetries || ctx.Err() != nil || !isRetriableResponse(resp, err) {
+			return resp, err
+		}
+		delay, ok := retryAfter(resp)
+		if !ok {
+			delay = c.retryPolicy.backoff(attempt)
+		} else if delay > c.retryPolicy.maxBackoff() {
+			delay = c.retryPolicy.maxBackoff()
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		// Rebuild the request, as the body has already been consumed
+		retryReq := req.Clone(ctx)
+		if req.GetBody != nil {
+			if retryReq.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req = retryReq
+	}
+}
+
+// doRateLimitedHTTPRequest performs req once it is allowed by the Client rate limiter, if any.
+func (c *Client) doRateLimitedHTTPRequest(req *http.Request) (*http.Response, error) {
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return c.doLoggedHTTPRequest(req)
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getFlakyTestContext(t *testing.T, failures int, options ...ClientOption) (*Client, *httptest.Server, *int) {
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{"group"}})
+	}, options...)
+
+	return client, server, &calls
+}
+
+func TestRetryOnServiceUnavailable(t *testing.T) {
+	client, server, calls := getFlakyTestContext(t, 2, WithRetryPolicy(3, time.Millisecond))
+	defer server.Close()
+
+	if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+		t.Error(err)
+	}
+	if *calls != 3 {
+		t.Error("Expected 3 calls, got", *calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	client, server, calls := getFlakyTestContext(t, 5, WithRetryPolicy(1, time.Millisecond))
+	defer server.Close()
+
+	if _, err := client.AppEngine.ListGroups(testRealmName); err == nil {
+		t.Error("Expected an error")
+	}
+	if *calls != 2 {
+		t.Error("Expected 2 calls, got", *calls)
+	}
+}
+
+func TestNoRetryOnPatchByDefault(t *testing.T) {
+	client, server, calls := getFlakyTestContext(t, 1, WithRetryPolicy(3, time.Millisecond))
+	defer server.Close()
+
+	if err := client.AppEngine.AddDeviceAlias(testRealmName, testDevices[0], "tag", "alias"); err == nil {
+		t.Error("Expected an error")
+	}
+	if *calls != 1 {
+		t.Error("Expected 1 call, got", *calls)
+	}
+}
+
+func TestRetryOnPatch(t *testing.T) {
+	client, server, calls := getFlakyTestContext(t, 1, WithRetryPolicy(3, time.Millisecond), WithPatchRetries(true))
+	defer server.Close()
+
+	if err := client.AppEngine.AddDeviceAlias(testRealmName, testDevices[0], "tag", "alias"); err != nil {
+		t.Error(err)
+	}
+	if *calls != 2 {
+		t.Error("Expected 2 calls, got", *calls)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{"group"}})
+	}, WithRetryPolicy(1, time.Hour))
+	defer server.Close()
+
+	// The backoff would be way longer than the test timeout, so this completes only if Retry-After is honored
+	if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+		t.Error(err)
+	}
+	if calls != 2 {
+		t.Error("Expected 2 calls, got", calls)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	calls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{"group"}})
+	}, WithRetryPolicy(1, time.Millisecond))
+	defer server.Close()
+	client.retryPolicy.maxDelay = 10 * time.Millisecond
+
+	// An unbounded Retry-After would make the request outlive its context
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.AppEngine.ListGroupsWithContext(ctx, testRealmName); err != nil {
+		t.Error(err)
+	}
+	if calls != 2 {
+		t.Error("Expected 2 calls, got", calls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	client, server, calls := getFlakyTestContext(t, 0, WithRateLimit(20, 1))
+	defer server.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Error("Requests were not throttled, took", elapsed)
+	}
+	if *calls != 3 {
+		t.Error("Expected 3 calls, got", *calls)
+	}
+
+	if _, err := NewClient(server.URL, nil, WithRateLimit(0, 1)); err == nil {
+		t.Error("Expected an error with a zero rate")
+	}
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer server.Close()
+
+	_, err := client.AppEngine.ListGroups(testRealmName)
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) {
+		t.Fatal("Expected an AstarteAPIError, got", err)
+	}
+	if apiError.RetryAfter != 2*time.Second {
+		t.Error("Unexpected RetryAfter", apiError.RetryAfter)
+	}
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	policy := retryPolicy{baseDelay: time.Second, maxDelay: 30 * time.Second}
+	for _, attempt := range []int{0, 4, 5, 16, 40, 63, 64, 1000} {
+		delay := policy.backoff(attempt)
+		if delay < 0 || delay > policy.maxDelay {
+			t.Errorf("attempt %d: delay %v out of [0, %v]", attempt, delay, policy.maxDelay)
+		}
+	}
+
+	// Without an explicit maximum, the default one applies
+	if delay := (retryPolicy{baseDelay: time.Second}).backoff(100); delay > defaultMaxRetryDelay {
+		t.Errorf("delay %v exceeds %v", delay, defaultMaxRetryDelay)
+	}
+}
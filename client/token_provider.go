@@ -0,0 +1,112 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	jwt "github.com/cristalhq/jwt/v3"
+)
+
+// tokenRefreshMargin is how long before its expiry a cached token is considered stale, so that it doesn't
+// expire while a request is in flight
+const tokenRefreshMargin = 30 * time.Second
+
+// TokenProvider returns the token the Client should use for its next request
+type TokenProvider func(ctx context.Context) (string, error)
+
+// cachingTokenProvider caches the tokens returned by provider until their expiry. Tokens which are not JWTs
+// or which have no expiry are not cached, and provider is called again on the next request
+type cachingTokenProvider struct {
+	provider TokenProvider
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (p *cachingTokenProvider) getToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Now().Add(tokenRefreshMargin).Before(p.expiry) {
+		return p.token, nil
+	}
+
+	token, err := p.provider(ctx)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", errors.New("the token provider returned an empty token")
+	}
+	p.token = token
+	p.expiry = tokenExpiry(token)
+	return token, nil
+}
+
+// invalidate drops the cached token, e.g. because Astarte rejected it
+func (p *cachingTokenProvider) invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = ""
+}
+
+// RealmTokenProvider returns the token the Client should use for its next request to realm. All requests to
+// Housekeeping, including the ones about a specific Realm such as GetRealm, ask for the token of realm "",
+// which must be a Housekeeping token.
+type RealmTokenProvider func(realm string) (string, error)
+
+// realmTokenProvider keeps a cachingTokenProvider for each Realm, so that tokens are cached per Realm
+type realmTokenProvider struct {
+	provider RealmTokenProvider
+
+	mu     sync.Mutex
+	realms map[string]*cachingTokenProvider
+}
+
+func (p *realmTokenProvider) forRealm(realm string) *cachingTokenProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.realms == nil {
+		p.realms = map[string]*cachingTokenProvider{}
+	}
+	realmProvider, ok := p.realms[realm]
+	if !ok {
+		realmProvider = &cachingTokenProvider{provider: func(context.Context) (string, error) {
+			return p.provider(realm)
+		}}
+		p.realms[realm] = realmProvider
+	}
+	return realmProvider
+}
+
+// tokenExpiry returns the expiry of token if it is a JWT with an exp claim, or the zero time otherwise
+func tokenExpiry(token string) time.Time {
+	parsed, err := jwt.ParseString(token)
+	if err != nil {
+		return time.Time{}
+	}
+	claims := jwt.StandardClaims{}
+	if err := json.Unmarshal(parsed.RawClaims(), &claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
@@ -0,0 +1,199 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/auth"
+)
+
+func getTokenProviderTestContext(t *testing.T, provider TokenProvider, rejectFirstRequest bool) (*Client, *[]string, func()) {
+	receivedTokens := []string{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		token := req.Header.Get("Authorization")
+		receivedTokens = append(receivedTokens, token)
+		// Reject the first request, as if the token had been revoked
+		if len(receivedTokens) == 1 && rejectFirstRequest {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":{"detail":"Unauthorized"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}, WithTokenProvider(provider))
+	return client, &receivedTokens, server.Close
+}
+
+func TestTokenProviderCachesJWT(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	calls := 0
+	client, receivedTokens, closeServer := getTokenProviderTestContext(t, func(ctx context.Context) (string, error) {
+		calls++
+		return auth.GenerateAstarteJWT(key, auth.AstarteClaims{}, time.Hour)
+	}, false)
+	defer closeServer()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.RealmManagement.ListInterfaces(testRealmName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Error("Expected the token provider to be called once, got", calls)
+	}
+	if (*receivedTokens)[0] == "Bearer "+testTokenValue || (*receivedTokens)[0] != (*receivedTokens)[2] {
+		t.Error("The provided token was not used", *receivedTokens)
+	}
+}
+
+func TestTokenProviderRefreshesExpiringJWT(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	calls := 0
+	client, _, closeServer := getTokenProviderTestContext(t, func(ctx context.Context) (string, error) {
+		calls++
+		// Expires within tokenRefreshMargin, so it must never be reused
+		return auth.GenerateAstarteJWT(key, auth.AstarteClaims{}, time.Second)
+	}, false)
+	defer closeServer()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.RealmManagement.ListInterfaces(testRealmName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Error("Expected the token provider to be called twice, got", calls)
+	}
+}
+
+func TestTokenProviderInvalidatesRejectedToken(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	calls := 0
+	client, _, closeServer := getTokenProviderTestContext(t, func(ctx context.Context) (string, error) {
+		calls++
+		return auth.GenerateAstarteJWT(key, auth.AstarteClaims{}, time.Hour)
+	}, true)
+	defer closeServer()
+
+	if _, err := client.RealmManagement.ListInterfaces(testRealmName); err == nil {
+		t.Fatal("Expected the first request to be rejected")
+	}
+	if _, err := client.RealmManagement.ListInterfaces(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Error("Expected the rejected token to be refreshed, got", calls, "calls")
+	}
+}
+
+func TestTokenProviderErrors(t *testing.T) {
+	client, receivedTokens, closeServer := getTokenProviderTestContext(t, func(ctx context.Context) (string, error) {
+		return "", fmt.Errorf("no token")
+	}, false)
+	defer closeServer()
+
+	if _, err := client.RealmManagement.ListInterfaces(testRealmName); err == nil || err.Error() != "no token" {
+		t.Error("Expected the token provider error, got", err)
+	}
+	if len(*receivedTokens) != 0 {
+		t.Error("No request should have been sent")
+	}
+}
+
+func TestRealmTokenProvider(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tokens := map[string]string{}
+	calls := map[string]int{}
+	receivedTokens := map[string][]string{}
+	rejected := false
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		realm := strings.Split(strings.TrimPrefix(req.URL.Path, "/realmmanagement/v1/"), "/")[0]
+		receivedTokens[realm] = append(receivedTokens[realm], req.Header.Get("Authorization"))
+		// Reject the first request to the other Realm, as if its token had been revoked
+		if realm == "other" && !rejected {
+			rejected = true
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":{"detail":"Unauthorized"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}, WithRealmTokenProvider(func(realm string) (string, error) {
+		calls[realm]++
+		token, err := auth.GenerateAstarteJWT(key, auth.AstarteClaims{}, time.Hour)
+		tokens[realm] = token
+		return token, err
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		for _, realm := range []string{testRealmName, "other"} {
+			// The first request to the other Realm fails, the Client must fetch a new token for the next one
+			_, _ = client.RealmManagement.ListInterfaces(realm)
+		}
+	}
+
+	if calls[testRealmName] != 1 || calls["other"] != 2 {
+		t.Error("Unexpected calls to the token provider", calls)
+	}
+	for realm, received := range receivedTokens {
+		if received[len(received)-1] != "Bearer "+tokens[realm] {
+			t.Errorf("Wrong token for Realm %s: %v", realm, received)
+		}
+	}
+	if tokens[testRealmName] == tokens["other"] {
+		t.Error("Realms should not share their token")
+	}
+}
+
+func TestRealmTokenProviderHousekeeping(t *testing.T) {
+	var receivedTokens []string
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		receivedTokens = append(receivedTokens, req.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"data":{"realm_name":"test","jwt_public_key_pem":""}}`)
+	}, WithRealmTokenProvider(func(realm string) (string, error) {
+		if realm == "" {
+			return "housekeeping-token", nil
+		}
+		return "realm-token", nil
+	}))
+	defer server.Close()
+
+	// Requests about a Realm still require the Housekeeping token
+	if _, err := client.Housekeeping.GetRealm(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if len(receivedTokens) != 1 || receivedTokens[0] != "Bearer housekeeping-token" {
+		t.Error("Wrong token for Housekeeping", receivedTokens)
+	}
+}
+
+func TestRealmTokenProviderExcludesTokenProvider(t *testing.T) {
+	realmProvider := WithRealmTokenProvider(func(realm string) (string, error) { return "", nil })
+	provider := WithTokenProvider(func(ctx context.Context) (string, error) { return "", nil })
+	if _, err := NewClient("http://localhost", nil, realmProvider, provider); err == nil {
+		t.Error("Expected an error combining WithRealmTokenProvider and WithTokenProvider")
+	}
+	if _, err := NewClient("http://localhost", nil, provider, realmProvider); err == nil {
+		t.Error("Expected an error combining WithTokenProvider and WithRealmTokenProvider")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/astarte-platform/astarte-go/misc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the Tracer used by the Client
+const tracerName = "github.com/astarte-platform/astarte-go/client"
+
+const (
+	// astarteServiceKey is the attribute holding the Astarte service a span refers to, e.g. "appengine"
+	astarteServiceKey = attribute.Key("astarte.service")
+	// astarteRealmKey is the attribute holding the Realm a span refers to, if any
+	astarteRealmKey = attribute.Key("astarte.realm")
+)
+
+var noopTracer = trace.NewNoopTracerProvider().Tracer(tracerName)
+
+// startRequestSpan starts the span tracing req to service, which covers all of its attempts, and returns
+// a copy of req carrying it in its context.
+func (c *Client) startRequestSpan(req *http.Request, service misc.AstarteService, realm string) (*http.Request, trace.Span) {
+	tracer := c.tracer
+	if tracer == nil {
+		tracer = noopTracer
+	}
+
+	attributes := []attribute.KeyValue{
+		semconv.HTTPMethodKey.String(req.Method),
+		semconv.HTTPURLKey.String(req.URL.String()),
+		astarteServiceKey.String(service.String()),
+	}
+	if realm != "" {
+		attributes = append(attributes, astarteRealmKey.String(realm))
+	}
+
+	ctx, span := tracer.Start(req.Context(), fmt.Sprintf("%s %s", service, req.Method),
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+	return req.WithContext(ctx), span
+}
+
+// endRequestSpan records the outcome of a request on span, and ends it. resp might be nil.
+func endRequestSpan(span trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// describeRequestURL returns the Astarte service requestURL belongs to, and the Realm it targets, if any.
+// Unknown is returned for URLs which don't belong to any of the services of the Client.
+func (c *Client) describeRequestURL(requestURL *url.URL) (misc.AstarteService, string) {
+	services := map[misc.AstarteService]*url.URL{}
+	if c.AppEngine != nil {
+		services[misc.AppEngine] = c.AppEngine.appEngineURL
+	}
+	if c.Flow != nil {
+		services[misc.Flow] = c.Flow.flowURL
+	}
+	if c.Housekeeping != nil {
+		services[misc.Housekeeping] = c.Housekeeping.housekeepingURL
+	}
+	if c.Pairing != nil {
+		services[misc.Pairing] = c.Pairing.pairingURL
+	}
+	if c.RealmManagement != nil {
+		services[misc.RealmManagement] = c.RealmManagement.realmManagementURL
+	}
+
+	for service, serviceURL := range services {
+		if serviceURL.Host != requestURL.Host {
+			continue
+		}
+		apiPrefix := path.Join("/", serviceURL.Path, "v1") + "/"
+		if !strings.HasPrefix(requestURL.Path, apiPrefix) {
+			continue
+		}
+
+		// All APIs are scoped by Realm, except for Housekeeping which manages the Realms themselves
+		segments := strings.Split(strings.TrimPrefix(requestURL.Path, apiPrefix), "/")
+		if service == misc.Housekeeping {
+			if len(segments) > 1 && segments[0] == "realms" {
+				return service, segments[1]
+			}
+			return service, ""
+		}
+		return service, segments[0]
+	}
+	return misc.Unknown, ""
+}
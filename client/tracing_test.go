@@ -0,0 +1,146 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/misc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type recordedSpan struct {
+	trace.Span
+	name       string
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	for _, a := range attributes {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func (s *recordedSpan) End(options ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+// recordingTracer is both a TracerProvider and a Tracer, which records the spans it starts
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return t
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{Span: trace.SpanFromContext(ctx), name: name, attributes: map[attribute.Key]attribute.Value{}}
+	config := trace.NewSpanStartConfig(options...)
+	span.SetAttributes(config.Attributes()...)
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	tracer := &recordingTracer{}
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/appengine/v1/test/groups" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{"group"}})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": map[string]string{"detail": "Not found"}})
+	}, WithTracerProvider(tracer))
+	defer server.Close()
+
+	if _, err := client.AppEngine.ListGroups(testRealmName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Housekeeping.GetRealm("other"); err == nil {
+		t.Fatal("Expected an error for a missing realm")
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(tracer.spans))
+	}
+	expected := []struct {
+		name   string
+		realm  string
+		status int64
+		code   codes.Code
+	}{
+		{"appengine GET", testRealmName, http.StatusOK, codes.Unset},
+		{"housekeeping GET", "other", http.StatusNotFound, codes.Error},
+	}
+	for i, e := range expected {
+		span := tracer.spans[i]
+		if span.name != e.name {
+			t.Errorf("Unexpected span name %s, expected %s", span.name, e.name)
+		}
+		if !span.ended {
+			t.Errorf("Span %s was not ended", span.name)
+		}
+		if realm := span.attributes[astarteRealmKey].AsString(); realm != e.realm {
+			t.Errorf("Unexpected realm %s for span %s", realm, span.name)
+		}
+		if status := span.attributes["http.status_code"].AsInt64(); status != e.status {
+			t.Errorf("Unexpected status %d for span %s", status, span.name)
+		}
+		if span.attributes["http.method"].AsString() != http.MethodGet {
+			t.Errorf("Unexpected method for span %s", span.name)
+		}
+		if span.status != e.code {
+			t.Errorf("Unexpected status code %v for span %s", span.status, span.name)
+		}
+	}
+}
+
+func TestDescribeRequestURL(t *testing.T) {
+	client, server := getTestContext(t)
+	defer server.Close()
+
+	testCases := []struct {
+		path    string
+		service misc.AstarteService
+		realm   string
+	}{
+		{"/appengine/v1/test/devices", misc.AppEngine, "test"},
+		{"/realmmanagement/v1/test/interfaces", misc.RealmManagement, "test"},
+		{"/pairing/v1/test/agent/devices", misc.Pairing, "test"},
+		{"/flow/v1/test/flows", misc.Flow, "test"},
+		{"/housekeeping/v1/realms/test", misc.Housekeeping, "test"},
+		{"/housekeeping/v1/realms", misc.Housekeeping, ""},
+		{"/other/v1/test", misc.Unknown, ""},
+	}
+	for _, tc := range testCases {
+		requestURL, _ := client.baseURL.Parse(tc.path)
+		service, realm := client.describeRequestURL(requestURL)
+		if service != tc.service || realm != tc.realm {
+			t.Errorf("Unexpected service %v and realm %s for %s", service, realm, tc.path)
+		}
+	}
+}
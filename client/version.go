@@ -0,0 +1,103 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// minTriggerDeliveryPoliciesVersion is the first Astarte version supporting Trigger Delivery Policies
+var minTriggerDeliveryPoliciesVersion = semver.MustParse("1.1.0")
+
+// GetAstarteVersion returns the version of the Astarte instance the Client talks to, as reported by its version
+// endpoint. The version is queried on the Realm Management, AppEngine or Pairing API of realm, whichever is
+// available in the Client, or on Housekeeping if realm is empty. The result is cached on the Client, so that
+// subsequent calls don't perform any request. Astarte versions predating the version endpoint reply with a 404,
+// in which case the returned error wraps ErrUnsupportedByAstarte: this outcome is cached as well.
+func (c *Client) GetAstarteVersion(ctx context.Context, realm string) (*semver.Version, error) {
+	c.versionLock.Lock()
+	version, versionErr := c.astarteVersion, c.astarteVersionErr
+	c.versionLock.Unlock()
+	if version != nil || versionErr != nil {
+		return version, versionErr
+	}
+
+	var callURL *url.URL
+	switch {
+	case realm == "" && c.Housekeeping != nil:
+		callURL, _ = url.Parse(c.Housekeeping.housekeepingURL.String())
+		callURL.Path = path.Join(callURL.Path, "/v1/version")
+	case realm != "" && c.RealmManagement != nil:
+		callURL, _ = url.Parse(c.RealmManagement.realmManagementURL.String())
+	case realm != "" && c.AppEngine != nil:
+		callURL, _ = url.Parse(c.AppEngine.appEngineURL.String())
+	case realm != "" && c.Pairing != nil:
+		callURL, _ = url.Parse(c.Pairing.pairingURL.String())
+	default:
+		return nil, errors.New("no Astarte service available to query the version")
+	}
+	if realm != "" {
+		callURL.Path = path.Join(callURL.Path, fmt.Sprintf("/v1/%s/version", realm))
+	}
+
+	// The request is performed without holding versionLock: concurrent callers might query the version more
+	// than once, but the result is the same
+	rawVersion := ""
+	if err := c.genericJSONDataAPIGET(ctx, &rawVersion, callURL.String(), 200); err != nil {
+		err = withErrorCause(err, http.StatusNotFound, ErrUnsupportedByAstarte)
+		if errors.Is(err, ErrUnsupportedByAstarte) {
+			c.versionLock.Lock()
+			c.astarteVersionErr = err
+			c.versionLock.Unlock()
+		}
+		return nil, err
+	}
+	version, err := semver.NewVersion(rawVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Astarte version %s: %w", rawVersion, err)
+	}
+	c.versionLock.Lock()
+	c.astarteVersion = version
+	c.versionLock.Unlock()
+
+	return version, nil
+}
+
+// requireAstarteVersion returns an error wrapping ErrUnsupportedByAstarte if Astarte is older than minVersion,
+// including when it predates the version endpoint. Any other error in determining the version is returned as is.
+// Prereleases of minVersion, such as release candidates, are considered to support the feature already.
+func (c *Client) requireAstarteVersion(ctx context.Context, realm string, minVersion *semver.Version, feature string) error {
+	version, err := c.GetAstarteVersion(ctx, realm)
+	switch {
+	case errors.Is(err, ErrUnsupportedByAstarte):
+		return fmt.Errorf("%w: %s require Astarte %s or later", ErrUnsupportedByAstarte, feature, minVersion)
+	case err != nil:
+		return err
+	}
+
+	// Semver orders 1.1.0-rc.0 before 1.1.0, while features are shipped with the release candidates
+	release, _ := version.SetPrerelease("")
+	if release.LessThan(minVersion) {
+		return fmt.Errorf("%w: %s require Astarte %s or later, found %s", ErrUnsupportedByAstarte, feature, minVersion, version)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/policies"
+)
+
+// getVersionTestContext returns a Client talking to an Astarte reporting version, or lacking the version
+// endpoint if version is empty, along with the number of version requests it received.
+func getVersionTestContext(t *testing.T, version string) (*Client, *httptest.Server, *int) {
+	versionCalls := 0
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case fmt.Sprintf("/realmmanagement/v1/%s/version", testRealmName), "/housekeeping/v1/version":
+			versionCalls++
+			if version == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"data":"%s"}`, version)
+		case fmt.Sprintf("/realmmanagement/v1/%s/policies", testRealmName):
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Error("Unexpected request", req.URL.Path)
+		}
+	})
+
+	return client, server, &versionCalls
+}
+
+func TestGetAstarteVersion(t *testing.T) {
+	client, server, versionCalls := getVersionTestContext(t, "1.1.0-rc.0")
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		version, err := client.GetAstarteVersion(context.Background(), testRealmName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version.String() != "1.1.0-rc.0" {
+			t.Error("Unexpected version", version)
+		}
+	}
+	if *versionCalls != 1 {
+		t.Error("Version was not cached, calls:", *versionCalls)
+	}
+
+	client, server, _ = getVersionTestContext(t, "1.2.0")
+	defer server.Close()
+	if version, err := client.GetAstarteVersion(context.Background(), ""); err != nil || version.Major() != 1 || version.Minor() != 2 {
+		t.Error("Unexpected Housekeeping version", version, err)
+	}
+
+	client, server, versionCalls = getVersionTestContext(t, "")
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetAstarteVersion(context.Background(), testRealmName); !errors.Is(err, ErrUnsupportedByAstarte) {
+			t.Error("Expected ErrUnsupportedByAstarte, got", err)
+		}
+	}
+	if *versionCalls != 1 {
+		t.Error("Missing version endpoint was not cached, calls:", *versionCalls)
+	}
+}
+
+func TestTriggerDeliveryPoliciesVersionGate(t *testing.T) {
+	policy := policies.Policy{
+		Name:            "retry",
+		MaximumCapacity: 100,
+		ErrorHandlers: []policies.ErrorHandler{
+			{On: policies.ErrorRange{Keyword: policies.AnyError}, Strategy: policies.DiscardStrategy},
+		},
+	}
+
+	testCases := []struct {
+		version   string
+		supported bool
+	}{
+		{"1.0.4", false},
+		{"1.0.4-rc.0", false},
+		{"1.1.0-rc.0", true},
+		{"1.1.0", true},
+		{"1.2.0-rc.1", true},
+		// Versions predating the version endpoint are older than any minimum version
+		{"", false},
+	}
+	for _, tc := range testCases {
+		client, server, _ := getVersionTestContext(t, tc.version)
+		err := client.RealmManagement.InstallTriggerDeliveryPolicy(testRealmName, policy)
+		server.Close()
+		if tc.supported && err != nil {
+			t.Errorf("Unexpected error with version %s: %v", tc.version, err)
+		} else if !tc.supported && !errors.Is(err, ErrUnsupportedByAstarte) {
+			t.Errorf("Expected ErrUnsupportedByAstarte with version %s, got %v", tc.version, err)
+		}
+	}
+}
+
+func TestTriggerDeliveryPoliciesVersionError(t *testing.T) {
+	client, server := getTestContextWithHandler(t, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == fmt.Sprintf("/realmmanagement/v1/%s/policies", testRealmName) {
+			t.Error("Policy was installed although the Astarte version could not be determined")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer server.Close()
+
+	policy := policies.Policy{
+		Name:            "retry",
+		MaximumCapacity: 100,
+		ErrorHandlers: []policies.ErrorHandler{
+			{On: policies.ErrorRange{Keyword: policies.AnyError}, Strategy: policies.DiscardStrategy},
+		},
+	}
+	err := client.RealmManagement.InstallTriggerDeliveryPolicy(testRealmName, policy)
+	var apiError *AstarteAPIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusInternalServerError {
+		t.Error("Expected the version error, got", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deviceid provides helpers to generate and validate Astarte Device IDs.
+// An Astarte Device ID is a 128 bit UUID encoded in base64 URL-safe format without padding, which always
+// results in a 22 characters long string.
+package deviceid
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// The last character encodes only 2 bits of the UUID, the remaining 4 must be zero
+var deviceIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{21}[AQgw]$`)
+
+// GenerateDeviceID returns the Device ID deterministically derived from namespace and payload, usually a
+// hardware identifier such as a MAC address or a serial number. The Device ID is computed as the UUIDv5 of
+// payload in namespace, the same way Astarte SDKs do.
+func GenerateDeviceID(namespace uuid.UUID, payload []byte) string {
+	return encodeUUID(uuid.NewSHA1(namespace, payload))
+}
+
+// GenerateRandomDeviceID returns a Device ID made of 128 cryptographically random bits. It is mostly useful for
+// tests and simulators, as the Device ID can't be derived again from any hardware identifier.
+func GenerateRandomDeviceID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ValidateDeviceID returns whether id is a well formed Astarte Device ID
+func ValidateDeviceID(id string) bool {
+	return deviceIDRegexp.MatchString(id)
+}
+
+func encodeUUID(u uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(u[:])
+}
@@ -0,0 +1,69 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceid
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGenerateDeviceID(t *testing.T) {
+	namespace := uuid.MustParse("f79ad91f-c638-4889-ae74-9d001a3b4cf8")
+	deviceID := GenerateDeviceID(namespace, []byte("myidentifierdata"))
+	if deviceID != GenerateDeviceID(namespace, []byte("myidentifierdata")) {
+		t.Error("Device ID generation is not deterministic")
+	}
+	if deviceID == GenerateDeviceID(namespace, []byte("otheridentifierdata")) {
+		t.Error("Different payloads generated the same Device ID")
+	}
+	if !ValidateDeviceID(deviceID) {
+		t.Error("Generated an invalid Device ID", deviceID)
+	}
+}
+
+func TestGenerateRandomDeviceID(t *testing.T) {
+	generated := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		deviceID, err := GenerateRandomDeviceID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ValidateDeviceID(deviceID) {
+			t.Error("Generated an invalid Device ID", deviceID)
+		}
+		if generated[deviceID] {
+			t.Error("Generated a duplicate Device ID", deviceID)
+		}
+		generated[deviceID] = true
+	}
+}
+
+func TestValidateDeviceID(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"2TBn-jNESuuHamE2Zo1anA":   true,
+		"f0VMRgIBAQAAAAAAAAAAAA":   true,
+		"2TBn-jNESuuHamE2Zo1anB":   false,
+		"2TBn-jNESuuHamE2Zo1an":    false,
+		"2TBn-jNESuuHamE2Zo1anAA":  false,
+		"2TBn+jNESuuHamE2Zo1anA":   false,
+		"2TBn-jNESuuHamE2Zo1anA==": false,
+		"":                         false,
+	} {
+		if ValidateDeviceID(id) != valid {
+			t.Errorf("Expected ValidateDeviceID(%q) to be %v", id, valid)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flow models the resources of Astarte Flow: Flows, which are running instances of a Pipeline, and
+// the Pipelines and Blocks they are built from.
+package flow
+
+import (
+	"errors"
+	"regexp"
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// Flow represents an Astarte Flow, i.e. a running instance of a Pipeline
+type Flow struct {
+	Name string `json:"name"`
+	// Pipeline is the name of the Pipeline the Flow instantiates
+	Pipeline string `json:"pipeline"`
+	// Config holds the values of the parameters of the Pipeline, which are referenced in its source
+	// as ${config.<key>}
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// Validate returns an error if the Flow is not well formed
+func (f Flow) Validate() error {
+	if !nameRegexp.MatchString(f.Name) {
+		return errors.New("flow name must contain only letters, digits and dashes, and not begin or end with a dash")
+	}
+	if f.Pipeline == "" {
+		return errors.New("flow pipeline must not be empty")
+	}
+	return nil
+}
+
+// Pipeline represents an Astarte Flow Pipeline, a reusable chain of Blocks Flows can be created from
+type Pipeline struct {
+	Name string `json:"name"`
+	// Source is the definition of the Pipeline in the Astarte Flow pipeline language, e.g.
+	// "random_source | json_mapper | http_sink.url(${config.url})"
+	Source      string `json:"source"`
+	Description string `json:"description,omitempty"`
+	// Schema is the JSON Schema of the Config of the Flows created from the Pipeline, if any
+	Schema map[string]interface{} `json:"schema,omitempty"`
+}
+
+// Validate returns an error if the Pipeline is not well formed
+func (p Pipeline) Validate() error {
+	if !nameRegexp.MatchString(p.Name) {
+		return errors.New("pipeline name must contain only letters, digits and dashes, and not begin or end with a dash")
+	}
+	if p.Source == "" {
+		return errors.New("pipeline source must not be empty")
+	}
+	return nil
+}
+
+// BlockType represents the role of a Block in a Pipeline
+type BlockType string
+
+const (
+	// ProducerBlock is a Block generating messages, placed at the beginning of a Pipeline
+	ProducerBlock BlockType = "producer"
+	// ConsumerBlock is a Block consuming messages, placed at the end of a Pipeline
+	ConsumerBlock BlockType = "consumer"
+	// ProducerConsumerBlock is a Block transforming messages, placed in the middle of a Pipeline
+	ProducerConsumerBlock BlockType = "producer_consumer"
+)
+
+// IsValid returns an error if BlockType does not represent a valid Block type
+func (t BlockType) IsValid() error {
+	switch t {
+	case ProducerBlock, ConsumerBlock, ProducerConsumerBlock:
+		return nil
+	}
+	return errors.New("invalid Block type")
+}
+
+// Block represents a custom Astarte Flow Block, defined as a Pipeline of other Blocks
+type Block struct {
+	Name   string    `json:"name"`
+	Source string    `json:"source"`
+	Type   BlockType `json:"type"`
+	// Schema is the JSON Schema of the properties of the Block
+	Schema map[string]interface{} `json:"schema,omitempty"`
+}
+
+// Validate returns an error if the Block is not well formed
+func (b Block) Validate() error {
+	if !nameRegexp.MatchString(b.Name) {
+		return errors.New("block name must contain only letters, digits and dashes, and not begin or end with a dash")
+	}
+	if b.Source == "" {
+		return errors.New("block source must not be empty")
+	}
+	return b.Type.IsValid()
+}
@@ -0,0 +1,45 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flow
+
+import "testing"
+
+func TestFlowValidate(t *testing.T) {
+	if err := (Flow{Name: "room-temperatures", Pipeline: "average"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, invalid := range []Flow{
+		{Name: "", Pipeline: "average"},
+		{Name: "room_temperatures", Pipeline: "average"},
+		{Name: "room-temperatures-", Pipeline: "average"},
+		{Name: "room-temperatures"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Error("Expected an error validating", invalid)
+		}
+	}
+}
+
+func TestBlockValidate(t *testing.T) {
+	if err := (Block{Name: "average", Source: "container", Type: ConsumerBlock}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Block{Name: "average", Source: "container", Type: "sink"}).Validate(); err == nil {
+		t.Error("Expected an error for an invalid Block type")
+	}
+	if err := (Pipeline{Name: "average"}).Validate(); err == nil {
+		t.Error("Expected an error for a Pipeline without source")
+	}
+}
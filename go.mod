@@ -3,7 +3,14 @@ module github.com/astarte-platform/astarte-go
 go 1.13
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/cristalhq/jwt/v3 v3.0.11
+	github.com/eclipse/paho.mqtt.golang v1.3.5
 	github.com/google/uuid v1.2.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/iancoleman/orderedmap v0.2.0
+	github.com/santhosh-tekuri/jsonschema v1.2.4
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
 )
@@ -15,10 +15,14 @@
 package interfaces
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"regexp"
 	"strings"
 )
 
@@ -41,6 +45,17 @@ func (t AstarteInterfaceType) IsValid() error {
 	return errors.New("invalid Astarte Interface type")
 }
 
+// MarshalJSON marshals the enum value to a quoted json string, returning an error if it is not valid. An empty
+// value, representing a missing field, is marshaled as is.
+func (t AstarteInterfaceType) MarshalJSON() ([]byte, error) {
+	if t != "" {
+		if err := t.IsValid(); err != nil {
+			return nil, fmt.Errorf("'%v' is not a valid Astarte Interface Type", string(t))
+		}
+	}
+	return json.Marshal(string(t))
+}
+
 // UnmarshalJSON unmashals a quoted json string to the enum value
 func (t *AstarteInterfaceType) UnmarshalJSON(b []byte) error {
 	var j string
@@ -74,6 +89,17 @@ func (o AstarteInterfaceOwnership) IsValid() error {
 	return errors.New("invalid Astarte Interface ownership")
 }
 
+// MarshalJSON marshals the enum value to a quoted json string, returning an error if it is not valid. An empty
+// value, representing a missing field, is marshaled as is.
+func (o AstarteInterfaceOwnership) MarshalJSON() ([]byte, error) {
+	if o != "" {
+		if err := o.IsValid(); err != nil {
+			return nil, fmt.Errorf("'%v' is not a valid Astarte Interface Ownership", string(o))
+		}
+	}
+	return json.Marshal(string(o))
+}
+
 // UnmarshalJSON unmashals a quoted json string to the enum value
 func (o *AstarteInterfaceOwnership) UnmarshalJSON(b []byte) error {
 	var j string
@@ -107,6 +133,17 @@ func (a AstarteInterfaceAggregation) IsValid() error {
 	return errors.New("invalid Astarte Interface aggregation")
 }
 
+// MarshalJSON marshals the enum value to a quoted json string, returning an error if it is not valid. An empty
+// value, representing a missing field, is marshaled as is.
+func (a AstarteInterfaceAggregation) MarshalJSON() ([]byte, error) {
+	if a != "" {
+		if err := a.IsValid(); err != nil {
+			return nil, fmt.Errorf("'%v' is not a valid Astarte Interface Aggregation", string(a))
+		}
+	}
+	return json.Marshal(string(a))
+}
+
 // UnmarshalJSON unmashals a quoted json string to the enum value
 func (a *AstarteInterfaceAggregation) UnmarshalJSON(b []byte) error {
 	var j string
@@ -304,6 +341,16 @@ type AstarteInterfaceMapping struct {
 	AllowUnset              bool                                  `json:"allow_unset,omitempty"`
 	Description             string                                `json:"description,omitempty"`
 	Documentation           string                                `json:"doc,omitempty"`
+
+	// defaulted tracks the fields set by EnsureInterfaceDefaults, so that they are not marshaled unless changed
+	defaulted mappingDefaults
+}
+
+// mappingDefaults tracks which fields of an AstarteInterfaceMapping were missing and have been defaulted
+type mappingDefaults struct {
+	reliability             bool
+	retention               bool
+	databaseRetentionPolicy bool
 }
 
 // AstarteInterface represents an Astarte Interface
@@ -319,15 +366,47 @@ type AstarteInterface struct {
 	Description       string                      `json:"description,omitempty"`
 	Documentation     string                      `json:"doc,omitempty"`
 	Mappings          []AstarteInterfaceMapping   `json:"mappings"`
+
+	// defaultedAggregation is set by EnsureInterfaceDefaults when Aggregation was missing and has been defaulted
+	defaultedAggregation bool
+}
+
+// InterfaceVersion represents the major and minor version of an Interface, e.g. in a Device introspection
+type InterfaceVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
 }
 
-// ParseInterfaceFromFile is a convenience function to call ParseInterface with a file as input
+// ParseInterfaceFromFile is a convenience function to call ParseInterfaceFrom with a file as input
 func ParseInterfaceFromFile(interfaceFile string) (AstarteInterface, error) {
-	b, err := ioutil.ReadFile(interfaceFile)
+	f, err := os.Open(interfaceFile)
 	if err != nil {
 		return AstarteInterface{}, err
 	}
-	return ParseInterface(b)
+	defer f.Close()
+	return ParseInterfaceFrom(f)
+}
+
+// ParseInterfaceFrom decodes an interface from reader, validates it and returns an AstarteInterface object with all
+// defaults set when successful. Unlike ParseInterface, decoding and validation errors report the path of the
+// offending field: when the interface is invalid, the returned error is a ValidationErrors.
+func ParseInterfaceFrom(reader io.Reader) (AstarteInterface, error) {
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return AstarteInterface{}, err
+	}
+
+	// Decode enums as plain strings, so that invalid values are reported by Validate along with their field path
+	raw := rawAstarteInterface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return AstarteInterface{}, describeJSONError(b, err)
+	}
+
+	astarteInterface := raw.toAstarteInterface()
+	if errs := Validate(astarteInterface); errs != nil {
+		return AstarteInterface{}, ValidationErrors(errs)
+	}
+	return EnsureInterfaceDefaults(astarteInterface), nil
 }
 
 // ParseInterfaceFromString is a convenience function to call ParseInterface with a string as input
@@ -354,18 +433,22 @@ func EnsureInterfaceDefaults(astarteInterface AstarteInterface) AstarteInterface
 	// Ensure we have all defaults set
 	if err := astarteInterface.Aggregation.IsValid(); err != nil {
 		astarteInterface.Aggregation = IndividualAggregation
+		astarteInterface.defaultedAggregation = true
 	}
 
 	subsMapping := []AstarteInterfaceMapping{}
 	for _, v := range astarteInterface.Mappings {
 		if err := v.Reliability.IsValid(); err != nil {
 			v.Reliability = UnreliableReliability
+			v.defaulted.reliability = true
 		}
 		if err := v.Retention.IsValid(); err != nil {
 			v.Retention = DiscardRetention
+			v.defaulted.retention = true
 		}
 		if err := v.DatabaseRetentionPolicy.IsValid(); err != nil {
 			v.DatabaseRetentionPolicy = NoTTL
+			v.defaulted.databaseRetentionPolicy = true
 		}
 		subsMapping = append(subsMapping, v)
 	}
@@ -374,6 +457,55 @@ func EnsureInterfaceDefaults(astarteInterface AstarteInterface) AstarteInterface
 	return astarteInterface
 }
 
+// MarshalJSON marshals the interface with its fields in a stable order, as declared in AstarteInterface, and its
+// mappings in the order they are declared in. Optional fields are omitted when empty, and mappings is always an
+// array, even when the interface has none. Fields which were missing when the interface was parsed, and have been
+// set to their default by EnsureInterfaceDefaults, are omitted as well unless they have been changed since.
+func (a AstarteInterface) MarshalJSON() ([]byte, error) {
+	// Avoid recursing into MarshalJSON
+	type astarteInterface AstarteInterface
+	if a.defaultedAggregation && a.Aggregation == IndividualAggregation {
+		a.Aggregation = ""
+	}
+	mappings := make([]AstarteInterfaceMapping, 0, len(a.Mappings))
+	for _, m := range a.Mappings {
+		if m.defaulted.reliability && m.Reliability == UnreliableReliability {
+			m.Reliability = ""
+		}
+		if m.defaulted.retention && m.Retention == DiscardRetention {
+			m.Retention = ""
+		}
+		if m.defaulted.databaseRetentionPolicy && m.DatabaseRetentionPolicy == NoTTL {
+			m.DatabaseRetentionPolicy = ""
+		}
+		mappings = append(mappings, m)
+	}
+	a.Mappings = mappings
+	// Leave HTML escaping to the caller: json.Marshal escapes the result anyway, while MarshalInterface must not
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(astarteInterface(a)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalInterface encodes astarteInterface in a canonical form, suitable to be stored in a file and kept under
+// version control: it is indented with 4 spaces, does not escape HTML characters found e.g. in doc, and ends with
+// a newline. Defaults missing from the parsed document are not added. Parsing the result with ParseInterface and
+// marshaling it again yields the very same bytes.
+func MarshalInterface(astarteInterface AstarteInterface) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(astarteInterface); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // IsParametric returns whether the interface has at least one parametric mapping
 func (a *AstarteInterface) IsParametric() bool {
 	for _, v := range a.Mappings {
@@ -383,3 +515,145 @@ func (a *AstarteInterface) IsParametric() bool {
 	}
 	return false
 }
+
+// MatchPath returns whether path matches the endpoint of the mapping, and if so the values taken by its parameters,
+// keyed by name. e.g. "/sensors/temp/value" matches "/sensors/%{sensor_id}/value" with {"sensor_id": "temp"}.
+func (m AstarteInterfaceMapping) MatchPath(path string) (map[string]string, bool) {
+	endpointTokens := strings.Split(m.Endpoint, "/")
+	pathTokens := strings.Split(path, "/")
+	if len(endpointTokens) != len(pathTokens) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, token := range endpointTokens {
+		if name, ok := endpointParameter(token); ok {
+			if pathTokens[i] == "" {
+				return nil, false
+			}
+			params[name] = pathTokens[i]
+		} else if pathTokens[i] != token {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// ExpandPath builds a concrete path from the endpoint of the mapping, replacing each of its parameters with the
+// value it has in params. An error is returned if a parameter is missing from params, or if its value is empty or
+// contains a slash. Values in params not matching any parameter are ignored.
+func (m AstarteInterfaceMapping) ExpandPath(params map[string]string) (string, error) {
+	tokens := strings.Split(m.Endpoint, "/")
+	for i, token := range tokens {
+		name, ok := endpointParameter(token)
+		if !ok {
+			continue
+		}
+		value, ok := params[name]
+		switch {
+		case !ok:
+			return "", fmt.Errorf("missing value for parameter %s of %s", name, m.Endpoint)
+		case value == "", strings.Contains(value, "/"):
+			return "", fmt.Errorf("invalid value %q for parameter %s of %s", value, name, m.Endpoint)
+		}
+		tokens[i] = value
+	}
+	return strings.Join(tokens, "/"), nil
+}
+
+// endpointParameter returns the name of the parameter token stands for, if it is a parametric token such as
+// %{sensor_id}
+func endpointParameter(token string) (string, bool) {
+	if !strings.HasPrefix(token, "%{") || !strings.HasSuffix(token, "}") {
+		return "", false
+	}
+	return token[2 : len(token)-1], true
+}
+
+type rawAstarteInterfaceMapping struct {
+	Endpoint                string `json:"endpoint"`
+	Type                    string `json:"type"`
+	Reliability             string `json:"reliability"`
+	Retention               string `json:"retention"`
+	DatabaseRetentionPolicy string `json:"database_retention_policy"`
+	DatabaseRetentionTTL    int    `json:"database_retention_ttl"`
+	Expiry                  int    `json:"expiry"`
+	ExplicitTimestamp       bool   `json:"explicit_timestamp"`
+	AllowUnset              bool   `json:"allow_unset"`
+	Description             string `json:"description"`
+	Documentation           string `json:"doc"`
+}
+
+type rawAstarteInterface struct {
+	Name              string                       `json:"interface_name"`
+	MajorVersion      int                          `json:"version_major"`
+	MinorVersion      int                          `json:"version_minor"`
+	Type              string                       `json:"type"`
+	Ownership         string                       `json:"ownership"`
+	Aggregation       string                       `json:"aggregation"`
+	ExplicitTimestamp bool                         `json:"explicit_timestamp"`
+	HasMetadata       bool                         `json:"has_metadata"`
+	Description       string                       `json:"description"`
+	Documentation     string                       `json:"doc"`
+	Mappings          []rawAstarteInterfaceMapping `json:"mappings"`
+}
+
+func (r rawAstarteInterface) toAstarteInterface() AstarteInterface {
+	astarteInterface := AstarteInterface{
+		Name:              r.Name,
+		MajorVersion:      r.MajorVersion,
+		MinorVersion:      r.MinorVersion,
+		Type:              AstarteInterfaceType(r.Type),
+		Ownership:         AstarteInterfaceOwnership(r.Ownership),
+		Aggregation:       AstarteInterfaceAggregation(r.Aggregation),
+		ExplicitTimestamp: r.ExplicitTimestamp,
+		HasMetadata:       r.HasMetadata,
+		Description:       r.Description,
+		Documentation:     r.Documentation,
+		Mappings:          []AstarteInterfaceMapping{},
+	}
+	for _, m := range r.Mappings {
+		astarteInterface.Mappings = append(astarteInterface.Mappings, AstarteInterfaceMapping{
+			Endpoint:                m.Endpoint,
+			Type:                    AstarteMappingType(m.Type),
+			Reliability:             AstarteMappingReliability(m.Reliability),
+			Retention:               AstarteMappingRetention(m.Retention),
+			DatabaseRetentionPolicy: AstarteMappingDatabaseRetentionPolicy(m.DatabaseRetentionPolicy),
+			DatabaseRetentionTTL:    m.DatabaseRetentionTTL,
+			Expiry:                  m.Expiry,
+			ExplicitTimestamp:       m.ExplicitTimestamp,
+			AllowUnset:              m.AllowUnset,
+			Description:             m.Description,
+			Documentation:           m.Documentation,
+		})
+	}
+	return astarteInterface
+}
+
+// jsonIndexRegexp matches array indexes in the dotted field paths reported by encoding/json
+var jsonIndexRegexp = regexp.MustCompile(`\.(\d+)`)
+
+// describeJSONError turns a JSON decoding error into one pointing to the offending field or position in content
+func describeJSONError(content []byte, err error) error {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return &ValidationError{
+			Field:   jsonIndexRegexp.ReplaceAllString(typeError.Field, "[$1]"),
+			Message: fmt.Sprintf("expected %s, got %s", typeError.Type, typeError.Value),
+		}
+	}
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		line, column := 1, 1
+		for _, c := range content[:syntaxError.Offset] {
+			if c == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		return fmt.Errorf("invalid JSON at line %d, column %d: %w", line, column, err)
+	}
+	return err
+}
@@ -16,6 +16,9 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -217,3 +220,281 @@ func TestFailedOwnershipParsing(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestParseInterfaceFrom(t *testing.T) {
+	i, err := ParseInterfaceFrom(strings.NewReader(`
+	{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"mappings": [{"endpoint": "/%{sensor_id}/value", "type": "double"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Aggregation != IndividualAggregation || i.Mappings[0].Reliability != UnreliableReliability {
+		t.Error("Defaults were not set", i)
+	}
+}
+
+func TestParseInterfaceFromReportsFieldPath(t *testing.T) {
+	_, err := ParseInterfaceFrom(strings.NewReader(`
+	{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 1,
+		"version_minor": 0,
+		"type": "datastream",
+		"ownership": "device",
+		"mappings": [
+			{"endpoint": "/%{sensor_id}/value", "type": "double"},
+			{"endpoint": "/%{sensor_id}/unit", "type": "stringa"}
+		]
+	}`))
+	var validationErrors ValidationErrors
+	if !errors.As(err, &validationErrors) || len(validationErrors) != 1 {
+		t.Fatal("Expected a single violation, got", err)
+	}
+	if validationError := validationErrors[0].(*ValidationError); validationError.Field != "mappings[1].type" {
+		t.Error("Unexpected field path", validationError.Field)
+	}
+
+	_, err = ParseInterfaceFrom(strings.NewReader(`{"interface_name": "test", "mappings": [{"endpoint": 3}]}`))
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) || validationError.Field != "mappings[0].endpoint" {
+		t.Error("Unexpected error", err)
+	}
+
+	_, err = ParseInterfaceFrom(strings.NewReader("{\n\"interface_name\": \"test\",,\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Error("Unexpected error", err)
+	}
+}
+
+func TestMappingMatchPath(t *testing.T) {
+	mapping := AstarteInterfaceMapping{Endpoint: "/sensors/%{sensor_id}/channels/%{channel}/value"}
+
+	params, ok := mapping.MatchPath("/sensors/temp/channels/ch0/value")
+	if !ok {
+		t.Fatal("Expected the path to match")
+	}
+	if !reflect.DeepEqual(params, map[string]string{"sensor_id": "temp", "channel": "ch0"}) {
+		t.Error("Wrong parameters", params)
+	}
+
+	for _, path := range []string{
+		"/sensors/temp/channels/ch0/value/extra",
+		"/sensors/temp/channels/ch0",
+		"/sensors/temp/channel/ch0/value",
+		"/sensors//channels/ch0/value",
+		"sensors/temp/channels/ch0/value",
+	} {
+		if _, ok := mapping.MatchPath(path); ok {
+			t.Errorf("Expected %s not to match", path)
+		}
+	}
+
+	static := AstarteInterfaceMapping{Endpoint: "/enabled"}
+	if params, ok := static.MatchPath("/enabled"); !ok || len(params) != 0 {
+		t.Error("Expected /enabled to match without parameters", params)
+	}
+}
+
+func TestMappingExpandPath(t *testing.T) {
+	mapping := AstarteInterfaceMapping{Endpoint: "/sensors/%{sensor_id}/channels/%{channel}/value"}
+
+	path, err := mapping.ExpandPath(map[string]string{"sensor_id": "temp", "channel": "ch0", "unused": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/sensors/temp/channels/ch0/value" {
+		t.Error("Wrong path", path)
+	}
+	if _, ok := mapping.MatchPath(path); !ok {
+		t.Error("Expected the expanded path to match")
+	}
+
+	for _, params := range []map[string]string{
+		{"sensor_id": "temp"},
+		{"sensor_id": "temp", "channel": ""},
+		{"sensor_id": "temp/other", "channel": "ch0"},
+	} {
+		if _, err := mapping.ExpandPath(params); err == nil {
+			t.Errorf("Expected an error for %v", params)
+		}
+	}
+}
+
+func TestMarshalInterfaceRoundTrip(t *testing.T) {
+	validInterface := `
+	{
+		"interface_name": "org.astarte-platform.genericsensors.Values",
+		"version_major": 0,
+		"version_minor": 1,
+		"type": "datastream",
+		"ownership": "device",
+		"description": "Generic sensors sampled data.",
+		"doc": "Values sampled by <sensors> & co.",
+		"mappings": [
+			{
+				"endpoint": "/%{sensor_id}/value",
+				"type": "double",
+				"explicit_timestamp": true
+			},
+			{
+				"endpoint": "/%{sensor_id}/status",
+				"type": "string",
+				"database_retention_policy": "use_ttl",
+				"database_retention_ttl": 3600
+			},
+			{
+				"endpoint": "/%{sensor_id}/calibrated",
+				"type": "boolean"
+			}
+		]
+	}`
+
+	parsed, err := ParseInterfaceFromString(validInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Parsing and marshaling must not add the defaults missing from the original document
+	unchanged, err := MarshalInterface(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var original, roundTripped interface{}
+	_ = json.Unmarshal([]byte(validInterface), &original)
+	_ = json.Unmarshal(unchanged, &roundTripped)
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Errorf("Round trip changed the document:\n%s", unchanged)
+	}
+
+	// Defaulted fields are marshaled once they are changed
+	changed := parsed
+	changed.Mappings = append([]AstarteInterfaceMapping{}, parsed.Mappings...)
+	changed.Mappings[0].Reliability = GuaranteedReliability
+	if marshaled, _ := json.Marshal(changed); !strings.Contains(string(marshaled), `"reliability":"guaranteed"`) {
+		t.Error("Expected the changed reliability to be marshaled", string(marshaled))
+	}
+
+	parsed.MinorVersion++
+
+	marshaled, err := MarshalInterface(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(marshaled), "<sensors> & co.") {
+		t.Error("Expected HTML characters not to be escaped", string(marshaled))
+	}
+
+	reparsed, err := ParseInterface(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, reparsed) {
+		t.Errorf("Round trip changed the interface: %+v != %+v", parsed, reparsed)
+	}
+	for i, m := range reparsed.Mappings {
+		if m.Endpoint != parsed.Mappings[i].Endpoint {
+			t.Errorf("Mapping %d out of order: %s", i, m.Endpoint)
+		}
+	}
+
+	remarshaled, err := MarshalInterface(reparsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(marshaled) != string(remarshaled) {
+		t.Errorf("Marshaling is not stable:\n%s\n%s", marshaled, remarshaled)
+	}
+}
+
+func TestMarshalEmptyMappings(t *testing.T) {
+	marshaled, err := json.Marshal(AstarteInterface{Name: "org.astarte-platform.Empty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(marshaled), `"mappings":[]`) {
+		t.Error("Expected mappings to be an empty array", string(marshaled))
+	}
+}
+
+func TestMappingFieldsMarshaling(t *testing.T) {
+	mapping := AstarteInterfaceMapping{
+		Endpoint:                "/%{sensor_id}/value",
+		Type:                    Double,
+		Reliability:             GuaranteedReliability,
+		Retention:               StoredRetention,
+		Expiry:                  3600,
+		DatabaseRetentionPolicy: UseTTL,
+		DatabaseRetentionTTL:    86400,
+		AllowUnset:              true,
+		ExplicitTimestamp:       true,
+		Description:             "Sampled value.",
+		Documentation:           "Value sampled by the sensor.",
+	}
+
+	marshaled, err := json.Marshal(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(marshaled, &fields); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"endpoint":                  "/%{sensor_id}/value",
+		"type":                      "double",
+		"reliability":               "guaranteed",
+		"retention":                 "stored",
+		"expiry":                    float64(3600),
+		"database_retention_policy": "use_ttl",
+		"database_retention_ttl":    float64(86400),
+		"allow_unset":               true,
+		"explicit_timestamp":        true,
+		"description":               "Sampled value.",
+		"doc":                       "Value sampled by the sensor.",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Error("Wrong fields marshaled", fields)
+	}
+
+	unmarshaled := AstarteInterfaceMapping{}
+	if err := json.Unmarshal(marshaled, &unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	if unmarshaled != mapping {
+		t.Error("Wrong mapping unmarshaled", unmarshaled)
+	}
+}
+
+func TestInterfaceEnumsMarshaling(t *testing.T) {
+	i := AstarteInterface{
+		Name:        "org.astarte-platform.genericsensors.Values",
+		Type:        DatastreamType,
+		Ownership:   DeviceOwnership,
+		Aggregation: ObjectAggregation,
+	}
+	marshaled, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"type":"datastream"`, `"ownership":"device"`, `"aggregation":"object"`} {
+		if !strings.Contains(string(marshaled), field) {
+			t.Errorf("Expected %s in %s", field, marshaled)
+		}
+	}
+
+	for _, invalid := range []AstarteInterface{
+		{Name: i.Name, Type: "datastreams", Ownership: DeviceOwnership},
+		{Name: i.Name, Type: DatastreamType, Ownership: "devices"},
+		{Name: i.Name, Type: DatastreamType, Ownership: DeviceOwnership, Aggregation: "objects"},
+	} {
+		if _, err := json.Marshal(invalid); err == nil {
+			t.Errorf("Expected an error marshaling %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// DecodeValue converts raw, a value as decoded from a JSON reply of Astarte (e.g. a float64 for any number), to
+// the golang type corresponding to the type of mapping: float64 for "double", int for "integer", int64 for
+// "longinteger", bool for "boolean", string for "string", []byte for "binaryblob", time.Time for "datetime", and
+// slices of those for the array types. An error is returned if raw cannot be converted without losing precision.
+func DecodeValue(mapping AstarteInterfaceMapping, raw interface{}) (interface{}, error) {
+	switch mapping.Type {
+	case Double, Integer, LongInteger, Boolean, String, BinaryBlob, DateTime:
+		return decodeScalar(mapping.Type, raw)
+	case DoubleArray:
+		return decodeArray(Double, raw, []float64{})
+	case IntegerArray:
+		return decodeArray(Integer, raw, []int{})
+	case LongIntegerArray:
+		return decodeArray(LongInteger, raw, []int64{})
+	case BooleanArray:
+		return decodeArray(Boolean, raw, []bool{})
+	case StringArray:
+		return decodeArray(String, raw, []string{})
+	case BinaryBlobArray:
+		return decodeArray(BinaryBlob, raw, [][]byte{})
+	case DateTimeArray:
+		return decodeArray(DateTime, raw, []time.Time{})
+	}
+	return nil, fmt.Errorf("'%s' is not a valid Astarte Mapping Type", mapping.Type)
+}
+
+// decodeArray decodes each element of raw as elementType, and appends it to ret, which must be a slice of the
+// golang type corresponding to elementType
+func decodeArray(elementType AstarteMappingType, raw interface{}, ret interface{}) (interface{}, error) {
+	elements, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array of %s, got %T", elementType, raw)
+	}
+	for i, element := range elements {
+		value, err := decodeScalar(elementType, element)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		switch r := ret.(type) {
+		case []float64:
+			ret = append(r, value.(float64))
+		case []int:
+			ret = append(r, value.(int))
+		case []int64:
+			ret = append(r, value.(int64))
+		case []bool:
+			ret = append(r, value.(bool))
+		case []string:
+			ret = append(r, value.(string))
+		case [][]byte:
+			ret = append(r, value.([]byte))
+		case []time.Time:
+			ret = append(r, value.(time.Time))
+		}
+	}
+	return ret, nil
+}
+
+func decodeScalar(mappingType AstarteMappingType, raw interface{}) (interface{}, error) {
+	switch mappingType {
+	case Double:
+		return decodeFloat(raw)
+	case Integer:
+		n, err := decodeInteger(raw)
+		if err != nil {
+			return nil, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%d overflows an integer", n)
+		}
+		return int(n), nil
+	case LongInteger:
+		return decodeInteger(raw)
+	case Boolean:
+		if b, ok := raw.(bool); ok {
+			return b, nil
+		}
+	case String:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+	case BinaryBlob:
+		if s, ok := raw.(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+	case DateTime:
+		if s, ok := raw.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	}
+	return nil, fmt.Errorf("cannot decode %T as %s", raw, mappingType)
+}
+
+func decodeFloat(raw interface{}) (float64, error) {
+	switch n := raw.(type) {
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("cannot decode %T as %s", raw, Double)
+}
+
+// decodeInteger decodes raw as an int64. Astarte might encode longintegers as strings, to avoid losing
+// precision in JSON, so strings are accepted as well.
+func decodeInteger(raw interface{}) (int64, error) {
+	switch n := raw.(type) {
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot decode %T as an integer", raw)
+}
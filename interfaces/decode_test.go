@@ -0,0 +1,78 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeValue(t *testing.T) {
+	payload := `{
+		"double": 42, "integer": 42, "longinteger": "9007199254740993", "boolean": true, "string": "hello",
+		"binaryblob": "aGVsbG8=", "datetime": "2020-10-15T12:00:00.123Z",
+		"doublearray": [1, 2.5], "integerarray": [1, 2], "longintegerarray": [1, "2"], "booleanarray": [true],
+		"stringarray": ["a"], "binaryblobarray": ["aGVsbG8="], "datetimearray": ["2020-10-15T12:00:00Z"]
+	}`
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(payload), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[AstarteMappingType]interface{}{
+		Double:           42.0,
+		Integer:          42,
+		LongInteger:      int64(9007199254740993),
+		Boolean:          true,
+		String:           "hello",
+		BinaryBlob:       []byte("hello"),
+		DateTime:         time.Date(2020, 10, 15, 12, 0, 0, 123000000, time.UTC),
+		DoubleArray:      []float64{1, 2.5},
+		IntegerArray:     []int{1, 2},
+		LongIntegerArray: []int64{1, 2},
+		BooleanArray:     []bool{true},
+		StringArray:      []string{"a"},
+		BinaryBlobArray:  [][]byte{[]byte("hello")},
+		DateTimeArray:    []time.Time{time.Date(2020, 10, 15, 12, 0, 0, 0, time.UTC)},
+	}
+	for mappingType, expectedValue := range expected {
+		value, err := DecodeValue(AstarteInterfaceMapping{Endpoint: "/value", Type: mappingType}, raw[string(mappingType)])
+		if err != nil {
+			t.Error(mappingType, err)
+			continue
+		}
+		if !reflect.DeepEqual(value, expectedValue) {
+			t.Errorf("Wrong %s value: expected %#v, got %#v", mappingType, expectedValue, value)
+		}
+	}
+}
+
+func TestDecodeValueErrors(t *testing.T) {
+	for mappingType, raw := range map[AstarteMappingType]interface{}{
+		Integer:      4.5,
+		LongInteger:  "not a number",
+		Boolean:      "true",
+		DateTime:     "yesterday",
+		IntegerArray: []interface{}{1.0, 5000000000.0},
+		DoubleArray:  1.0,
+		"float":      1.0,
+	} {
+		if value, err := DecodeValue(AstarteInterfaceMapping{Endpoint: "/value", Type: mappingType}, raw); err == nil {
+			t.Errorf("Expected an error decoding %v as %s, got %#v", raw, mappingType, value)
+		}
+	}
+}
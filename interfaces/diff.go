@@ -0,0 +1,168 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change between two definitions of an Interface
+type ChangeKind string
+
+const (
+	// VersionChanged is a change of version_major or version_minor
+	VersionChanged ChangeKind = "version_changed"
+	// InterfaceModified is a change of a field of the Interface other than its version and mappings
+	InterfaceModified ChangeKind = "interface_modified"
+	// MappingAdded is a mapping whose endpoint exists only in the new definition
+	MappingAdded ChangeKind = "mapping_added"
+	// MappingRemoved is a mapping whose endpoint exists only in the old definition
+	MappingRemoved ChangeKind = "mapping_removed"
+	// MappingModified is a change of a field of a mapping existing in both definitions
+	MappingModified ChangeKind = "mapping_modified"
+)
+
+// Change is a single difference between two definitions of an Interface, as reported by Diff
+type Change struct {
+	Kind ChangeKind
+	// Endpoint is the endpoint of the mapping, for changes to mappings
+	Endpoint string
+	// Field is the JSON name of the changed field, e.g. "ownership" or "database_retention_ttl". It is empty for
+	// added and removed mappings.
+	Field string
+	// Old and New are the values of Field before and after the change, if any
+	Old interface{}
+	New interface{}
+	// Breaking is true if Astarte would reject updating the old definition to the new one because of this change
+	Breaking bool
+}
+
+// String returns a human readable description of the change
+func (c Change) String() string {
+	var description string
+	switch c.Kind {
+	case MappingAdded:
+		description = fmt.Sprintf("mapping %s added", c.Endpoint)
+	case MappingRemoved:
+		description = fmt.Sprintf("mapping %s removed", c.Endpoint)
+	case MappingModified:
+		description = fmt.Sprintf("mapping %s: %s changed from %v to %v", c.Endpoint, c.Field, c.Old, c.New)
+	default:
+		description = fmt.Sprintf("%s changed from %v to %v", c.Field, c.Old, c.New)
+	}
+	if c.Breaking {
+		description += " (breaking)"
+	}
+	return description
+}
+
+// Diff reports the changes needed to turn oldInterface into newInterface, in a stable order: version changes
+// first, then changes to the other fields of the Interface, to existing mappings and finally added mappings.
+// Mappings are matched by endpoint. Missing fields are set to their defaults before comparing, so that e.g. an
+// omitted reliability and an explicit "unreliable" one are the same.
+//
+// Within the same major version, Astarte accepts only updates bumping the minor version and adding mappings or
+// changing descriptions and docs: any other change is flagged as Breaking, and so is every change if the minor
+// version is not bumped. Changes between different major versions are never breaking, as each major version is
+// a distinct Interface. An error is returned if the two definitions are not of the same Interface.
+func Diff(oldInterface, newInterface AstarteInterface) ([]Change, error) {
+	if oldInterface.Name != newInterface.Name {
+		return nil, fmt.Errorf("cannot compare interface %s with interface %s", oldInterface.Name, newInterface.Name)
+	}
+	oldInterface = EnsureInterfaceDefaults(oldInterface)
+	newInterface = EnsureInterfaceDefaults(newInterface)
+
+	sameMajor := oldInterface.MajorVersion == newInterface.MajorVersion
+	changes := []Change{}
+	if !sameMajor {
+		changes = append(changes, Change{Kind: VersionChanged, Field: "version_major",
+			Old: oldInterface.MajorVersion, New: newInterface.MajorVersion})
+	}
+	if oldInterface.MinorVersion != newInterface.MinorVersion {
+		changes = append(changes, Change{Kind: VersionChanged, Field: "version_minor",
+			Old: oldInterface.MinorVersion, New: newInterface.MinorVersion,
+			Breaking: sameMajor && newInterface.MinorVersion < oldInterface.MinorVersion})
+	}
+	versionChanges := len(changes)
+
+	for _, c := range diffFields(oldInterface, newInterface) {
+		c.Kind = InterfaceModified
+		changes = append(changes, c)
+	}
+
+	newMappings := map[string]AstarteInterfaceMapping{}
+	for _, m := range newInterface.Mappings {
+		newMappings[m.Endpoint] = m
+	}
+	oldEndpoints := map[string]bool{}
+	for _, oldMapping := range oldInterface.Mappings {
+		oldEndpoints[oldMapping.Endpoint] = true
+		newMapping, ok := newMappings[oldMapping.Endpoint]
+		if !ok {
+			changes = append(changes, Change{Kind: MappingRemoved, Endpoint: oldMapping.Endpoint, Breaking: true})
+			continue
+		}
+		for _, c := range diffFields(oldMapping, newMapping) {
+			c.Kind = MappingModified
+			c.Endpoint = oldMapping.Endpoint
+			changes = append(changes, c)
+		}
+	}
+	for _, m := range newInterface.Mappings {
+		if !oldEndpoints[m.Endpoint] {
+			changes = append(changes, Change{Kind: MappingAdded, Endpoint: m.Endpoint})
+		}
+	}
+
+	if !sameMajor {
+		for i := range changes {
+			changes[i].Breaking = false
+		}
+	} else if newInterface.MinorVersion <= oldInterface.MinorVersion {
+		// Astarte rejects any update which does not bump the minor version
+		for i := range changes[versionChanges:] {
+			changes[versionChanges+i].Breaking = true
+		}
+	}
+	return changes, nil
+}
+
+// diffFields compares the fields of two AstarteInterface or two AstarteInterfaceMapping, skipping the ones
+// identifying them and mappings. Only changes to description and doc are not flagged as Breaking.
+func diffFields(oldValue, newValue interface{}) []Change {
+	changes := []Change{}
+	oldStruct, newStruct := reflect.ValueOf(oldValue), reflect.ValueOf(newValue)
+	for i := 0; i < oldStruct.NumField(); i++ {
+		structField := oldStruct.Type().Field(i)
+		if structField.PkgPath != "" {
+			// Unexported fields are not part of the interface definition
+			continue
+		}
+		field := strings.Split(structField.Tag.Get("json"), ",")[0]
+		switch field {
+		case "interface_name", "version_major", "version_minor", "mappings", "endpoint":
+			continue
+		}
+		o, n := oldStruct.Field(i).Interface(), newStruct.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, Change{Field: field, Old: o, New: n,
+			Breaking: field != "description" && field != "doc"})
+	}
+	return changes
+}
@@ -0,0 +1,118 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testDiffInterface() AstarteInterface {
+	return AstarteInterface{
+		Name:         "org.astarte-platform.genericsensors.Values",
+		MajorVersion: 1,
+		MinorVersion: 0,
+		Type:         DatastreamType,
+		Ownership:    DeviceOwnership,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/status", Type: String, Description: "Sensor status."},
+		},
+	}
+}
+
+func TestDiffAdditive(t *testing.T) {
+	oldInterface := testDiffInterface()
+	newInterface := testDiffInterface()
+	newInterface.MinorVersion = 1
+	newInterface.Mappings[1].Description = "Status of the sensor."
+	newInterface.Mappings = append(newInterface.Mappings, AstarteInterfaceMapping{Endpoint: "/%{sensor_id}/unit", Type: String})
+
+	changes, err := Diff(oldInterface, newInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Kind: VersionChanged, Field: "version_minor", Old: 0, New: 1},
+		{Kind: MappingModified, Endpoint: "/%{sensor_id}/status", Field: "description", Old: "Sensor status.", New: "Status of the sensor."},
+		{Kind: MappingAdded, Endpoint: "/%{sensor_id}/unit"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Wrong changes: %v", changes)
+	}
+}
+
+func TestDiffBreaking(t *testing.T) {
+	oldInterface := testDiffInterface()
+	newInterface := testDiffInterface()
+	newInterface.MinorVersion = 1
+	newInterface.Ownership = ServerOwnership
+	newInterface.Mappings[0].Type = Integer
+	newInterface.Mappings = newInterface.Mappings[:1]
+
+	changes, err := Diff(oldInterface, newInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Kind: VersionChanged, Field: "version_minor", Old: 0, New: 1},
+		{Kind: InterfaceModified, Field: "ownership", Old: DeviceOwnership, New: ServerOwnership, Breaking: true},
+		{Kind: MappingModified, Endpoint: "/%{sensor_id}/value", Field: "type", Old: Double, New: Integer, Breaking: true},
+		{Kind: MappingRemoved, Endpoint: "/%{sensor_id}/status", Breaking: true},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Wrong changes: %v", changes)
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	oldInterface := testDiffInterface()
+
+	// Without a minor bump, even additive changes are rejected
+	newInterface := testDiffInterface()
+	newInterface.Mappings = append(newInterface.Mappings, AstarteInterfaceMapping{Endpoint: "/%{sensor_id}/unit", Type: String})
+	changes, err := Diff(oldInterface, newInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Kind != MappingAdded || !changes[0].Breaking {
+		t.Errorf("Wrong changes: %v", changes)
+	}
+
+	// A new major version may change anything
+	newInterface = testDiffInterface()
+	newInterface.MajorVersion = 2
+	newInterface.Mappings = newInterface.Mappings[:1]
+	changes, err = Diff(oldInterface, newInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range changes {
+		if c.Breaking {
+			t.Errorf("Unexpected breaking change: %v", c)
+		}
+	}
+
+	// Defaults do not count as changes
+	newInterface = EnsureInterfaceDefaults(testDiffInterface())
+	if changes, _ := Diff(oldInterface, newInterface); len(changes) != 0 {
+		t.Errorf("Unexpected changes: %v", changes)
+	}
+
+	newInterface.Name = "org.astarte-platform.genericsensors.Other"
+	if _, err := Diff(oldInterface, newInterface); err == nil {
+		t.Error("Expected an error for different interfaces")
+	}
+}
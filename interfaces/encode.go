@@ -0,0 +1,62 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// astarteTimeFormat is the ISO8601 format, with millisecond precision, used to encode datetime values
+const astarteTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// EncodeValue converts value to its JSON representation for the type of mapping, as expected by Astarte: []byte
+// values are encoded in base64 for "binaryblob", and time.Time values as ISO8601 strings for "datetime". An error
+// is returned if the golang type of value is not compatible with the type of mapping (e.g. a string for a
+// "double"), so that mismatches are caught before reaching Astarte.
+func EncodeValue(mapping AstarteInterfaceMapping, value interface{}) (interface{}, error) {
+	if err := validateType(mapping.Type, value); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", mapping.Endpoint, err)
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case [][]byte:
+		encoded := []string{}
+		for _, blob := range v {
+			encoded = append(encoded, base64.StdEncoding.EncodeToString(blob))
+		}
+		return encoded, nil
+	case time.Time:
+		return v.UTC().Format(astarteTimeFormat), nil
+	case *time.Time:
+		return v.UTC().Format(astarteTimeFormat), nil
+	case []time.Time:
+		encoded := []string{}
+		for _, t := range v {
+			encoded = append(encoded, t.UTC().Format(astarteTimeFormat))
+		}
+		return encoded, nil
+	case []*time.Time:
+		encoded := []string{}
+		for _, t := range v {
+			encoded = append(encoded, t.UTC().Format(astarteTimeFormat))
+		}
+		return encoded, nil
+	}
+	return value, nil
+}
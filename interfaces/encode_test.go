@@ -0,0 +1,73 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeValue(t *testing.T) {
+	timestamp := time.Date(2020, 10, 15, 14, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+	for _, tc := range []struct {
+		mappingType AstarteMappingType
+		value       interface{}
+		expected    interface{}
+	}{
+		{Double, 42, 42},
+		{String, "hello", "hello"},
+		{BinaryBlob, []byte("hello"), "aGVsbG8="},
+		{BinaryBlobArray, [][]byte{[]byte("hello")}, []string{"aGVsbG8="}},
+		{DateTime, timestamp, "2020-10-15T12:00:00.123Z"},
+		{DateTime, &timestamp, "2020-10-15T12:00:00.123Z"},
+		{DateTimeArray, []time.Time{timestamp}, []string{"2020-10-15T12:00:00.123Z"}},
+	} {
+		encoded, err := EncodeValue(AstarteInterfaceMapping{Endpoint: "/value", Type: tc.mappingType}, tc.value)
+		if err != nil {
+			t.Error(tc.mappingType, err)
+			continue
+		}
+		if !reflect.DeepEqual(encoded, tc.expected) {
+			t.Errorf("Wrong %s encoding: expected %#v, got %#v", tc.mappingType, tc.expected, encoded)
+		}
+	}
+}
+
+func TestEncodeValueRejectsMismatchedTypes(t *testing.T) {
+	for mappingType, value := range map[AstarteMappingType]interface{}{
+		Double:     "42",
+		Integer:    4.2,
+		BinaryBlob: "aGVsbG8=",
+		DateTime:   "2020-10-15T12:00:00Z",
+	} {
+		if _, err := EncodeValue(AstarteInterfaceMapping{Endpoint: "/value", Type: mappingType}, value); err == nil {
+			t.Errorf("Expected an error encoding %T as %s", value, mappingType)
+		}
+	}
+}
+
+func TestEncodeValueRejectsNilTimes(t *testing.T) {
+	var nilTime *time.Time
+	timestamp := time.Now()
+	for mappingType, value := range map[AstarteMappingType]interface{}{
+		DateTime:      nilTime,
+		DateTimeArray: []*time.Time{&timestamp, nil},
+	} {
+		if _, err := EncodeValue(AstarteInterfaceMapping{Endpoint: "/value", Type: mappingType}, value); err == nil {
+			t.Errorf("Expected an error encoding a nil time as %s", mappingType)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema (draft-07) describing the Astarte Interface format, mirroring the rules
+// enforced by Validate so that tools can check Interface files as they are edited. Rules spanning several
+// mappings, such as endpoint uniqueness and the common base path of object aggregated Interfaces, cannot be
+// expressed in JSON Schema and are only checked by Validate.
+func JSONSchema() []byte {
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "Astarte Interface",
+		"type":        "object",
+		"required":    []string{"interface_name", "version_major", "version_minor", "type", "ownership", "mappings"},
+		"definitions": map[string]interface{}{"mapping": mappingSchema()},
+		"properties": map[string]interface{}{
+			"interface_name": map[string]interface{}{
+				"type":      "string",
+				"maxLength": maxInterfaceNameLength,
+				"pattern":   interfaceNameRegexp.String(),
+				"not":       map[string]interface{}{"pattern": caseInsensitivePattern(reservedInterfaceNames)},
+			},
+			"version_major":      map[string]interface{}{"type": "integer", "minimum": 0},
+			"version_minor":      map[string]interface{}{"type": "integer", "minimum": 0},
+			"type":               enumSchema(PropertiesType, DatastreamType),
+			"ownership":          enumSchema(DeviceOwnership, ServerOwnership),
+			"aggregation":        enumSchema(IndividualAggregation, ObjectAggregation),
+			"explicit_timestamp": map[string]interface{}{"type": "boolean"},
+			"has_metadata":       map[string]interface{}{"type": "boolean"},
+			"description":        map[string]interface{}{"type": "string"},
+			"doc":                map[string]interface{}{"type": "string"},
+			"mappings": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"maxItems": maxMappingsCount,
+				"items":    map[string]interface{}{"$ref": "#/definitions/mapping"},
+			},
+		},
+		"allOf": []interface{}{
+			// version_minor must be greater than 0 when version_major is 0
+			map[string]interface{}{
+				"if":   map[string]interface{}{"properties": map[string]interface{}{"version_major": map[string]interface{}{"const": 0}}},
+				"then": map[string]interface{}{"properties": map[string]interface{}{"version_minor": map[string]interface{}{"minimum": 1}}},
+			},
+			// properties Interfaces cannot have object aggregation
+			map[string]interface{}{
+				"if": map[string]interface{}{
+					"required":   []string{"type"},
+					"properties": map[string]interface{}{"type": map[string]interface{}{"const": PropertiesType}},
+				},
+				"then": map[string]interface{}{"properties": map[string]interface{}{"aggregation": map[string]interface{}{"not": map[string]interface{}{"const": ObjectAggregation}}}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	// The schema is made of plain maps and slices, encoding it can't fail
+	_ = encoder.Encode(schema)
+	return buf.Bytes()
+}
+
+func mappingSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"endpoint", "type"},
+		"properties": map[string]interface{}{
+			"endpoint": map[string]interface{}{"type": "string", "pattern": endpointRegexp.String()},
+			"type": enumSchema(Double, Integer, Boolean, LongInteger, String, BinaryBlob, DateTime, DoubleArray,
+				IntegerArray, BooleanArray, LongIntegerArray, StringArray, BinaryBlobArray, DateTimeArray),
+			"reliability":               enumSchema(UnreliableReliability, GuaranteedReliability, UniqueReliability),
+			"retention":                 enumSchema(DiscardRetention, VolatileRetention, StoredRetention),
+			"database_retention_policy": enumSchema(NoTTL, UseTTL),
+			"database_retention_ttl":    map[string]interface{}{"type": "integer", "minimum": 0},
+			"expiry":                    map[string]interface{}{"type": "integer", "minimum": 0},
+			"explicit_timestamp":        map[string]interface{}{"type": "boolean"},
+			"allow_unset":               map[string]interface{}{"type": "boolean"},
+			"description":               map[string]interface{}{"type": "string"},
+			"doc":                       map[string]interface{}{"type": "string"},
+		},
+		// database_retention_ttl is required when database_retention_policy is use_ttl
+		"if": map[string]interface{}{
+			"required":   []string{"database_retention_policy"},
+			"properties": map[string]interface{}{"database_retention_policy": map[string]interface{}{"const": UseTTL}},
+		},
+		"then": map[string]interface{}{
+			"required":   []string{"database_retention_ttl"},
+			"properties": map[string]interface{}{"database_retention_ttl": map[string]interface{}{"minimum": 1}},
+		},
+	}
+}
+
+func enumSchema(values ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values}
+}
+
+// caseInsensitivePattern returns a pattern matching any of names regardless of their case, since JSON Schema
+// patterns have no case insensitive flag
+func caseInsensitivePattern(names []string) string {
+	alternatives := []string{}
+	for _, name := range names {
+		var b strings.Builder
+		for _, r := range name {
+			lower, upper := strings.ToLower(string(r)), strings.ToUpper(string(r))
+			if lower == upper {
+				b.WriteString(regexp.QuoteMeta(lower))
+			} else {
+				b.WriteString("[" + lower + upper + "]")
+			}
+		}
+		alternatives = append(alternatives, b.String())
+	}
+	return "^(" + strings.Join(alternatives, "|") + ")$"
+}
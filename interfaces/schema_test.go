@@ -0,0 +1,138 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema"
+)
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties struct {
+			InterfaceName struct {
+				Pattern string `json:"pattern"`
+				Not     struct {
+					Pattern string `json:"pattern"`
+				} `json:"not"`
+			} `json:"interface_name"`
+		} `json:"properties"`
+		Definitions struct {
+			Mapping struct {
+				Properties struct {
+					Endpoint struct {
+						Pattern string `json:"pattern"`
+					} `json:"endpoint"`
+					Type struct {
+						Enum []AstarteMappingType `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"mapping"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(JSONSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schema.Required) != 6 {
+		t.Error("Unexpected required fields", schema.Required)
+	}
+	if schema.Properties.InterfaceName.Pattern != interfaceNameRegexp.String() ||
+		schema.Definitions.Mapping.Properties.Endpoint.Pattern != endpointRegexp.String() {
+		t.Error("Schema patterns differ from the ones used by Validate")
+	}
+	for _, mappingType := range schema.Definitions.Mapping.Properties.Type.Enum {
+		if err := mappingType.IsValid(); err != nil {
+			t.Error("Invalid mapping type in schema", mappingType)
+		}
+	}
+	if len(schema.Definitions.Mapping.Properties.Type.Enum) != 14 {
+		t.Error("Missing mapping types in schema", schema.Definitions.Mapping.Properties.Type.Enum)
+	}
+
+	reserved := regexp.MustCompile(schema.Properties.InterfaceName.Not.Pattern)
+	for name, matches := range map[string]bool{"control": true, "CoNtRoL": true, "controller": false} {
+		if reserved.MatchString(name) != matches {
+			t.Errorf("Reserved name pattern matching %s should be %v", name, matches)
+		}
+	}
+}
+
+func TestJSONSchemaValidatesInterfaces(t *testing.T) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("interface.json", bytes.NewReader(JSONSchema())); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile("interface.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := getValidTestInterface()
+	properties.Type = PropertiesType
+	properties.Aggregation = IndividualAggregation
+	for _, i := range []AstarteInterface{getValidTestInterface(), properties} {
+		doc, _ := json.Marshal(i)
+		if err := schema.Validate(bytes.NewReader(doc)); err != nil {
+			t.Errorf("Valid interface %s rejected: %v", doc, err)
+		}
+	}
+
+	invalid := map[string]func(*AstarteInterface){
+		"endpoint without slash":   func(i *AstarteInterface) { i.Mappings[0].Endpoint = "invalid endpoint" },
+		"invalid parameter":        func(i *AstarteInterface) { i.Mappings[0].Endpoint = "/%{sensor-id}/value" },
+		"unterminated parameter":   func(i *AstarteInterface) { i.Mappings[0].Endpoint = "/%{sensor_id/value" },
+		"draft without minor":      func(i *AstarteInterface) { i.MinorVersion = 0 },
+		"aggregated properties":    func(i *AstarteInterface) { i.Type = PropertiesType },
+		"reserved interface name":  func(i *AstarteInterface) { i.Name = "control" },
+		"invalid mapping type":     func(i *AstarteInterface) { i.Mappings[0].Type = "float" },
+		"ttl policy without a ttl": func(i *AstarteInterface) { i.Mappings[0].DatabaseRetentionPolicy = UseTTL },
+	}
+	for name, invalidate := range invalid {
+		i := getValidTestInterface()
+		invalidate(&i)
+		doc, _ := json.Marshal(i)
+		if err := schema.Validate(bytes.NewReader(doc)); err == nil {
+			t.Errorf("Expected %s to be rejected: %s", name, doc)
+		}
+	}
+}
+
+func TestJSONSchemaPatternsAreECMAScriptCompatible(t *testing.T) {
+	// In ECMAScript unicode regular expressions, braces not forming a quantifier must be escaped
+	quantifier := regexp.MustCompile(`^\{[0-9]+(,[0-9]*)?\}`)
+	for _, pattern := range []string{interfaceNameRegexp.String(), endpointRegexp.String()} {
+		for i := 0; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				match := quantifier.FindString(pattern[i:])
+				if match == "" {
+					t.Errorf("Unescaped { at %d in %s", i, pattern)
+					continue
+				}
+				i += len(match) - 1
+			case '}':
+				t.Errorf("Unescaped } at %d in %s", i, pattern)
+			}
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -154,6 +155,69 @@ func ValidateInterfacePath(astarteInterface AstarteInterface, interfacePath stri
 	return err
 }
 
+// ValidatePath checks whether interfacePath can be written to on astarteInterface, before sending any data to
+// Astarte. For individual interfaces, interfacePath must match one of the endpoints, with any parametric
+// segment (e.g. %{sensor_id}) matching a single non-empty token. For object aggregated interfaces, interfacePath
+// is the base path of the object, and must match the endpoints up to their last token.
+func ValidatePath(astarteInterface AstarteInterface, interfacePath string) error {
+	if !strings.HasPrefix(interfacePath, "/") {
+		return fmt.Errorf("path %s must start with a slash", interfacePath)
+	}
+	interfacePathTokens := strings.Split(interfacePath, "/")
+	for _, t := range interfacePathTokens[1:] {
+		if t == "" {
+			return fmt.Errorf("path %s must not contain empty tokens", interfacePath)
+		}
+	}
+
+	if astarteInterface.Aggregation != ObjectAggregation {
+		return ValidateInterfacePath(astarteInterface, interfacePath)
+	}
+
+	for _, mapping := range astarteInterface.Mappings {
+		// The base path of the object is the endpoint without its last token
+		mapping.Endpoint = mapping.Endpoint[:strings.LastIndex(mapping.Endpoint, "/")]
+		if _, ok := mapping.MatchPath(interfacePath); !ok {
+			return fmt.Errorf("Path %s does not exist on Interface %s", interfacePath, astarteInterface.Name)
+		}
+	}
+	return nil
+}
+
+// InvalidPathsError is returned when some of the paths of a message do not exist on an Interface
+type InvalidPathsError struct {
+	Interface string
+	Paths     []string
+}
+
+func (e *InvalidPathsError) Error() string {
+	return fmt.Sprintf("Paths %s do not exist on Interface %s", strings.Join(e.Paths, ", "), e.Interface)
+}
+
+// ValidateObjectPaths checks whether an object can be sent to the object aggregated astarteInterface under basePath,
+// with values mapping the last token of each endpoint to its value. basePath is checked with ValidatePath, and all
+// the keys of values which do not belong to the object are reported, sorted, in an InvalidPathsError.
+func ValidateObjectPaths(astarteInterface AstarteInterface, basePath string, values map[string]interface{}) error {
+	if astarteInterface.Aggregation != ObjectAggregation {
+		return fmt.Errorf("Interface %s is not object aggregated", astarteInterface.Name)
+	}
+	if err := ValidatePath(astarteInterface, basePath); err != nil {
+		return err
+	}
+
+	invalidPaths := []string{}
+	for k := range values {
+		if _, err := InterfaceMappingFromPath(astarteInterface, basePath+"/"+k); err != nil || strings.Contains(k, "/") {
+			invalidPaths = append(invalidPaths, basePath+"/"+k)
+		}
+	}
+	if len(invalidPaths) > 0 {
+		sort.Strings(invalidPaths)
+		return &InvalidPathsError{Interface: astarteInterface.Name, Paths: invalidPaths}
+	}
+	return nil
+}
+
 // NormalizePayload returns a normalized payload, ready to be used for calling APIs or, in general, interact with
 // Astarte. encodeBytes controls whether []byte types should be encoded in base64, used for data structures which do not
 // support bytes (e.g.: JSON)
@@ -303,7 +367,14 @@ func validateType(mappingType AstarteMappingType, value interface{}) error {
 		if mappingType == BinaryBlob {
 			return nil
 		}
-	case time.Time, *time.Time:
+	case time.Time:
+		if mappingType == DateTime {
+			return nil
+		}
+	case *time.Time:
+		if c == nil {
+			return fmt.Errorf("nil *time.Time is not a valid %s", mappingType)
+		}
 		if mappingType == DateTime {
 			return nil
 		}
@@ -331,7 +402,16 @@ func validateType(mappingType AstarteMappingType, value interface{}) error {
 		if mappingType == BinaryBlobArray {
 			return nil
 		}
-	case []time.Time, []*time.Time:
+	case []time.Time:
+		if mappingType == DateTimeArray {
+			return nil
+		}
+	case []*time.Time:
+		for _, t := range c {
+			if t == nil {
+				return fmt.Errorf("nil *time.Time is not a valid element of %s", mappingType)
+			}
+		}
 		if mappingType == DateTimeArray {
 			return nil
 		}
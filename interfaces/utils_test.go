@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -573,3 +574,79 @@ func TestPayloadNormalization(t *testing.T) {
 		t.Error("Multimap conversion failed", NormalizePayload(inMultiMap, false), outMultiMapNonEncoded)
 	}
 }
+
+func TestValidatePath(t *testing.T) {
+	individual := AstarteInterface{
+		Name:        "org.astarte-platform.genericsensors.Values",
+		Aggregation: IndividualAggregation,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/%{channel}/raw", Type: Integer},
+		},
+	}
+	object := AstarteInterface{
+		Name:        "org.astarte-platform.genericsensors.AvailableSensors",
+		Aggregation: ObjectAggregation,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/sensors/%{sensor_id}/name", Type: String},
+			{Endpoint: "/sensors/%{sensor_id}/unit", Type: String},
+		},
+	}
+
+	for _, tc := range []struct {
+		astarteInterface AstarteInterface
+		path             string
+		valid            bool
+	}{
+		{individual, "/temp/value", true},
+		{individual, "/temp/ch0/raw", true},
+		{individual, "/temp", false},
+		{individual, "/temp/value/extra", false},
+		{individual, "//value", false},
+		{individual, "temp/value", false},
+		{object, "/sensors/temp", true},
+		{object, "/sensors/temp/name", false},
+		{object, "/sensors", false},
+		{object, "/other/temp", false},
+	} {
+		err := ValidatePath(tc.astarteInterface, tc.path)
+		if tc.valid && err != nil {
+			t.Errorf("%s on %s: %s", tc.path, tc.astarteInterface.Name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s on %s: expected an error", tc.path, tc.astarteInterface.Name)
+		}
+	}
+}
+
+func TestValidateObjectPaths(t *testing.T) {
+	object := AstarteInterface{
+		Name:        "org.astarte-platform.genericsensors.AvailableSensors",
+		Aggregation: ObjectAggregation,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/sensors/%{sensor_id}/name", Type: String},
+			{Endpoint: "/sensors/%{sensor_id}/unit", Type: String},
+		},
+	}
+
+	if err := ValidateObjectPaths(object, "/sensors/temp", map[string]interface{}{"name": "Temperature", "unit": "K"}); err != nil {
+		t.Error(err)
+	}
+
+	err := ValidateObjectPaths(object, "/sensors/temp", map[string]interface{}{"name": "Temperature", "value": 3, "scale": 1})
+	var invalidPathsError *InvalidPathsError
+	if !errors.As(err, &invalidPathsError) {
+		t.Fatal("Expected an InvalidPathsError, got", err)
+	}
+	if !reflect.DeepEqual(invalidPathsError.Paths, []string{"/sensors/temp/scale", "/sensors/temp/value"}) {
+		t.Error("Wrong invalid paths", invalidPathsError.Paths)
+	}
+
+	if err := ValidateObjectPaths(object, "/other/temp", map[string]interface{}{"name": "Temperature"}); err == nil {
+		t.Error("Expected an error for an invalid base path")
+	}
+	object.Aggregation = IndividualAggregation
+	if err := ValidateObjectPaths(object, "/sensors/temp", map[string]interface{}{"name": "Temperature"}); err == nil {
+		t.Error("Expected an error for an individual interface")
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxInterfaceNameLength = 128
+	maxMappingsCount       = 1024
+)
+
+var (
+	interfaceNameRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*\.([a-zA-Z0-9][a-zA-Z0-9-]*\.)*)?[a-zA-Z][a-zA-Z0-9]*$`)
+	// Literal braces are escaped, so that the pattern is valid in ECMAScript too when exported by JSONSchema
+	endpointRegexp = regexp.MustCompile(`^(/(%\{[a-zA-Z_][a-zA-Z0-9_]*\}|[a-zA-Z_][a-zA-Z0-9_]*)){1,64}$`)
+	// control is used by Astarte for the device-level MQTT control topic
+	reservedInterfaceNames = []string{"control"}
+)
+
+// ValidationError describes a single violation found while validating an AstarteInterface. Field is the JSON
+// path of the offending field, e.g. "mappings[2].endpoint"
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is returned by ParseInterfaceFrom when the parsed Interface is invalid, and holds all the
+// violations reported by Validate
+type ValidationErrors []error
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("invalid Interface: %s", strings.Join(messages, "; "))
+}
+
+// Validate checks astarteInterface against the rules Astarte enforces when installing an Interface, and returns
+// all the violations it finds. Each returned error is a *ValidationError. A nil return value means the Interface
+// is valid.
+func Validate(astarteInterface AstarteInterface) []error {
+	errs := []error{}
+	addError := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case astarteInterface.Name == "":
+		addError("interface_name", "is required")
+	case len(astarteInterface.Name) > maxInterfaceNameLength:
+		addError("interface_name", "must be at most %d characters long", maxInterfaceNameLength)
+	case !interfaceNameRegexp.MatchString(astarteInterface.Name):
+		addError("interface_name", "'%s' is not a valid Interface name", astarteInterface.Name)
+	}
+	for _, reserved := range reservedInterfaceNames {
+		if strings.EqualFold(astarteInterface.Name, reserved) {
+			addError("interface_name", "'%s' is a reserved name", astarteInterface.Name)
+		}
+	}
+
+	if astarteInterface.MajorVersion < 0 {
+		addError("version_major", "must not be negative")
+	}
+	if astarteInterface.MinorVersion < 0 {
+		addError("version_minor", "must not be negative")
+	}
+	if astarteInterface.MajorVersion == 0 && astarteInterface.MinorVersion == 0 {
+		addError("version_minor", "must be greater than 0 when version_major is 0")
+	}
+
+	if err := astarteInterface.Type.IsValid(); err != nil {
+		addError("type", "'%s' is not a valid Interface type", astarteInterface.Type)
+	}
+	if err := astarteInterface.Ownership.IsValid(); err != nil {
+		addError("ownership", "'%s' is not a valid Interface ownership", astarteInterface.Ownership)
+	}
+	if astarteInterface.Aggregation != "" {
+		if err := astarteInterface.Aggregation.IsValid(); err != nil {
+			addError("aggregation", "'%s' is not a valid Interface aggregation", astarteInterface.Aggregation)
+		} else if astarteInterface.Type == PropertiesType && astarteInterface.Aggregation == ObjectAggregation {
+			addError("aggregation", "properties Interfaces cannot have object aggregation")
+		}
+	}
+
+	switch {
+	case len(astarteInterface.Mappings) == 0:
+		addError("mappings", "at least one mapping is required")
+	case len(astarteInterface.Mappings) > maxMappingsCount:
+		addError("mappings", "at most %d mappings are allowed", maxMappingsCount)
+	}
+
+	endpoints := map[string]int{}
+	for i, mapping := range astarteInterface.Mappings {
+		field := fmt.Sprintf("mappings[%d]", i)
+		validateMapping(mapping, field, addError)
+
+		normalized := normalizeEndpoint(mapping.Endpoint)
+		if previous, ok := endpoints[normalized]; ok {
+			addError(field+".endpoint", "'%s' conflicts with mappings[%d].endpoint", mapping.Endpoint, previous)
+		} else {
+			endpoints[normalized] = i
+		}
+	}
+
+	if astarteInterface.Aggregation == ObjectAggregation {
+		validateObjectEndpoints(astarteInterface.Mappings, addError)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateMapping(mapping AstarteInterfaceMapping, field string, addError func(string, string, ...interface{})) {
+	if mapping.Endpoint == "" {
+		addError(field+".endpoint", "is required")
+	} else if !endpointRegexp.MatchString(mapping.Endpoint) {
+		addError(field+".endpoint", "'%s' is not a valid endpoint", mapping.Endpoint)
+	}
+	if err := mapping.Type.IsValid(); err != nil {
+		addError(field+".type", "'%s' is not a valid mapping type", mapping.Type)
+	}
+	if mapping.Reliability != "" {
+		if err := mapping.Reliability.IsValid(); err != nil {
+			addError(field+".reliability", "'%s' is not a valid reliability", mapping.Reliability)
+		}
+	}
+	if mapping.Retention != "" {
+		if err := mapping.Retention.IsValid(); err != nil {
+			addError(field+".retention", "'%s' is not a valid retention", mapping.Retention)
+		}
+	}
+	if mapping.DatabaseRetentionPolicy != "" {
+		if err := mapping.DatabaseRetentionPolicy.IsValid(); err != nil {
+			addError(field+".database_retention_policy", "'%s' is not a valid database retention policy",
+				mapping.DatabaseRetentionPolicy)
+		}
+	}
+	if mapping.DatabaseRetentionTTL < 0 {
+		addError(field+".database_retention_ttl", "must not be negative")
+	}
+	if mapping.DatabaseRetentionPolicy == UseTTL && mapping.DatabaseRetentionTTL == 0 {
+		addError(field+".database_retention_ttl", "is required when database_retention_policy is use_ttl")
+	}
+	if mapping.Expiry < 0 {
+		addError(field+".expiry", "must not be negative")
+	}
+}
+
+// validateObjectEndpoints ensures all the endpoints of an object aggregated Interface share the same base path
+// and have at least two levels
+func validateObjectEndpoints(mappings []AstarteInterfaceMapping, addError func(string, string, ...interface{})) {
+	basePath := ""
+	for i, mapping := range mappings {
+		field := fmt.Sprintf("mappings[%d].endpoint", i)
+		lastSlash := strings.LastIndex(mapping.Endpoint, "/")
+		if lastSlash <= 0 {
+			addError(field, "endpoints of object aggregated Interfaces must have at least two levels")
+			continue
+		}
+		mappingBasePath := normalizeEndpoint(mapping.Endpoint[:lastSlash])
+		if basePath == "" {
+			basePath = mappingBasePath
+		} else if mappingBasePath != basePath {
+			addError(field, "'%s' does not share the common base path of the object aggregation", mapping.Endpoint)
+		}
+	}
+}
+
+// normalizeEndpoint replaces all parameters in endpoint with a placeholder, so that endpoints differing only
+// in the parameter names compare as equal
+func normalizeEndpoint(endpoint string) string {
+	tokens := strings.Split(endpoint, "/")
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "%{") {
+			tokens[i] = "%{}"
+		}
+	}
+	return strings.Join(tokens, "/")
+}
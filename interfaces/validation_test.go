@@ -0,0 +1,93 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interfaces
+
+import (
+	"errors"
+	"testing"
+)
+
+func getValidTestInterface() AstarteInterface {
+	return AstarteInterface{
+		Name:         "org.astarte-platform.genericsensors.Values",
+		MajorVersion: 0,
+		MinorVersion: 1,
+		Type:         DatastreamType,
+		Ownership:    DeviceOwnership,
+		Aggregation:  ObjectAggregation,
+		Mappings: []AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: Double},
+			{Endpoint: "/%{sensor_id}/unit", Type: String},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if errs := Validate(getValidTestInterface()); errs != nil {
+		t.Error("Valid interface failed validation", errs)
+	}
+}
+
+func TestValidateReturnsAllViolations(t *testing.T) {
+	i := getValidTestInterface()
+	i.Name = "org.astarte-platform..Values"
+	i.MinorVersion = 0
+	i.Ownership = "someone"
+	i.Mappings = append(i.Mappings,
+		AstarteInterfaceMapping{Endpoint: "/%{id}/value", Type: Double},
+		AstarteInterfaceMapping{Endpoint: "/other/path/value", Type: "float"},
+		AstarteInterfaceMapping{Endpoint: "invalid endpoint", Type: Integer},
+	)
+
+	expectedFields := []string{
+		"interface_name",
+		"version_minor",
+		"ownership",
+		"mappings[2].endpoint",
+		"mappings[3].type",
+		"mappings[4].endpoint",
+		"mappings[3].endpoint",
+		"mappings[4].endpoint",
+	}
+	errs := Validate(i)
+	if len(errs) != len(expectedFields) {
+		t.Fatalf("Expected %d violations, got %d: %v", len(expectedFields), len(errs), errs)
+	}
+	for index, err := range errs {
+		var validationError *ValidationError
+		if !errors.As(err, &validationError) {
+			t.Fatal("Unexpected error type", err)
+		}
+		if validationError.Field != expectedFields[index] {
+			t.Errorf("Expected violation on %s, got %s", expectedFields[index], err)
+		}
+	}
+}
+
+func TestValidateReservedName(t *testing.T) {
+	i := getValidTestInterface()
+	i.Name = "control"
+	if errs := Validate(i); len(errs) != 1 {
+		t.Error("Expected reserved name violation, got", errs)
+	}
+}
+
+func TestValidatePropertiesObjectAggregation(t *testing.T) {
+	i := getValidTestInterface()
+	i.Type = PropertiesType
+	if errs := Validate(i); len(errs) != 1 {
+		t.Error("Expected aggregation violation, got", errs)
+	}
+}
@@ -17,36 +17,20 @@ package misc
 import (
 	"encoding/base64"
 
+	"github.com/astarte-platform/astarte-go/deviceid"
 	"github.com/google/uuid"
 )
 
 // IsValidAstarteDeviceID returns whether the provided Device ID is a valid Astarte Device ID or not.
+// It is the same as deviceid.ValidateDeviceID.
 func IsValidAstarteDeviceID(deviceID string) bool {
-	decoded, err := base64.RawURLEncoding.DecodeString(deviceID)
-	if err != nil {
-		return false
-	}
-
-	// 16 bytes == 128 bit
-	if len(decoded) != 16 {
-		return false
-	}
-
-	return true
+	return deviceid.ValidateDeviceID(deviceID)
 }
 
-// GenerateRandomAstarteDeviceID returns a new Astarte Device ID on a fully Random basis
+// GenerateRandomAstarteDeviceID returns a new Astarte Device ID on a fully Random basis.
+// It is the same as deviceid.GenerateRandomDeviceID.
 func GenerateRandomAstarteDeviceID() (string, error) {
-	randomUUID, err := uuid.NewRandom()
-	if err != nil {
-		return "", err
-	}
-	deviceID, err := randomUUID.MarshalBinary()
-	if err != nil {
-		return "", err
-	}
-
-	return base64.RawURLEncoding.EncodeToString(deviceID), nil
+	return deviceid.GenerateRandomDeviceID()
 }
 
 // GetNamespacedAstarteDeviceID returns an Astarte Device ID generated from a namespaced arbitrary payload.
@@ -57,14 +41,7 @@ func GetNamespacedAstarteDeviceID(uuidNamespace string, payloadData []byte) (str
 		return "", err
 	}
 
-	deviceUUID := uuid.NewSHA1(encodedUUIDNamespace, payloadData)
-
-	deviceID, err := deviceUUID.MarshalBinary()
-	if err != nil {
-		return "", err
-	}
-
-	return base64.RawURLEncoding.EncodeToString(deviceID), nil
+	return deviceid.GenerateDeviceID(encodedUUIDNamespace, payloadData), nil
 }
 
 // DeviceIDToUUID converts a Device ID from the standard Astarte representation (Base 64 Url Encoded) to
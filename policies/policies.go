@@ -0,0 +1,161 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policies models Astarte Trigger Delivery Policies, which control how the HTTP actions of Triggers
+// are retried or discarded when their delivery fails.
+package policies
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrorKeyword represents a class of HTTP errors an ErrorHandler can be applied to
+type ErrorKeyword string
+
+const (
+	// AnyError matches any error, including network errors
+	AnyError ErrorKeyword = "any_error"
+	// ClientError matches 4xx status codes
+	ClientError ErrorKeyword = "client_error"
+	// ServerError matches 5xx status codes
+	ServerError ErrorKeyword = "server_error"
+)
+
+// IsValid returns an error if ErrorKeyword does not represent a valid error keyword
+func (k ErrorKeyword) IsValid() error {
+	switch k {
+	case AnyError, ClientError, ServerError:
+		return nil
+	}
+	return fmt.Errorf("'%s' is not a valid error keyword", k)
+}
+
+// ErrorRange represents the errors an ErrorHandler is applied to: either all the errors of the class
+// identified by Keyword, or the given HTTP ErrorCodes. Exactly one of the two must be set.
+type ErrorRange struct {
+	Keyword    ErrorKeyword
+	ErrorCodes []int
+}
+
+// MarshalJSON marshals the ErrorRange either as a keyword or as an object holding the error codes
+func (r ErrorRange) MarshalJSON() ([]byte, error) {
+	if r.Keyword != "" {
+		return json.Marshal(r.Keyword)
+	}
+	return json.Marshal(map[string][]int{"error_codes": r.ErrorCodes})
+}
+
+// UnmarshalJSON unmarshals an ErrorRange from either a keyword or an object holding the error codes
+func (r *ErrorRange) UnmarshalJSON(b []byte) error {
+	var keyword string
+	if err := json.Unmarshal(b, &keyword); err == nil {
+		*r = ErrorRange{Keyword: ErrorKeyword(keyword)}
+		return nil
+	}
+	var codes struct {
+		ErrorCodes []int `json:"error_codes"`
+	}
+	if err := json.Unmarshal(b, &codes); err != nil {
+		return err
+	}
+	*r = ErrorRange{ErrorCodes: codes.ErrorCodes}
+	return nil
+}
+
+// Validate returns an error if the ErrorRange is not well formed
+func (r ErrorRange) Validate() error {
+	switch {
+	case r.Keyword != "" && len(r.ErrorCodes) > 0:
+		return errors.New("an error range can have either a keyword or error codes, not both")
+	case r.Keyword != "":
+		return r.Keyword.IsValid()
+	case len(r.ErrorCodes) == 0:
+		return errors.New("an error range requires either a keyword or error codes")
+	}
+	for _, code := range r.ErrorCodes {
+		if code < 400 || code > 599 {
+			return fmt.Errorf("%d is not an HTTP error code", code)
+		}
+	}
+	return nil
+}
+
+// Strategy represents what happens to an event whose delivery failed
+type Strategy string
+
+const (
+	// DiscardStrategy drops the event
+	DiscardStrategy Strategy = "discard"
+	// RetryStrategy retries the delivery of the event, up to the RetryTimes of the Policy
+	RetryStrategy Strategy = "retry"
+)
+
+// IsValid returns an error if Strategy does not represent a valid strategy
+func (s Strategy) IsValid() error {
+	switch s {
+	case DiscardStrategy, RetryStrategy:
+		return nil
+	}
+	return fmt.Errorf("'%s' is not a valid strategy", s)
+}
+
+// ErrorHandler associates a Strategy to a range of errors
+type ErrorHandler struct {
+	On       ErrorRange `json:"on"`
+	Strategy Strategy   `json:"strategy"`
+}
+
+// Policy represents an Astarte Trigger Delivery Policy
+type Policy struct {
+	Name string `json:"name"`
+	// MaximumCapacity is the maximum number of events waiting to be delivered
+	MaximumCapacity int            `json:"maximum_capacity"`
+	ErrorHandlers   []ErrorHandler `json:"error_handlers"`
+	// RetryTimes is how many times delivery is retried for handlers using RetryStrategy
+	RetryTimes int `json:"retry_times,omitempty"`
+	// EventTTL is how many seconds an event is kept waiting for delivery, or forever when 0
+	EventTTL int `json:"event_ttl,omitempty"`
+	// PrefetchCount is how many events can be delivered concurrently
+	PrefetchCount int `json:"prefetch_count,omitempty"`
+}
+
+// Validate returns an error if the Policy is not well formed
+func (p Policy) Validate() error {
+	if p.Name == "" {
+		return errors.New("policy name must not be empty")
+	}
+	if p.MaximumCapacity <= 0 {
+		return errors.New("maximum capacity must be > 0")
+	}
+	if len(p.ErrorHandlers) == 0 {
+		return errors.New("a policy must have at least one error handler")
+	}
+	for _, handler := range p.ErrorHandlers {
+		if err := handler.On.Validate(); err != nil {
+			return err
+		}
+		if err := handler.Strategy.IsValid(); err != nil {
+			return err
+		}
+		if handler.Strategy == RetryStrategy && p.RetryTimes <= 0 {
+			return errors.New("retry times must be > 0 when using the retry strategy")
+		}
+	}
+	if p.RetryTimes < 0 || p.EventTTL < 0 || p.PrefetchCount < 0 {
+		return errors.New("retry times, event TTL and prefetch count must not be negative")
+	}
+	return nil
+}
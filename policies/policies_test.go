@@ -0,0 +1,83 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policies
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyParsing(t *testing.T) {
+	policyJSON := `
+	{
+		"name": "retry_server_errors",
+		"maximum_capacity": 100,
+		"error_handlers": [
+			{"on": {"error_codes": [500, 503]}, "strategy": "retry"},
+			{"on": "any_error", "strategy": "discard"}
+		],
+		"retry_times": 10
+	}`
+
+	policy := Policy{}
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		t.Fatal(err)
+	}
+	expected := Policy{
+		Name:            "retry_server_errors",
+		MaximumCapacity: 100,
+		ErrorHandlers: []ErrorHandler{
+			{On: ErrorRange{ErrorCodes: []int{500, 503}}, Strategy: RetryStrategy},
+			{On: ErrorRange{Keyword: AnyError}, Strategy: DiscardStrategy},
+		},
+		RetryTimes: 10,
+	}
+	if !reflect.DeepEqual(policy, expected) {
+		t.Error("Unexpected policy", policy)
+	}
+	if err := policy.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip := Policy{}
+	if err := json.Unmarshal(b, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, expected) {
+		t.Error("Policy did not survive a round trip", string(b))
+	}
+}
+
+func TestPolicyValidation(t *testing.T) {
+	discardAll := []ErrorHandler{{On: ErrorRange{Keyword: AnyError}, Strategy: DiscardStrategy}}
+	for name, policy := range map[string]Policy{
+		"empty name":       {MaximumCapacity: 10, ErrorHandlers: discardAll},
+		"no capacity":      {Name: "test", ErrorHandlers: discardAll},
+		"no handlers":      {Name: "test", MaximumCapacity: 10},
+		"retry no times":   {Name: "test", MaximumCapacity: 10, ErrorHandlers: []ErrorHandler{{On: ErrorRange{Keyword: AnyError}, Strategy: RetryStrategy}}},
+		"invalid keyword":  {Name: "test", MaximumCapacity: 10, ErrorHandlers: []ErrorHandler{{On: ErrorRange{Keyword: "some_error"}, Strategy: DiscardStrategy}}},
+		"invalid code":     {Name: "test", MaximumCapacity: 10, ErrorHandlers: []ErrorHandler{{On: ErrorRange{ErrorCodes: []int{200}}, Strategy: DiscardStrategy}}},
+		"invalid strategy": {Name: "test", MaximumCapacity: 10, ErrorHandlers: []ErrorHandler{{On: ErrorRange{Keyword: AnyError}, Strategy: "ignore"}}},
+	} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Policy with %s should have failed validation", name)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// bsonElement is a key of a BSON document along with its value
+type bsonElement struct {
+	key   string
+	value interface{}
+}
+
+// marshalBSON encodes elements, in the given order, as a BSON document. Only the types making up the values of
+// Astarte mappings are supported: maps are encoded as documents, and slices other than []byte as arrays.
+func marshalBSON(elements []bsonElement) ([]byte, error) {
+	return appendBSONDocument(nil, elements)
+}
+
+func appendBSONDocument(buf []byte, elements []bsonElement) ([]byte, error) {
+	start := len(buf)
+	// The length is filled in once the document is complete
+	buf = append(buf, 0, 0, 0, 0)
+	for _, element := range elements {
+		var err error
+		if buf, err = appendBSONElement(buf, element.key, element.value); err != nil {
+			return nil, err
+		}
+	}
+	buf = append(buf, 0)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	return buf, nil
+}
+
+func appendBSONElement(buf []byte, key string, value interface{}) ([]byte, error) {
+	header := func(elementType byte) []byte {
+		buf = append(buf, elementType)
+		buf = append(buf, key...)
+		return append(buf, 0)
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return appendUint64(header(0x01), math.Float64bits(v)), nil
+	case float32:
+		return appendUint64(header(0x01), math.Float64bits(float64(v))), nil
+	case string:
+		buf = appendUint32(header(0x02), uint32(len(v)+1))
+		buf = append(buf, v...)
+		return append(buf, 0), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		elements := make([]bsonElement, 0, len(keys))
+		for _, k := range keys {
+			elements = append(elements, bsonElement{key: k, value: v[k]})
+		}
+		return appendBSONDocument(header(0x03), elements)
+	case []byte:
+		buf = appendUint32(header(0x05), uint32(len(v)))
+		// Generic binary subtype
+		buf = append(buf, 0x00)
+		return append(buf, v...), nil
+	case bool:
+		if v {
+			return append(header(0x08), 1), nil
+		}
+		return append(header(0x08), 0), nil
+	case time.Time:
+		return appendUint64(header(0x09), uint64(v.UnixNano()/int64(time.Millisecond))), nil
+	case int32:
+		return appendUint32(header(0x10), uint32(v)), nil
+	case int:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return appendUint32(header(0x10), uint32(v)), nil
+		}
+		return appendUint64(header(0x12), uint64(v)), nil
+	case int64:
+		return appendUint64(header(0x12), uint64(v)), nil
+	}
+
+	// Arrays are documents whose keys are the indexes of their elements
+	slice := reflect.ValueOf(value)
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot encode %T in BSON", value)
+	}
+	elements := make([]bsonElement, slice.Len())
+	for i := range elements {
+		elements[i] = bsonElement{key: strconv.Itoa(i), value: slice.Index(i).Interface()}
+	}
+	return appendBSONDocument(header(0x04), elements)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,56 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMarshalBSON(t *testing.T) {
+	testCases := []struct {
+		elements []bsonElement
+		expected string
+	}{
+		{[]bsonElement{{"hello", "world"}}, "\x16\x00\x00\x00\x02hello\x00\x06\x00\x00\x00world\x00\x00"},
+		{[]bsonElement{{"v", true}}, "\x09\x00\x00\x00\x08v\x00\x01\x00"},
+		{[]bsonElement{{"v", int32(1)}}, "\x0c\x00\x00\x00\x10v\x00\x01\x00\x00\x00\x00"},
+		{[]bsonElement{{"v", int64(-1)}}, "\x10\x00\x00\x00\x12v\x00\xff\xff\xff\xff\xff\xff\xff\xff\x00"},
+		{[]bsonElement{{"v", 1.5}}, "\x10\x00\x00\x00\x01v\x00\x00\x00\x00\x00\x00\x00\xf8\x3f\x00"},
+		{[]bsonElement{{"v", []byte{0xca, 0xfe}}}, "\x0f\x00\x00\x00\x05v\x00\x02\x00\x00\x00\x00\xca\xfe\x00"},
+		{[]bsonElement{{"t", time.Unix(1, 0)}}, "\x10\x00\x00\x00\x09t\x00\xe8\x03\x00\x00\x00\x00\x00\x00\x00"},
+		{[]bsonElement{{"v", []interface{}{int32(1)}}},
+			"\x14\x00\x00\x00\x04v\x00\x0c\x00\x00\x00\x100\x00\x01\x00\x00\x00\x00\x00"},
+		{[]bsonElement{{"v", []int32{1}}},
+			"\x14\x00\x00\x00\x04v\x00\x0c\x00\x00\x00\x100\x00\x01\x00\x00\x00\x00\x00"},
+		// Keys of objects are sorted, so that encoding is deterministic
+		{[]bsonElement{{"v", map[string]interface{}{"b": false, "a": true}}},
+			"\x15\x00\x00\x00\x03v\x00\x0d\x00\x00\x00\x08a\x00\x01\x08b\x00\x00\x00\x00"},
+	}
+	for _, tc := range testCases {
+		encoded, err := marshalBSON(tc.elements)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, []byte(tc.expected)) {
+			t.Errorf("Unexpected encoding of %v: %q", tc.elements, encoded)
+		}
+	}
+
+	if _, err := marshalBSON([]bsonElement{{"v", struct{}{}}}); err == nil {
+		t.Error("Expected an error for an unsupported type")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"context"
+	"crypto/tls"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// disconnectQuiesceMillis is how long closing a connection waits for in-flight messages to be delivered
+const disconnectQuiesceMillis = 250
+
+// connection is an MQTT connection to the broker of Astarte, authenticated as a Device
+type connection interface {
+	publish(ctx context.Context, topic string, qos byte, payload []byte) error
+	close()
+}
+
+// dialFunc connects to brokerURL as clientID, authenticating with the client certificate in tlsConfig
+type dialFunc func(ctx context.Context, brokerURL, clientID string, tlsConfig *tls.Config) (connection, error)
+
+type mqttConnection struct {
+	client mqtt.Client
+}
+
+func dialMQTT(ctx context.Context, brokerURL, clientID string, tlsConfig *tls.Config) (connection, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(brokerURL).
+		SetClientID(clientID).
+		SetTLSConfig(tlsConfig).
+		SetCleanSession(true)
+	mqttClient := mqtt.NewClient(opts)
+	if err := waitToken(ctx, mqttClient.Connect()); err != nil {
+		mqttClient.Disconnect(0)
+		return nil, err
+	}
+	return &mqttConnection{client: mqttClient}, nil
+}
+
+func (c *mqttConnection) publish(ctx context.Context, topic string, qos byte, payload []byte) error {
+	return waitToken(ctx, c.client.Publish(topic, qos, false, payload))
+}
+
+func (c *mqttConnection) close() {
+	c.client.Disconnect(disconnectQuiesceMillis)
+}
+
+// waitToken waits for the operation tracked by token to complete, or for ctx to be done
+func waitToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
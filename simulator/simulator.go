@@ -0,0 +1,376 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulator publishes generated values to Astarte Datastream interfaces on a schedule, as a Device would,
+// to load test Realms and benchmark the pipelines consuming their data.
+//
+// A Simulator obtains the credentials of the Device from Pairing by means of its Credentials Secret, and
+// publishes values over MQTT with the astarte_mqtt_v1 protocol: hence only device-owned Datastream interfaces
+// can be simulated.
+package simulator
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+var (
+	// ErrUnsupportedInterface is returned by New when an interface is not a device-owned Datastream
+	ErrUnsupportedInterface = errors.New("only device-owned datastream interfaces can be simulated")
+	// ErrNotConnected is returned when publishing values before calling Connect
+	ErrNotConnected = errors.New("simulator is not connected")
+)
+
+// Generator returns the value to publish on mapping at the given step, starting from 0. The value must be
+// compatible with the type of the mapping. A Generator is never called concurrently by a Simulator.
+type Generator func(mapping interfaces.AstarteInterfaceMapping, step int) interface{}
+
+// Option configures a Simulator
+type Option func(s *Simulator)
+
+// WithInterval makes the Simulator publish a round of values every interval, rather than every second.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Simulator) {
+		s.interval = interval
+	}
+}
+
+// WithPathParameters sets the values of the parameters of parametric endpoints, e.g. {"sensor_id": "room1"}
+// for "/%{sensor_id}/temperature". Every parameter of the simulated interfaces must have a value.
+func WithPathParameters(params map[string]string) Option {
+	return func(s *Simulator) {
+		s.params = params
+	}
+}
+
+// WithGenerator makes the Simulator generate values with generator, rather than with RandomWalkGenerator.
+func WithGenerator(generator Generator) Option {
+	return func(s *Simulator) {
+		s.generator = generator
+	}
+}
+
+// WithErrorHandler makes Run report publishing errors to handler and keep going, rather than stopping at the
+// first error.
+func WithErrorHandler(handler func(error)) Option {
+	return func(s *Simulator) {
+		s.errorHandler = handler
+	}
+}
+
+// WithTLSConfig makes the Simulator connect to the broker with tlsConfig, e.g. to trust the CA of a test
+// instance. The client certificate of the Device is added to it by Connect.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(s *Simulator) {
+		s.tlsConfig = tlsConfig
+	}
+}
+
+// Simulator publishes generated values on behalf of a Device. Create one with New.
+type Simulator struct {
+	client            *client.Client
+	realm             string
+	deviceID          string
+	credentialsSecret string
+	interval          time.Duration
+	params            map[string]string
+	generator         Generator
+	errorHandler      func(error)
+	tlsConfig         *tls.Config
+	targets           []target
+	step              int
+
+	// dial opens the MQTT connection, it is replaced in tests
+	dial dialFunc
+	conn connection
+}
+
+// target is a single message published at each round: an individual value or a whole object
+type target struct {
+	astarteInterface interfaces.AstarteInterface
+	path             string
+	mappings         []interfaces.AstarteInterfaceMapping
+}
+
+// New returns a Simulator publishing values to astarteInterfaces as deviceID in realm. astarteClient is used to
+// obtain the credentials of the Device from Pairing, authenticating with its credentialsSecret, while values are
+// published over MQTT. Each round publishes a value on every mapping of individual interfaces, and a whole object
+// on object aggregated interfaces.
+func New(astarteClient *client.Client, realm, deviceID, credentialsSecret string,
+	astarteInterfaces []interfaces.AstarteInterface, opts ...Option) (*Simulator, error) {
+	s := &Simulator{
+		client:            astarteClient,
+		realm:             realm,
+		deviceID:          deviceID,
+		credentialsSecret: credentialsSecret,
+		interval:          time.Second,
+		dial:              dialMQTT,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	if s.generator == nil {
+		s.generator = RandomWalkGenerator(time.Now().UnixNano())
+	}
+
+	for _, astarteInterface := range astarteInterfaces {
+		if astarteInterface.Type != interfaces.DatastreamType || astarteInterface.Ownership != interfaces.DeviceOwnership {
+			return nil, fmt.Errorf("%s: %w", astarteInterface.Name, ErrUnsupportedInterface)
+		}
+		targets, err := s.buildTargets(astarteInterface)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", astarteInterface.Name, err)
+		}
+		s.targets = append(s.targets, targets...)
+	}
+	return s, nil
+}
+
+func (s *Simulator) buildTargets(astarteInterface interfaces.AstarteInterface) ([]target, error) {
+	if astarteInterface.Aggregation == interfaces.ObjectAggregation {
+		if len(astarteInterface.Mappings) == 0 {
+			return nil, nil
+		}
+		// All the mappings of an object share the same endpoint, except for their last token
+		endpoint := astarteInterface.Mappings[0].Endpoint
+		base := interfaces.AstarteInterfaceMapping{Endpoint: endpoint[:strings.LastIndex(endpoint, "/")]}
+		path, err := base.ExpandPath(s.params)
+		if err != nil {
+			return nil, err
+		}
+		return []target{{astarteInterface: astarteInterface, path: path, mappings: astarteInterface.Mappings}}, nil
+	}
+
+	targets := make([]target, 0, len(astarteInterface.Mappings))
+	for _, mapping := range astarteInterface.Mappings {
+		path, err := mapping.ExpandPath(s.params)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{
+			astarteInterface: astarteInterface,
+			path:             path,
+			mappings:         []interfaces.AstarteInterfaceMapping{mapping},
+		})
+	}
+	return targets, nil
+}
+
+// Connect obtains a certificate for the Device from Pairing and connects to its MQTT broker. Once connected, it
+// declares the simulated interfaces as the introspection of the Device. Connect must be called before Step or Run.
+func (s *Simulator) Connect(ctx context.Context) error {
+	if s.client == nil || s.client.Pairing == nil {
+		return errors.New("the Pairing API is needed to obtain the credentials of the Device")
+	}
+	csrPEM, privateKeyPEM, err := client.GenerateDeviceCSR(s.realm, s.deviceID)
+	if err != nil {
+		return err
+	}
+	certificatePEM, err := s.client.Pairing.ObtainCredentials(s.realm, s.deviceID, s.credentialsSecret, csrPEM)
+	if err != nil {
+		return fmt.Errorf("could not obtain credentials: %w", err)
+	}
+	brokerURL, err := s.client.Pairing.GetMQTTv1Transport(s.realm, s.deviceID, s.credentialsSecret)
+	if err != nil {
+		return fmt.Errorf("could not get the broker URL: %w", err)
+	}
+	certificate, err := tls.X509KeyPair(certificatePEM, privateKeyPEM)
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{}
+	if s.tlsConfig != nil {
+		tlsConfig = s.tlsConfig.Clone()
+	}
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	conn, err := s.dial(ctx, brokerURL, s.topic(""), tlsConfig)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", brokerURL, err)
+	}
+	// Sessions are not persisted, so the Device has to declare its introspection each time it connects, and
+	// tell Astarte it has no cached Properties
+	if err := conn.publish(ctx, s.topic(""), 2, []byte(s.introspection())); err != nil {
+		conn.close()
+		return err
+	}
+	if err := conn.publish(ctx, s.topic("/control/emptyCache"), 2, []byte("1")); err != nil {
+		conn.close()
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// Close disconnects the Simulator from the broker.
+func (s *Simulator) Close() {
+	if s.conn != nil {
+		s.conn.close()
+		s.conn = nil
+	}
+}
+
+// topic returns the MQTT topic of the Device with the given suffix, i.e. "<realm>/<device ID><suffix>"
+func (s *Simulator) topic(suffix string) string {
+	return s.realm + "/" + s.deviceID + suffix
+}
+
+// introspection returns the introspection of the Device, listing the simulated interfaces
+func (s *Simulator) introspection() string {
+	entries := []string{}
+	seen := map[string]bool{}
+	for _, t := range s.targets {
+		if seen[t.astarteInterface.Name] {
+			continue
+		}
+		seen[t.astarteInterface.Name] = true
+		entries = append(entries, fmt.Sprintf("%s:%d:%d", t.astarteInterface.Name, t.astarteInterface.MajorVersion,
+			t.astarteInterface.MinorVersion))
+	}
+	return strings.Join(entries, ";")
+}
+
+// Run publishes a round of values every interval until ctx is done, returning ctx.Err(). Unless
+// WithErrorHandler is given, Run stops and returns the first publishing error.
+func (s *Simulator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Step(ctx); err != nil {
+			if s.errorHandler == nil {
+				return err
+			}
+			s.errorHandler(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step publishes a single round of values right away. All the values of the round are attempted, and the first
+// error encountered is returned.
+func (s *Simulator) Step(ctx context.Context) error {
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+	var firstErr error
+	for _, t := range s.targets {
+		if err := s.publish(ctx, t); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s%s: %w", t.astarteInterface.Name, t.path, err)
+		}
+	}
+	s.step++
+	return firstErr
+}
+
+func (s *Simulator) publish(ctx context.Context, t target) error {
+	var value interface{}
+	if t.astarteInterface.Aggregation == interfaces.ObjectAggregation {
+		values := make(map[string]interface{}, len(t.mappings))
+		for _, mapping := range t.mappings {
+			values[mapping.Endpoint[strings.LastIndex(mapping.Endpoint, "/")+1:]] = s.generator(mapping, s.step)
+		}
+		value = values
+	} else {
+		value = s.generator(t.mappings[0], s.step)
+	}
+
+	message := []bsonElement{{key: "v", value: value}}
+	if t.astarteInterface.ExplicitTimestamp || t.mappings[0].ExplicitTimestamp {
+		message = append(message, bsonElement{key: "t", value: time.Now()})
+	}
+	payload, err := marshalBSON(message)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.publish(ctx, s.topic("/"+t.astarteInterface.Name+t.path), qos(t.mappings[0].Reliability), payload)
+}
+
+// qos returns the MQTT QoS matching reliability
+func qos(reliability interfaces.AstarteMappingReliability) byte {
+	switch reliability {
+	case interfaces.GuaranteedReliability:
+		return 1
+	case interfaces.UniqueReliability:
+		return 2
+	}
+	return 0
+}
+
+// RandomWalkGenerator returns a Generator producing plausible sensor readings out of seed: numbers follow a
+// random walk around a slow sine wave, booleans flip now and then, and strings, blobs and dates change at each
+// step. Arrays hold three such values.
+func RandomWalkGenerator(seed int64) Generator {
+	rnd := rand.New(rand.NewSource(seed))
+	levels := map[string]float64{}
+
+	scalar := func(mappingType interfaces.AstarteMappingType, key string, step int) interface{} {
+		switch mappingType {
+		case interfaces.Double, interfaces.Integer, interfaces.LongInteger:
+			levels[key] += rnd.NormFloat64()
+			value := 20 + 5*math.Sin(float64(step)/10) + levels[key]
+			switch mappingType {
+			case interfaces.Integer:
+				return int32(math.Round(value))
+			case interfaces.LongInteger:
+				return int64(math.Round(value))
+			}
+			return value
+		case interfaces.Boolean:
+			if rnd.Intn(10) == 0 {
+				levels[key] = 1 - levels[key]
+			}
+			return levels[key] == 1
+		case interfaces.String:
+			return fmt.Sprintf("sample-%d", step)
+		case interfaces.BinaryBlob:
+			blob := make([]byte, 8)
+			rnd.Read(blob)
+			return blob
+		case interfaces.DateTime:
+			return time.Now().UTC()
+		}
+		return nil
+	}
+
+	return func(mapping interfaces.AstarteInterfaceMapping, step int) interface{} {
+		elementType := interfaces.AstarteMappingType(strings.TrimSuffix(string(mapping.Type), "array"))
+		if elementType == mapping.Type {
+			return scalar(mapping.Type, mapping.Endpoint, step)
+		}
+		values := make([]interface{}, 3)
+		for i := range values {
+			values[i] = scalar(elementType, fmt.Sprintf("%s[%d]", mapping.Endpoint, i), step)
+		}
+		return values
+	}
+}
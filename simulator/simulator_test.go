@@ -0,0 +1,346 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+const (
+	testRealm             = "test"
+	testDeviceID          = "f0VMRgIBAQAAAAAAAAAAAA"
+	testCredentialsSecret = "TTkd5OgB13X/3qU0LXU7OCxyTXz5QHM2NY1IgidtPOs="
+	testBrokerURL         = "mqtts://broker.astarte.example.com:8883/"
+)
+
+var (
+	testIndividualInterface = interfaces.AstarteInterface{
+		Name:         "org.astarte-platform.genericsensors.Values",
+		MajorVersion: 1,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.DeviceOwnership,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/value", Type: interfaces.Double, ExplicitTimestamp: true,
+				Reliability: interfaces.GuaranteedReliability},
+			{Endpoint: "/%{sensor_id}/samples", Type: interfaces.IntegerArray},
+		},
+	}
+	testObjectInterface = interfaces.AstarteInterface{
+		Name:         "org.astarte-platform.genericsensors.Geolocation",
+		MinorVersion: 2,
+		Type:         interfaces.DatastreamType,
+		Ownership:    interfaces.DeviceOwnership,
+		Aggregation:  interfaces.ObjectAggregation,
+		Mappings: []interfaces.AstarteInterfaceMapping{
+			{Endpoint: "/%{sensor_id}/latitude", Type: interfaces.Double, Reliability: interfaces.UniqueReliability},
+			{Endpoint: "/%{sensor_id}/available", Type: interfaces.Boolean, Reliability: interfaces.UniqueReliability},
+		},
+	}
+)
+
+type testMessage struct {
+	topic   string
+	qos     byte
+	payload []byte
+}
+
+// testConnection records the messages published through it
+type testConnection struct {
+	lock     sync.Mutex
+	messages []testMessage
+	closed   bool
+}
+
+func (c *testConnection) publish(ctx context.Context, topic string, qos byte, payload []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, testMessage{topic: topic, qos: qos, payload: payload})
+	return nil
+}
+
+func (c *testConnection) close() {
+	c.closed = true
+}
+
+// getPairingTestClient returns a Client talking to a Pairing API which signs the CSRs of testDeviceID
+func getPairingTestClient(t *testing.T) (*client.Client, *httptest.Server) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	devicePath := fmt.Sprintf("/pairing/v1/%s/devices/%s", testRealm, testDeviceID)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer "+testCredentialsSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case devicePath:
+			fmt.Fprintf(w, `{"data":{"version":"1.0.0","status":"pending","protocols":{"astarte_mqtt_v1":{"broker_url":"%s"}}}}`,
+				testBrokerURL)
+		case devicePath + "/protocols/astarte_mqtt_v1/credentials":
+			var body struct {
+				Data struct {
+					CSR string `json:"csr"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			block, _ := pem.Decode([]byte(body.Data.CSR))
+			if block == nil {
+				t.Error("Invalid CSR", body.Data.CSR)
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				Subject:      csr.Subject,
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}
+			certificate, err := x509.CreateCertificate(rand.Reader, template, caTemplate, csr.PublicKey, caKey)
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"client_crt": string(certificatePEM)}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	astarteClient, err := client.NewClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return astarteClient, server
+}
+
+// getConnectedSimulator returns a Simulator connected to a testConnection
+func getConnectedSimulator(t *testing.T, astarteInterfaces []interfaces.AstarteInterface, opts ...Option) (*Simulator, *testConnection) {
+	astarteClient, server := getPairingTestClient(t)
+	defer server.Close()
+
+	s, err := New(astarteClient, testRealm, testDeviceID, testCredentialsSecret, astarteInterfaces, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &testConnection{}
+	s.dial = func(ctx context.Context, brokerURL, clientID string, tlsConfig *tls.Config) (connection, error) {
+		if brokerURL != testBrokerURL {
+			t.Error("Unexpected broker URL", brokerURL)
+		}
+		if clientID != testRealm+"/"+testDeviceID {
+			t.Error("Unexpected client ID", clientID)
+		}
+		if len(tlsConfig.Certificates) != 1 {
+			t.Error("Missing client certificate")
+		}
+		return conn, nil
+	}
+	if err := s.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s, conn
+}
+
+func TestSimulatorConnect(t *testing.T) {
+	s, conn := getConnectedSimulator(t, []interfaces.AstarteInterface{testIndividualInterface, testObjectInterface},
+		WithPathParameters(map[string]string{"sensor_id": "room1"}))
+
+	expected := []testMessage{
+		{testRealm + "/" + testDeviceID, 2,
+			[]byte("org.astarte-platform.genericsensors.Values:1:0;org.astarte-platform.genericsensors.Geolocation:0:2")},
+		{testRealm + "/" + testDeviceID + "/control/emptyCache", 2, []byte("1")},
+	}
+	if len(conn.messages) != len(expected) {
+		t.Fatal("Unexpected messages", conn.messages)
+	}
+	for i := range expected {
+		if conn.messages[i].topic != expected[i].topic || conn.messages[i].qos != expected[i].qos ||
+			string(conn.messages[i].payload) != string(expected[i].payload) {
+			t.Errorf("Unexpected message %d: %+v", i, conn.messages[i])
+		}
+	}
+
+	s.Close()
+	if !conn.closed {
+		t.Error("Connection was not closed")
+	}
+	if err := s.Step(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Error("Expected ErrNotConnected, got", err)
+	}
+}
+
+func TestSimulatorConnectWrongSecret(t *testing.T) {
+	astarteClient, server := getPairingTestClient(t)
+	defer server.Close()
+
+	s, err := New(astarteClient, testRealm, testDeviceID, "wrong", []interfaces.AstarteInterface{testObjectInterface},
+		WithPathParameters(map[string]string{"sensor_id": "room1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apiError *client.AstarteAPIError
+	if err := s.Connect(context.Background()); !errors.As(err, &apiError) || apiError.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected a 401 error, got", err)
+	}
+}
+
+func TestSimulatorStep(t *testing.T) {
+	generator := func(mapping interfaces.AstarteInterfaceMapping, step int) interface{} {
+		switch mapping.Type {
+		case interfaces.IntegerArray:
+			return []interface{}{int32(step)}
+		case interfaces.Boolean:
+			return true
+		}
+		return 1.5
+	}
+	s, conn := getConnectedSimulator(t, []interfaces.AstarteInterface{testIndividualInterface, testObjectInterface},
+		WithPathParameters(map[string]string{"sensor_id": "room1"}), WithGenerator(generator))
+	conn.messages = nil
+
+	if err := s.Step(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	topic := testRealm + "/" + testDeviceID + "/"
+	samples, _ := marshalBSON([]bsonElement{{"v", []interface{}{int32(0)}}})
+	object, _ := marshalBSON([]bsonElement{{"v", map[string]interface{}{"latitude": 1.5, "available": true}}})
+	expected := []testMessage{
+		{topic + testIndividualInterface.Name + "/room1/value", 1, nil},
+		{topic + testIndividualInterface.Name + "/room1/samples", 0, samples},
+		{topic + testObjectInterface.Name + "/room1", 2, object},
+	}
+	if len(conn.messages) != len(expected) {
+		t.Fatal("Unexpected messages", conn.messages)
+	}
+	for i := range expected {
+		if conn.messages[i].topic != expected[i].topic || conn.messages[i].qos != expected[i].qos {
+			t.Errorf("Unexpected message %d: %+v", i, conn.messages[i])
+		}
+		if expected[i].payload != nil && string(conn.messages[i].payload) != string(expected[i].payload) {
+			t.Errorf("Unexpected payload of message %d: %q", i, conn.messages[i].payload)
+		}
+	}
+
+	// The value with an explicit timestamp carries both v and t
+	value := conn.messages[0].payload
+	if len(value) != 27 || value[4] != 0x01 || value[15] != 0x09 {
+		t.Errorf("Unexpected payload with timestamp: %q", value)
+	}
+}
+
+func TestSimulatorRun(t *testing.T) {
+	steps := 0
+	generator := func(mapping interfaces.AstarteInterfaceMapping, step int) interface{} {
+		steps = step + 1
+		return float64(step)
+	}
+	individual := testIndividualInterface
+	individual.Mappings = individual.Mappings[:1]
+	s, conn := getConnectedSimulator(t, []interfaces.AstarteInterface{individual},
+		WithPathParameters(map[string]string{"sensor_id": "room1"}), WithInterval(time.Millisecond), WithGenerator(generator))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected context.DeadlineExceeded, got", err)
+	}
+	if steps < 2 {
+		t.Error("Expected several rounds, got", steps)
+	}
+	// Introspection and emptyCache are followed by a message per round
+	if len(conn.messages) != steps+2 {
+		t.Errorf("Expected %d messages, got %d", steps+2, len(conn.messages))
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	properties := testIndividualInterface
+	properties.Type = interfaces.PropertiesType
+	serverOwned := testIndividualInterface
+	serverOwned.Ownership = interfaces.ServerOwnership
+	for _, astarteInterface := range []interfaces.AstarteInterface{properties, serverOwned} {
+		if _, err := New(nil, testRealm, testDeviceID, testCredentialsSecret,
+			[]interfaces.AstarteInterface{astarteInterface}); !errors.Is(err, ErrUnsupportedInterface) {
+			t.Error("Expected ErrUnsupportedInterface, got", err)
+		}
+	}
+	if _, err := New(nil, testRealm, testDeviceID, testCredentialsSecret,
+		[]interfaces.AstarteInterface{testIndividualInterface}); err == nil {
+		t.Error("Expected an error for missing path parameters")
+	}
+}
+
+func TestRandomWalkGenerator(t *testing.T) {
+	generator := RandomWalkGenerator(42)
+	for _, mappingType := range []interfaces.AstarteMappingType{
+		interfaces.Double, interfaces.Integer, interfaces.LongInteger, interfaces.Boolean, interfaces.String,
+		interfaces.BinaryBlob, interfaces.DateTime, interfaces.DoubleArray, interfaces.StringArray,
+	} {
+		mapping := interfaces.AstarteInterfaceMapping{Endpoint: "/value", Type: mappingType}
+		value := generator(mapping, 0)
+		if err := interfaces.ValidateIndividualMessage(interfaces.AstarteInterface{
+			Type: interfaces.DatastreamType, Mappings: []interfaces.AstarteInterfaceMapping{mapping},
+		}, "/value", value); err != nil {
+			t.Errorf("Invalid %s value: %v", mappingType, err)
+		}
+		if _, err := marshalBSON([]bsonElement{{"v", value}}); err != nil {
+			t.Errorf("%s value cannot be encoded: %v", mappingType, err)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil provides an in-memory Astarte, to test code using the client package without a real
+// Astarte instance.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+// DefaultRealm is the Realm served by a MockAstarte, unless WithRealm is given
+const DefaultRealm = "test"
+
+// RecordedRequest is a request received by a MockAstarte
+type RecordedRequest struct {
+	Method string
+	// Path is the path of the request, including the API prefix, e.g. "/appengine/v1/test/devices"
+	Path  string
+	Query url.Values
+	Body  []byte
+}
+
+// MockAstarte is an in-memory Astarte serving a subset of the AppEngine and Realm Management APIs for a single
+// Realm: Device listing, details, aliases and stats, reading and writing Interface values, and listing,
+// getting and installing Interfaces. Point a client.Client to its URL to use it. Any token is accepted.
+type MockAstarte struct {
+	*httptest.Server
+
+	realm      string
+	lock       sync.Mutex
+	devices    map[string]client.DeviceDetails
+	interfaces map[string]map[int]interfaces.AstarteInterface
+	// values holds Interface values by Device ID, Interface name and path
+	values   map[string]map[string]map[string]interface{}
+	requests []RecordedRequest
+}
+
+// MockOption configures a MockAstarte
+type MockOption func(m *MockAstarte)
+
+// WithRealm makes the MockAstarte serve realm rather than DefaultRealm
+func WithRealm(realm string) MockOption {
+	return func(m *MockAstarte) {
+		m.realm = realm
+	}
+}
+
+// WithDevices preloads devices into the MockAstarte
+func WithDevices(devices ...client.DeviceDetails) MockOption {
+	return func(m *MockAstarte) {
+		for _, device := range devices {
+			m.devices[device.DeviceID] = device
+		}
+	}
+}
+
+// WithInterfaces preloads astarteInterfaces into the Realm of the MockAstarte
+func WithInterfaces(astarteInterfaces ...interfaces.AstarteInterface) MockOption {
+	return func(m *MockAstarte) {
+		for _, astarteInterface := range astarteInterfaces {
+			m.addInterface(astarteInterface)
+		}
+	}
+}
+
+// WithValue sets value on interfacePath of interfaceName for deviceID
+func WithValue(deviceID, interfaceName, interfacePath string, value interface{}) MockOption {
+	return func(m *MockAstarte) {
+		m.setValue(deviceID, interfaceName, interfacePath, value)
+	}
+}
+
+// NewMockAstarte starts a MockAstarte configured with options. It must be closed with Close once done.
+func NewMockAstarte(options ...MockOption) *MockAstarte {
+	m := &MockAstarte{
+		realm:      DefaultRealm,
+		devices:    map[string]client.DeviceDetails{},
+		interfaces: map[string]map[int]interfaces.AstarteInterface{},
+		values:     map[string]map[string]map[string]interface{}{},
+	}
+	for _, option := range options {
+		option(m)
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+
+	return m
+}
+
+// Requests returns all the requests received so far, in order.
+func (m *MockAstarte) Requests() []RecordedRequest {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]RecordedRequest(nil), m.requests...)
+}
+
+// ResetRequests forgets all the requests received so far.
+func (m *MockAstarte) ResetRequests() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = nil
+}
+
+// AssertRequested fails t unless at least a request with the given method and path has been received.
+func (m *MockAstarte) AssertRequested(t testing.TB, method, path string) {
+	t.Helper()
+	if m.countRequests(method, path) == 0 {
+		t.Errorf("Expected a %s request to %s, got %v", method, path, m.describeRequests())
+	}
+}
+
+// AssertNotRequested fails t if a request with the given method and path has been received.
+func (m *MockAstarte) AssertNotRequested(t testing.TB, method, path string) {
+	t.Helper()
+	if count := m.countRequests(method, path); count > 0 {
+		t.Errorf("Expected no %s requests to %s, got %d", method, path, count)
+	}
+}
+
+// Value returns the value currently set on interfacePath of interfaceName for deviceID, if any.
+func (m *MockAstarte) Value(deviceID, interfaceName, interfacePath string) (interface{}, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	value, ok := m.values[deviceID][interfaceName][interfacePath]
+	return value, ok
+}
+
+func (m *MockAstarte) countRequests(method, path string) int {
+	count := 0
+	for _, request := range m.Requests() {
+		if request.Method == method && request.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MockAstarte) describeRequests() []string {
+	descriptions := []string{}
+	for _, request := range m.Requests() {
+		descriptions = append(descriptions, request.Method+" "+request.Path)
+	}
+	return descriptions
+}
+
+func (m *MockAstarte) addInterface(astarteInterface interfaces.AstarteInterface) {
+	if _, ok := m.interfaces[astarteInterface.Name]; !ok {
+		m.interfaces[astarteInterface.Name] = map[int]interfaces.AstarteInterface{}
+	}
+	m.interfaces[astarteInterface.Name][astarteInterface.MajorVersion] = astarteInterface
+}
+
+func (m *MockAstarte) setValue(deviceID, interfaceName, interfacePath string, value interface{}) {
+	if _, ok := m.values[deviceID]; !ok {
+		m.values[deviceID] = map[string]map[string]interface{}{}
+	}
+	if _, ok := m.values[deviceID][interfaceName]; !ok {
+		m.values[deviceID][interfaceName] = map[string]interface{}{}
+	}
+	m.values[deviceID][interfaceName][interfacePath] = value
+}
+
+func (m *MockAstarte) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, RecordedRequest{Method: req.Method, Path: req.URL.Path, Query: req.URL.Query(), Body: body})
+
+	if req.Header.Get("Authorization") == "" {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	appEnginePrefix := fmt.Sprintf("/appengine/v1/%s/", m.realm)
+	realmManagementPrefix := fmt.Sprintf("/realmmanagement/v1/%s/", m.realm)
+	switch {
+	case strings.HasPrefix(req.URL.Path, appEnginePrefix):
+		m.serveAppEngine(w, req, strings.Split(strings.TrimPrefix(req.URL.Path, appEnginePrefix), "/"), body)
+	case strings.HasPrefix(req.URL.Path, realmManagementPrefix):
+		m.serveRealmManagement(w, req, strings.Split(strings.TrimPrefix(req.URL.Path, realmManagementPrefix), "/"), body)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (m *MockAstarte) serveAppEngine(w http.ResponseWriter, req *http.Request, tokens []string, body []byte) {
+	switch {
+	case len(tokens) == 1 && tokens[0] == "devices" && req.Method == http.MethodGet:
+		m.serveDeviceList(w, req)
+	case len(tokens) == 2 && tokens[0] == "stats" && tokens[1] == "devices":
+		stats := client.DevicesStats{TotalDevices: int64(len(m.devices))}
+		for _, device := range m.devices {
+			if device.Connected {
+				stats.ConnectedDevices++
+			}
+		}
+		writeData(w, http.StatusOK, stats)
+	case len(tokens) >= 2 && (tokens[0] == "devices" || tokens[0] == "devices-by-alias"):
+		device, ok := m.findDevice(tokens[0], tokens[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "Device not found")
+			return
+		}
+		if len(tokens) == 2 {
+			m.serveDevice(w, req, device)
+			return
+		}
+		if len(tokens) >= 4 && tokens[2] == "interfaces" {
+			m.serveInterfaceValues(w, req, device.DeviceID, tokens[3], "/"+strings.Join(tokens[4:], "/"), body)
+			return
+		}
+		writeError(w, http.StatusNotFound, "Not found")
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (m *MockAstarte) serveDeviceList(w http.ResponseWriter, req *http.Request) {
+	deviceIDs := []string{}
+	for deviceID := range m.devices {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	sort.Strings(deviceIDs)
+
+	// Paginate using the index of the next Device as from_token
+	from, _ := strconv.Atoi(req.URL.Query().Get("from_token"))
+	if from > len(deviceIDs) {
+		from = len(deviceIDs)
+	}
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || from+limit > len(deviceIDs) {
+		limit = len(deviceIDs) - from
+	}
+	page := deviceIDs[from : from+limit]
+
+	links := client.Links{Self: fmt.Sprintf("/v1/%s/devices", m.realm)}
+	if from+limit < len(deviceIDs) {
+		links.Next = fmt.Sprintf("/v1/%s/devices?from_token=%d", m.realm, from+limit)
+	}
+	var data interface{} = page
+	if req.URL.Query().Get("details") == "true" {
+		details := []client.DeviceDetails{}
+		for _, deviceID := range page {
+			details = append(details, m.devices[deviceID])
+		}
+		data = details
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data, "links": links})
+}
+
+func (m *MockAstarte) findDevice(collection, identifier string) (client.DeviceDetails, bool) {
+	if collection == "devices" {
+		device, ok := m.devices[identifier]
+		return device, ok
+	}
+	for _, device := range m.devices {
+		for _, alias := range device.Aliases {
+			if alias == identifier {
+				return device, true
+			}
+		}
+	}
+	return client.DeviceDetails{}, false
+}
+
+func (m *MockAstarte) serveDevice(w http.ResponseWriter, req *http.Request, device client.DeviceDetails) {
+	switch req.Method {
+	case http.MethodGet:
+		writeData(w, http.StatusOK, device)
+	case http.MethodDelete:
+		delete(m.devices, device.DeviceID)
+		delete(m.values, device.DeviceID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (m *MockAstarte) serveInterfaceValues(w http.ResponseWriter, req *http.Request, deviceID, interfaceName,
+	interfacePath string, body []byte) {
+	switch req.Method {
+	case http.MethodGet:
+		if interfacePath == "/" {
+			interfacePath = ""
+		}
+		value, ok := m.lookupValue(deviceID, interfaceName, interfacePath)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Path not found")
+			return
+		}
+		writeData(w, http.StatusOK, value)
+	case http.MethodPut, http.MethodPost:
+		var payload struct {
+			Data interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid payload")
+			return
+		}
+		if object, ok := payload.Data.(map[string]interface{}); ok && req.Method == http.MethodPost {
+			// Object aggregated values are stored on each of their endpoints
+			for key, value := range object {
+				m.setValue(deviceID, interfaceName, interfacePath+"/"+key, value)
+			}
+		} else {
+			m.setValue(deviceID, interfaceName, interfacePath, payload.Data)
+		}
+		writeData(w, http.StatusOK, payload.Data)
+	case http.MethodDelete:
+		if _, ok := m.values[deviceID][interfaceName][interfacePath]; !ok {
+			writeError(w, http.StatusNotFound, "Path not found")
+			return
+		}
+		delete(m.values[deviceID][interfaceName], interfacePath)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// lookupValue returns the value set on interfacePath or, if interfacePath is a prefix of other paths, a tree
+// of the values below it, as Astarte does.
+func (m *MockAstarte) lookupValue(deviceID, interfaceName, interfacePath string) (interface{}, bool) {
+	values := m.values[deviceID][interfaceName]
+	if value, ok := values[interfacePath]; ok {
+		return value, true
+	}
+
+	tree := map[string]interface{}{}
+	for valuePath, value := range values {
+		if !strings.HasPrefix(valuePath, interfacePath+"/") {
+			continue
+		}
+		node := tree
+		tokens := strings.Split(strings.TrimPrefix(valuePath, interfacePath+"/"), "/")
+		for _, token := range tokens[:len(tokens)-1] {
+			child, ok := node[token].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[token] = child
+			}
+			node = child
+		}
+		node[tokens[len(tokens)-1]] = value
+	}
+	return tree, len(tree) > 0
+}
+
+func (m *MockAstarte) serveRealmManagement(w http.ResponseWriter, req *http.Request, tokens []string, body []byte) {
+	if tokens[0] != "interfaces" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	switch {
+	case len(tokens) == 1 && req.Method == http.MethodGet:
+		names := []string{}
+		for name := range m.interfaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		writeData(w, http.StatusOK, names)
+	case len(tokens) == 1 && req.Method == http.MethodPost:
+		var payload struct {
+			Data interfaces.AstarteInterface `json:"data"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid interface")
+			return
+		}
+		if _, ok := m.interfaces[payload.Data.Name][payload.Data.MajorVersion]; ok {
+			writeError(w, http.StatusConflict, "Interface already exists")
+			return
+		}
+		m.addInterface(payload.Data)
+		writeData(w, http.StatusCreated, payload.Data)
+	case len(tokens) == 2 && req.Method == http.MethodGet:
+		majors := []int{}
+		for major := range m.interfaces[tokens[1]] {
+			majors = append(majors, major)
+		}
+		if len(majors) == 0 {
+			writeError(w, http.StatusNotFound, "Interface not found")
+			return
+		}
+		sort.Ints(majors)
+		writeData(w, http.StatusOK, majors)
+	case len(tokens) == 3 && req.Method == http.MethodGet:
+		major, _ := strconv.Atoi(tokens[2])
+		astarteInterface, ok := m.interfaces[tokens[1]][major]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Interface not found")
+			return
+		}
+		writeData(w, http.StatusOK, astarteInterface)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func writeData(w http.ResponseWriter, statusCode int, data interface{}) {
+	writeJSON(w, statusCode, map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, detail string) {
+	writeJSON(w, statusCode, map[string]interface{}{"errors": map[string]string{"detail": detail}})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
+	b := new(bytes.Buffer)
+	_ = json.NewEncoder(b).Encode(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(b.Bytes())
+}
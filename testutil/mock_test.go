@@ -0,0 +1,125 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/astarte-platform/astarte-go/client"
+	"github.com/astarte-platform/astarte-go/interfaces"
+)
+
+const (
+	testDeviceID      = "f0VMRgIBAQAAAAAAAAAAAA"
+	testOtherDeviceID = "YXN0YXJ0ZS1wbGF0Zm9ybQ"
+	testInterfaceName = "org.astarte-platform.genericsensors.SamplingRate"
+)
+
+func getMockContext(t *testing.T) (*MockAstarte, *client.Client) {
+	mock := NewMockAstarte(
+		WithDevices(
+			client.DeviceDetails{DeviceID: testDeviceID, Connected: true, Aliases: map[string]string{"name": "sensor-1"}},
+			client.DeviceDetails{DeviceID: testOtherDeviceID},
+		),
+		WithInterfaces(interfaces.AstarteInterface{
+			Name:         testInterfaceName,
+			MinorVersion: 1,
+			Type:         interfaces.PropertiesType,
+			Ownership:    interfaces.ServerOwnership,
+			Mappings:     []interfaces.AstarteInterfaceMapping{{Endpoint: "/%{sensor_id}/samplingPeriod", Type: interfaces.Integer}},
+		}),
+		WithValue(testDeviceID, testInterfaceName, "/temperature/samplingPeriod", 10),
+	)
+	astarteClient, err := client.NewClient(mock.URL, mock.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	astarteClient.SetToken("token")
+
+	return mock, astarteClient
+}
+
+func TestMockAstarteDevices(t *testing.T) {
+	mock, astarteClient := getMockContext(t)
+	defer mock.Close()
+
+	devices, err := astarteClient.AppEngine.ListDevices(DefaultRealm)
+	if err != nil || !reflect.DeepEqual(devices, []string{testOtherDeviceID, testDeviceID}) {
+		t.Error("Unexpected Devices", devices, err)
+	}
+	if total, connected, err := astarteClient.AppEngine.GetDeviceCount(DefaultRealm); err != nil || total != 2 || connected != 1 {
+		t.Error("Unexpected count", total, connected, err)
+	}
+	if deviceID, err := astarteClient.AppEngine.GetDeviceIDFromAlias(DefaultRealm, "sensor-1"); err != nil || deviceID != testDeviceID {
+		t.Error("Unexpected alias resolution", deviceID, err)
+	}
+	if _, err := astarteClient.AppEngine.GetDevice(DefaultRealm, "missing", client.AstarteDeviceAlias); !errors.Is(err, client.ErrDeviceNotFound) {
+		t.Error("Expected ErrDeviceNotFound, got", err)
+	}
+
+	mock.AssertRequested(t, http.MethodGet, "/appengine/v1/test/stats/devices")
+	mock.AssertNotRequested(t, http.MethodDelete, "/appengine/v1/test/devices/"+testDeviceID)
+}
+
+func TestMockAstarteValues(t *testing.T) {
+	mock, astarteClient := getMockContext(t)
+	defer mock.Close()
+
+	value, err := astarteClient.AppEngine.GetProperty(DefaultRealm, testDeviceID, client.AstarteDeviceID, testInterfaceName,
+		"/temperature/samplingPeriod")
+	if err != nil || value != 10.0 {
+		t.Error("Unexpected value", value, err)
+	}
+	properties, err := astarteClient.AppEngine.GetProperties(DefaultRealm, testDeviceID, client.AstarteDeviceID, testInterfaceName)
+	if err != nil || !reflect.DeepEqual(properties, map[string]interface{}{"/temperature/samplingPeriod": 10.0}) {
+		t.Error("Unexpected properties", properties, err)
+	}
+
+	mock.ResetRequests()
+	if err := astarteClient.AppEngine.SetProperty(DefaultRealm, testDeviceID, client.AstarteDeviceID, testInterfaceName,
+		"/humidity/samplingPeriod", 60); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := mock.Value(testDeviceID, testInterfaceName, "/humidity/samplingPeriod"); !ok || value != 60.0 {
+		t.Error("Value was not stored", value)
+	}
+	if requests := mock.Requests(); len(requests) != 1 || string(requests[0].Body) != "{\"data\":60}\n" {
+		t.Error("Unexpected requests", requests)
+	}
+}
+
+func TestMockAstarteInterfaces(t *testing.T) {
+	mock, astarteClient := getMockContext(t)
+	defer mock.Close()
+
+	astarteInterface, err := astarteClient.RealmManagement.GetInterface(DefaultRealm, testInterfaceName, 0)
+	if err != nil || astarteInterface.Name != testInterfaceName {
+		t.Error("Unexpected Interface", astarteInterface, err)
+	}
+	if err := astarteClient.RealmManagement.InstallInterface(DefaultRealm, astarteInterface); !errors.Is(err, client.ErrInterfaceAlreadyInstalled) {
+		t.Error("Expected ErrInterfaceAlreadyInstalled, got", err)
+	}
+	astarteInterface.MajorVersion = 1
+	if err := astarteClient.RealmManagement.InstallInterface(DefaultRealm, astarteInterface); err != nil {
+		t.Error(err)
+	}
+	if majors, err := astarteClient.RealmManagement.ListInterfaceMajorVersions(DefaultRealm, testInterfaceName); err != nil ||
+		!reflect.DeepEqual(majors, []int{0, 1}) {
+		t.Error("Unexpected major versions", majors, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+// DataTriggerBuilder builds a Data Trigger through a fluent API, e.g.:
+//
+//	trigger, err := triggers.NewDataTrigger().Named("high_temperature").
+//		OnInterface("org.astarte-platform.genericsensors.Values", 1).
+//		OnPath("/%{sensor_id}/value").
+//		When(triggers.GreaterThan, 40).
+//		Do(triggers.NewHTTPAction("https://example.com/hook", "post"))
+//
+// The Trigger is validated only when calling Do.
+type DataTriggerBuilder struct {
+	trigger       AstarteTrigger
+	simpleTrigger SimpleTrigger
+}
+
+// NewDataTrigger returns a DataTriggerBuilder for a Trigger firing on IncomingData on any path of any
+// Interface, for any value
+func NewDataTrigger() *DataTriggerBuilder {
+	return &DataTriggerBuilder{
+		simpleTrigger: SimpleTrigger{
+			Type:               DataTrigger,
+			On:                 IncomingData,
+			InterfaceName:      AnyInterface,
+			MatchPath:          "/*",
+			ValueMatchOperator: AnyValue,
+		},
+	}
+}
+
+// Named sets the name of the Trigger
+func (b *DataTriggerBuilder) Named(name string) *DataTriggerBuilder {
+	b.trigger.Name = name
+	return b
+}
+
+// On sets the condition the Trigger fires on. It defaults to IncomingData
+func (b *DataTriggerBuilder) On(condition TriggerCondition) *DataTriggerBuilder {
+	b.simpleTrigger.On = condition
+	return b
+}
+
+// OnInterface restricts the Trigger to the given Interface major version
+func (b *DataTriggerBuilder) OnInterface(name string, major int) *DataTriggerBuilder {
+	b.simpleTrigger.InterfaceName = name
+	b.simpleTrigger.InterfaceMajor = major
+	return b
+}
+
+// OnPath restricts the Trigger to the given path, which can contain parameters such as %{sensor_id}
+func (b *DataTriggerBuilder) OnPath(path string) *DataTriggerBuilder {
+	b.simpleTrigger.MatchPath = path
+	return b
+}
+
+// When restricts the Trigger to the values matching value according to operator
+func (b *DataTriggerBuilder) When(operator ValueMatchOperator, value interface{}) *DataTriggerBuilder {
+	b.simpleTrigger.ValueMatchOperator = operator
+	b.simpleTrigger.KnownValue = value
+	return b
+}
+
+// WithPolicy sets the Trigger Delivery Policy used for delivering the action of the Trigger
+func (b *DataTriggerBuilder) WithPolicy(policy string) *DataTriggerBuilder {
+	b.trigger.Policy = policy
+	return b
+}
+
+// Do sets the action of the Trigger, and returns the resulting Trigger if it is valid
+func (b *DataTriggerBuilder) Do(action TriggerAction) (AstarteTrigger, error) {
+	trigger := b.trigger
+	trigger.Action = action
+	trigger.SimpleTriggers = []SimpleTrigger{b.simpleTrigger}
+	if err := trigger.Validate(); err != nil {
+		return AstarteTrigger{}, err
+	}
+	return trigger, nil
+}
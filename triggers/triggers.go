@@ -0,0 +1,281 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package triggers models Astarte Triggers, which perform an action (such as an HTTP request or publishing an
+// AMQP message) whenever a condition on Devices or on their data is met.
+package triggers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SimpleTriggerType represents the kind of condition a SimpleTrigger watches
+type SimpleTriggerType string
+
+const (
+	// DataTrigger is a SimpleTrigger watching data sent on an Interface
+	DataTrigger SimpleTriggerType = "data_trigger"
+	// DeviceTrigger is a SimpleTrigger watching events of a Device, such as its connection
+	DeviceTrigger SimpleTriggerType = "device_trigger"
+)
+
+// IsValid returns an error if SimpleTriggerType does not represent a valid Simple Trigger type
+func (t SimpleTriggerType) IsValid() error {
+	switch t {
+	case DataTrigger, DeviceTrigger:
+		return nil
+	}
+	return errors.New("invalid Simple Trigger type")
+}
+
+// TriggerCondition represents the event a SimpleTrigger fires on. Data conditions can be used only with
+// DataTrigger, and Device conditions only with DeviceTrigger
+type TriggerCondition string
+
+const (
+	// IncomingData fires when a Device sends data on the Interface
+	IncomingData TriggerCondition = "incoming_data"
+	// ValueChange fires when a Device sends a value different from the last one on the same path
+	ValueChange TriggerCondition = "value_change"
+	// ValueChangeApplied fires after a changed value has been stored
+	ValueChangeApplied TriggerCondition = "value_change_applied"
+	// PathCreated fires when a Device sends data on a path for the first time
+	PathCreated TriggerCondition = "path_created"
+	// PathRemoved fires when a property path is unset
+	PathRemoved TriggerCondition = "path_removed"
+	// ValueStored fires after a value has been stored
+	ValueStored TriggerCondition = "value_stored"
+
+	// DeviceConnected fires when a Device connects
+	DeviceConnected TriggerCondition = "device_connected"
+	// DeviceDisconnected fires when a Device disconnects
+	DeviceDisconnected TriggerCondition = "device_disconnected"
+	// DeviceError fires when Astarte detects an error on a Device, such as an invalid payload
+	DeviceError TriggerCondition = "device_error"
+	// DeviceEmptyCacheReceived fires when a Device sends an empty cache message
+	DeviceEmptyCacheReceived TriggerCondition = "device_empty_cache_received"
+	// IncomingIntrospection fires when a Device sends its introspection
+	IncomingIntrospection TriggerCondition = "incoming_introspection"
+	// InterfaceAdded fires when a new Interface appears in the introspection of a Device
+	InterfaceAdded TriggerCondition = "interface_added"
+	// InterfaceRemoved fires when an Interface disappears from the introspection of a Device
+	InterfaceRemoved TriggerCondition = "interface_removed"
+	// InterfaceMinorUpdated fires when the minor version of an Interface in the introspection of a Device changes
+	InterfaceMinorUpdated TriggerCondition = "interface_minor_updated"
+)
+
+// IsValidFor returns an error if TriggerCondition is not a valid condition for triggerType
+func (c TriggerCondition) IsValidFor(triggerType SimpleTriggerType) error {
+	switch c {
+	case IncomingData, ValueChange, ValueChangeApplied, PathCreated, PathRemoved, ValueStored:
+		if triggerType == DataTrigger {
+			return nil
+		}
+	case DeviceConnected, DeviceDisconnected, DeviceError, DeviceEmptyCacheReceived, IncomingIntrospection,
+		InterfaceAdded, InterfaceRemoved, InterfaceMinorUpdated:
+		if triggerType == DeviceTrigger {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' is not a valid condition for a %s", c, triggerType)
+}
+
+// ValueMatchOperator represents how a Data Trigger compares incoming values with its known value
+type ValueMatchOperator string
+
+const (
+	// AnyValue matches any value, and requires no known value
+	AnyValue ValueMatchOperator = "*"
+	// EqualTo matches values equal to the known value
+	EqualTo ValueMatchOperator = "=="
+	// NotEqualTo matches values different from the known value
+	NotEqualTo ValueMatchOperator = "!="
+	// GreaterThan matches values greater than the known value, which must be a number
+	GreaterThan ValueMatchOperator = ">"
+	// GreaterOrEqualTo matches values greater than or equal to the known value, which must be a number
+	GreaterOrEqualTo ValueMatchOperator = ">="
+	// LessThan matches values less than the known value, which must be a number
+	LessThan ValueMatchOperator = "<"
+	// LessOrEqualTo matches values less than or equal to the known value, which must be a number
+	LessOrEqualTo ValueMatchOperator = "<="
+	// Contains matches values containing the known value, which must be a string
+	Contains ValueMatchOperator = "contains"
+	// NotContains matches values not containing the known value, which must be a string
+	NotContains ValueMatchOperator = "not_contains"
+)
+
+// ValidateKnownValue returns an error if ValueMatchOperator is not a valid operator, or if knownValue is not
+// compatible with it
+func (o ValueMatchOperator) ValidateKnownValue(knownValue interface{}) error {
+	switch o {
+	case AnyValue:
+		if knownValue != nil {
+			return fmt.Errorf("operator %s does not take a known value", o)
+		}
+	case EqualTo, NotEqualTo:
+		switch knownValue.(type) {
+		case nil, map[string]interface{}:
+			return fmt.Errorf("operator %s requires a scalar or array known value", o)
+		}
+	case GreaterThan, GreaterOrEqualTo, LessThan, LessOrEqualTo:
+		switch knownValue.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		default:
+			return fmt.Errorf("operator %s requires a numeric known value, got %T", o, knownValue)
+		}
+	case Contains, NotContains:
+		if _, ok := knownValue.(string); !ok {
+			return fmt.Errorf("operator %s requires a string known value, got %T", o, knownValue)
+		}
+	default:
+		return fmt.Errorf("'%s' is not a valid value match operator", o)
+	}
+	return nil
+}
+
+// AnyInterface can be used as InterfaceName to match data sent on any Interface
+const AnyInterface = "*"
+
+// SimpleTrigger represents the condition of a Trigger
+type SimpleTrigger struct {
+	Type SimpleTriggerType `json:"type"`
+	On   TriggerCondition  `json:"on"`
+
+	// Data Trigger fields. InterfaceMajor is ignored when InterfaceName is AnyInterface
+	InterfaceName      string             `json:"interface_name,omitempty"`
+	InterfaceMajor     int                `json:"interface_major"`
+	MatchPath          string             `json:"match_path,omitempty"`
+	ValueMatchOperator ValueMatchOperator `json:"value_match_operator,omitempty"`
+	KnownValue         interface{}        `json:"known_value,omitempty"`
+
+	// Device Trigger fields. When both are empty, the Trigger applies to all the Devices in the Realm
+	DeviceID  string `json:"device_id,omitempty"`
+	GroupName string `json:"group_name,omitempty"`
+}
+
+// MarshalJSON marshals the SimpleTrigger, omitting interface_major when it does not apply
+func (t SimpleTrigger) MarshalJSON() ([]byte, error) {
+	// Avoid recursing into MarshalJSON
+	type simpleTrigger SimpleTrigger
+	var interfaceMajor *int
+	if t.Type == DataTrigger && t.InterfaceName != AnyInterface {
+		interfaceMajor = &t.InterfaceMajor
+	}
+	return json.Marshal(struct {
+		simpleTrigger
+		InterfaceMajor *int `json:"interface_major,omitempty"`
+	}{simpleTrigger(t), interfaceMajor})
+}
+
+// Validate returns an error if the SimpleTrigger is not well formed
+func (t SimpleTrigger) Validate() error {
+	if err := t.Type.IsValid(); err != nil {
+		return fmt.Errorf("'%s' is not a valid Simple Trigger type", t.Type)
+	}
+	if err := t.On.IsValidFor(t.Type); err != nil {
+		return err
+	}
+	if t.Type == DataTrigger {
+		if t.InterfaceName == "" {
+			return errors.New("data triggers require an interface name")
+		}
+		if t.InterfaceMajor < 0 {
+			return errors.New("interface major must not be negative")
+		}
+		if t.ValueMatchOperator != "" {
+			if err := t.ValueMatchOperator.ValidateKnownValue(t.KnownValue); err != nil {
+				return err
+			}
+		}
+	}
+	if t.DeviceID != "" && t.GroupName != "" {
+		return errors.New("device_id and group_name are mutually exclusive")
+	}
+	return nil
+}
+
+// TriggerAction represents the action performed when a Trigger fires. Exactly one between HTTPURL and
+// AMQPExchange must be set, and only the fields of the corresponding kind of action are taken into account.
+type TriggerAction struct {
+	// HTTP action fields. HTTPMethod defaults to post
+	HTTPURL           string            `json:"http_url,omitempty"`
+	HTTPMethod        string            `json:"http_method,omitempty"`
+	HTTPStaticHeaders map[string]string `json:"http_static_headers,omitempty"`
+	IgnoreSSLErrors   bool              `json:"ignore_ssl_errors,omitempty"`
+	Template          string            `json:"template,omitempty"`
+	TemplateType      string            `json:"template_type,omitempty"`
+
+	// AMQP action fields
+	AMQPExchange            string            `json:"amqp_exchange,omitempty"`
+	AMQPRoutingKey          string            `json:"amqp_routing_key,omitempty"`
+	AMQPMessageExpirationMs int               `json:"amqp_message_expiration_ms,omitempty"`
+	AMQPMessagePersistent   bool              `json:"amqp_message_persistent,omitempty"`
+	AMQPMessagePriority     int               `json:"amqp_message_priority,omitempty"`
+	AMQPStaticHeaders       map[string]string `json:"amqp_static_headers,omitempty"`
+}
+
+// NewHTTPAction returns a TriggerAction sending an HTTP request with the given method to url
+func NewHTTPAction(url string, method string) TriggerAction {
+	return TriggerAction{HTTPURL: url, HTTPMethod: method}
+}
+
+// NewAMQPAction returns a TriggerAction publishing an AMQP message on exchange with the given routing key.
+// Messages expire after expirationMs milliseconds.
+func NewAMQPAction(exchange string, routingKey string, expirationMs int) TriggerAction {
+	return TriggerAction{AMQPExchange: exchange, AMQPRoutingKey: routingKey, AMQPMessageExpirationMs: expirationMs}
+}
+
+// Validate returns an error if the TriggerAction is not well formed
+func (a TriggerAction) Validate() error {
+	switch {
+	case a.HTTPURL != "" && a.AMQPExchange != "":
+		return errors.New("an action can be either an HTTP or an AMQP action, not both")
+	case a.HTTPURL == "" && a.AMQPExchange == "":
+		return errors.New("an action requires either an HTTP URL or an AMQP exchange")
+	case a.AMQPExchange != "" && a.AMQPMessageExpirationMs <= 0:
+		return errors.New("AMQP actions require a message expiration > 0")
+	}
+	return nil
+}
+
+// AstarteTrigger represents an Astarte Trigger
+type AstarteTrigger struct {
+	Name           string          `json:"name"`
+	Action         TriggerAction   `json:"action"`
+	SimpleTriggers []SimpleTrigger `json:"simple_triggers"`
+	// Policy is the name of the Trigger Delivery Policy used for delivering HTTP actions, if any
+	Policy string `json:"policy,omitempty"`
+}
+
+// Validate returns an error if the AstarteTrigger is not well formed. Astarte currently supports exactly one
+// SimpleTrigger per Trigger.
+func (t AstarteTrigger) Validate() error {
+	if t.Name == "" {
+		return errors.New("trigger name must not be empty")
+	}
+	if err := t.Action.Validate(); err != nil {
+		return err
+	}
+	if len(t.SimpleTriggers) != 1 {
+		return errors.New("a trigger must have exactly one simple trigger")
+	}
+	for _, simpleTrigger := range t.SimpleTriggers {
+		if err := simpleTrigger.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2020 Ispirata Srl
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTriggerMarshaling(t *testing.T) {
+	trigger := AstarteTrigger{
+		Name:   "high_temperature",
+		Action: NewHTTPAction("https://example.com/hook", "post"),
+		SimpleTriggers: []SimpleTrigger{{
+			Type:               DataTrigger,
+			On:                 IncomingData,
+			InterfaceName:      "org.astarte-platform.genericsensors.Values",
+			InterfaceMajor:     0,
+			MatchPath:          "/%{sensor_id}/value",
+			ValueMatchOperator: ">",
+			KnownValue:         40.5,
+		}},
+	}
+	if err := trigger.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(trigger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var marshaled map[string]interface{}
+	if err := json.Unmarshal(b, &marshaled); err != nil {
+		t.Fatal(err)
+	}
+	simpleTrigger := marshaled["simple_triggers"].([]interface{})[0].(map[string]interface{})
+	if simpleTrigger["interface_major"] != float64(0) {
+		t.Error("interface_major 0 must be marshaled for data triggers", simpleTrigger)
+	}
+
+	parsed := AstarteTrigger{}
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, trigger) {
+		t.Error("Trigger did not survive a round trip", parsed)
+	}
+}
+
+func TestDeviceTriggerMarshaling(t *testing.T) {
+	simpleTrigger := SimpleTrigger{Type: DeviceTrigger, On: DeviceConnected, GroupName: "sensors"}
+	b, err := json.Marshal(simpleTrigger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"type":"device_trigger","on":"device_connected","group_name":"sensors"}` {
+		t.Error("Unexpected JSON", string(b))
+	}
+}
+
+func TestTriggerValidation(t *testing.T) {
+	valid := SimpleTrigger{Type: DeviceTrigger, On: DeviceConnected}
+	for name, trigger := range map[string]AstarteTrigger{
+		"empty name": {Action: NewHTTPAction("https://example.com", "post"), SimpleTriggers: []SimpleTrigger{valid}},
+		"no action":  {Name: "test", SimpleTriggers: []SimpleTrigger{valid}},
+		"both actions": {Name: "test", SimpleTriggers: []SimpleTrigger{valid},
+			Action: TriggerAction{HTTPURL: "https://example.com", AMQPExchange: "astarte_events_test_exchange"}},
+		"no amqp expiration": {Name: "test", SimpleTriggers: []SimpleTrigger{valid},
+			Action: NewAMQPAction("astarte_events_test_exchange", "key", 0)},
+		"no simple triggers": {Name: "test", Action: NewHTTPAction("https://example.com", "post")},
+		"wrong condition": {Name: "test", Action: NewHTTPAction("https://example.com", "post"),
+			SimpleTriggers: []SimpleTrigger{{Type: DeviceTrigger, On: IncomingData}}},
+		"no interface": {Name: "test", Action: NewHTTPAction("https://example.com", "post"),
+			SimpleTriggers: []SimpleTrigger{{Type: DataTrigger, On: IncomingData}}},
+	} {
+		if err := trigger.Validate(); err == nil {
+			t.Errorf("Trigger with %s should have failed validation", name)
+		}
+	}
+}
+
+func TestDataTriggerBuilder(t *testing.T) {
+	trigger, err := NewDataTrigger().Named("high_temperature").
+		OnInterface("org.astarte-platform.genericsensors.Values", 1).
+		OnPath("/%{sensor_id}/value").
+		When(GreaterThan, 40).
+		Do(NewHTTPAction("https://example.com/hook", "post"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AstarteTrigger{
+		Name:   "high_temperature",
+		Action: TriggerAction{HTTPURL: "https://example.com/hook", HTTPMethod: "post"},
+		SimpleTriggers: []SimpleTrigger{{
+			Type:               DataTrigger,
+			On:                 IncomingData,
+			InterfaceName:      "org.astarte-platform.genericsensors.Values",
+			InterfaceMajor:     1,
+			MatchPath:          "/%{sensor_id}/value",
+			ValueMatchOperator: GreaterThan,
+			KnownValue:         40,
+		}},
+	}
+	if !reflect.DeepEqual(trigger, expected) {
+		t.Error("Unexpected trigger", trigger)
+	}
+}
+
+func TestDataTriggerBuilderValidation(t *testing.T) {
+	action := NewHTTPAction("https://example.com/hook", "post")
+	for name, builder := range map[string]*DataTriggerBuilder{
+		"missing name":       NewDataTrigger(),
+		"unknown operator":   NewDataTrigger().Named("test").When("greater", 40),
+		"string for >":       NewDataTrigger().Named("test").When(GreaterThan, "40"),
+		"number for contain": NewDataTrigger().Named("test").When(Contains, 40),
+		"value for *":        NewDataTrigger().Named("test").When(AnyValue, 40),
+		"no value for ==":    NewDataTrigger().Named("test").When(EqualTo, nil),
+		"device condition":   NewDataTrigger().Named("test").On(DeviceConnected),
+	} {
+		if _, err := builder.Do(action); err == nil {
+			t.Errorf("Trigger with %s should have failed validation", name)
+		}
+	}
+
+	if _, err := NewDataTrigger().Named("test").When(Contains, "error").Do(action); err != nil {
+		t.Error(err)
+	}
+}